@@ -1,41 +1,65 @@
 package main
 
 import (
+	"context"
 	"log/slog"
 	"slices"
 	"strconv"
 	"strings"
-
-	runner "github.com/ThePants999/advent-of-code-go-runner"
 )
 
-var Day1 = runner.DayImplementation{
+var Day1 = newPuzzle(dayDefinition[[][]int]{
 	DayNumber:          1,
 	ExecutePart1:       Day1Part1,
 	ExecutePart2:       Day1Part2,
 	ExampleInput:       "3   4\n4   3\n2   5\n1   3\n3   9\n3   3",
 	ExamplePart1Answer: "11",
 	ExamplePart2Answer: "31",
-}
+})
 
-func Day1Part1(logger *slog.Logger, input string) (string, any) {
-	// Split on any amount of whitespace, following which
-	// we can direct even and odd elements into separate
-	// lists.
-	numbers := strings.Fields(input)
-	var list1, list2 []int = make([]int, 0, len(numbers)/2), make([]int, 0, len(numbers)/2)
-
-	for ix, num := range numbers {
-		numInt, err := strconv.Atoi(num)
-		if err != nil {
-			panic(err)
+// Parse the two lists. They're returned in the order they're given,
+// not sorted.
+func parseD1Lists(input string) (list1 []int, list2 []int, err error) {
+	lines := strings.Split(input, "\n")
+	list1, list2 = make([]int, 0, len(lines)), make([]int, 0, len(lines))
+	for lineIx, line := range lines {
+		if len(line) == 0 {
+			continue
 		}
-		if ix%2 == 0 {
-			list1 = append(list1, numInt)
-		} else {
-			list2 = append(list2, numInt)
+		num1, num2, err := parseD1Line(line, lineIx+1)
+		if err != nil {
+			return nil, nil, err
 		}
+		list1 = append(list1, num1)
+		list2 = append(list2, num2)
+	}
+	return list1, list2, nil
+}
+
+// Each line holds one element of each list, separated by any
+// amount of whitespace.
+func parseD1Line(line string, lineNum int) (int, int, error) {
+	numbers := strings.Fields(line)
+	if len(numbers) != 2 {
+		return 0, 0, errorAt(lineNum, 0, "expected two numbers, found %d", len(numbers))
+	}
+	num1, err := atoiAt(numbers[0], lineNum, strings.Index(line, numbers[0])+1)
+	if err != nil {
+		return 0, 0, err
+	}
+	num2, err := atoiAt(numbers[1], lineNum, strings.LastIndex(line, numbers[1])+1)
+	if err != nil {
+		return 0, 0, err
+	}
+	return num1, num2, nil
+}
+
+func Day1Part1(ctx context.Context, logger *slog.Logger, input string) (string, [][]int, error) {
+	list1, list2, err := parseD1Lists(input)
+	if err != nil {
+		return "", nil, err
 	}
+	logger.Debug("Parsed input", slog.Int("pairs", len(list1)))
 
 	// Part 1 just requires that we sort the lists before
 	// performing an element-wise comparison. We'll leave
@@ -52,11 +76,10 @@ func Day1Part1(logger *slog.Logger, input string) (string, any) {
 		distanceSum += distance
 	}
 
-	return strconv.Itoa(distanceSum), [][]int{list1, list2}
+	return strconv.Itoa(distanceSum), [][]int{list1, list2}, nil
 }
 
-func Day1Part2(logger *slog.Logger, input string, part1Context any) string {
-	lists := part1Context.([][]int)
+func Day1Part2(ctx context.Context, logger *slog.Logger, input string, lists [][]int) (string, error) {
 	list1, list2 := lists[0], lists[1]
 
 	// Calculate frequencies by iterating through the
@@ -74,5 +97,5 @@ func Day1Part2(logger *slog.Logger, input string, part1Context any) string {
 		similarityScore += val * m[val]
 	}
 
-	return strconv.Itoa(similarityScore)
+	return strconv.Itoa(similarityScore), nil
 }
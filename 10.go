@@ -1,17 +1,16 @@
 package main
 
 import (
+	"context"
 	"log/slog"
 	"strconv"
 	"strings"
 
 	"github.com/golang-collections/collections/set"
 	stack "github.com/golang-collections/collections/stack"
-
-	runner "github.com/ThePants999/advent-of-code-go-runner"
 )
 
-var Day10 = runner.DayImplementation{
+var Day10 = newPuzzle(dayDefinition[day10context]{
 	DayNumber:    10,
 	ExecutePart1: Day10Part1,
 	ExecutePart2: Day10Part2,
@@ -25,10 +24,10 @@ var Day10 = runner.DayImplementation{
 10456732`,
 	ExamplePart1Answer: "36",
 	ExamplePart2Answer: "81",
-}
+})
 
 type day10context struct {
-	grid       [][]int
+	grid       Grid[int]
 	trailheads []gridPos
 }
 
@@ -38,42 +37,27 @@ type day10context struct {
 // previously-visited locations. You surely don't need more
 // commenting than that ;-)
 
-func Day10Part1(logger *slog.Logger, input string) (string, any) {
-	rows := strings.Fields(input)
-	grid := make([][]int, len(rows))
+func Day10Part1(ctx context.Context, logger *slog.Logger, input string) (string, day10context, error) {
+	grid, err := parseGrid(strings.Fields(input), func(square rune) (int, bool) {
+		// Impassable squares are sometimes drawn as '.', which
+		// conveniently can never be one higher than anything.
+		return int(square - '0'), (square >= '0' && square <= '9') || square == '.'
+	})
+	if err != nil {
+		return "", day10context{}, err
+	}
 	trailheads := make([]gridPos, 0, len(input))
-	for rowIx, row := range rows {
-		grid[rowIx] = make([]int, len(row))
-		for colIx, square := range row {
-			val := int(square - '0')
-			grid[rowIx][colIx] = val
-			if val == 0 {
-				trailheads = append(trailheads, gridPos{rowIx, colIx})
-			}
+	for pos, val := range grid.All() {
+		if val == 0 {
+			trailheads = append(trailheads, pos)
 		}
 	}
+	logger.Debug("Parsed input", slog.Int("rows", grid.NumRows()), slog.Int("cols", grid.NumCols()), slog.Int("trailheads", len(trailheads)))
 
-	return strconv.Itoa(day10dfs(grid, trailheads, true)), day10context{grid, trailheads}
-}
-
-func (p gridPos) adjacencies(numRows int, numCols int) []gridPos {
-	adj := make([]gridPos, 0, 4)
-	if p.row > 0 {
-		adj = append(adj, gridPos{p.row - 1, p.col})
-	}
-	if p.row < numRows-1 {
-		adj = append(adj, gridPos{p.row + 1, p.col})
-	}
-	if p.col > 0 {
-		adj = append(adj, gridPos{p.row, p.col - 1})
-	}
-	if p.col < numCols-1 {
-		adj = append(adj, gridPos{p.row, p.col + 1})
-	}
-	return adj
+	return strconv.Itoa(day10dfs(grid, trailheads, true)), day10context{grid, trailheads}, nil
 }
 
-func day10dfs(grid [][]int, trailheads []gridPos, visitedCheck bool) int {
+func day10dfs(grid Grid[int], trailheads []gridPos, visitedCheck bool) int {
 	sum := 0
 	for _, trailhead := range trailheads {
 		visited := set.New()
@@ -83,11 +67,11 @@ func day10dfs(grid [][]int, trailheads []gridPos, visitedCheck bool) int {
 			pos := s.Pop().(gridPos)
 			if !visitedCheck || !visited.Has(pos) {
 				visited.Insert(pos)
-				if grid[pos.row][pos.col] == 9 {
+				if grid.At(pos) == 9 {
 					sum++
 				} else {
-					for _, adj := range pos.adjacencies(len(grid), len(grid[pos.row])) {
-						if grid[adj.row][adj.col]-grid[pos.row][pos.col] == 1 {
+					for adj := range grid.Neighbours(pos) {
+						if grid.At(adj)-grid.At(pos) == 1 {
 							s.Push(adj)
 						}
 					}
@@ -99,7 +83,6 @@ func day10dfs(grid [][]int, trailheads []gridPos, visitedCheck bool) int {
 	return sum
 }
 
-func Day10Part2(logger *slog.Logger, input string, part1Context any) string {
-	context := part1Context.(day10context)
-	return strconv.Itoa(day10dfs(context.grid, context.trailheads, false))
+func Day10Part2(ctx context.Context, logger *slog.Logger, input string, part1Context day10context) (string, error) {
+	return strconv.Itoa(day10dfs(part1Context.grid, part1Context.trailheads, false)), nil
 }
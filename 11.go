@@ -1,12 +1,11 @@
 package main
 
 import (
+	"context"
 	"log/slog"
 	"math"
 	"strconv"
 	"strings"
-
-	runner "github.com/ThePants999/advent-of-code-go-runner"
 )
 
 type intPair struct {
@@ -14,13 +13,28 @@ type intPair struct {
 	two int
 }
 
-var Day11 = runner.DayImplementation{
+var Day11 = newPuzzle(dayDefinition[map[int]int]{
 	DayNumber:          11,
 	ExecutePart1:       Day11Part1,
 	ExecutePart2:       Day11Part2,
 	ExampleInput:       "125 17",
 	ExamplePart1Answer: "55312",
 	ExamplePart2Answer: "65601038650482",
+	Parameters:         []*dayParameter{d11Part1Blinks, d11Part2Blinks},
+})
+
+var d11Part1Blinks = &dayParameter{
+	Name:        "part1Blinks",
+	Description: "How many times to blink in part 1",
+	Default:     25,
+	Example:     25,
+}
+
+var d11Part2Blinks = &dayParameter{
+	Name:        "part2Blinks",
+	Description: "How many times to blink in part 2, which can't be fewer than part 1",
+	Default:     75,
+	Example:     75,
 }
 
 // The approach we take here is stone counting. Let's
@@ -38,21 +52,33 @@ var Day11 = runner.DayImplementation{
 // slowed things down vs this implementation. Map
 // operations can be expensive.
 
-func Day11Part1(logger *slog.Logger, input string) (string, any) {
-	first := parseDay11Input(input)
-	stones := doDay11Calc(first, 25)
-	return strconv.Itoa(countStones(stones)), stones
+func Day11Part1(ctx context.Context, logger *slog.Logger, input string) (string, map[int]int, error) {
+	first, err := parseDay11Input(input)
+	if err != nil {
+		return "", nil, err
+	}
+	logger.Debug("Parsed input", slog.Int("stones", countStones(first)), slog.Int("distinctStones", len(first)))
+	blinks := d11Part1Blinks.get(ctx)
+	stones, err := doDay11Calc(ctx, first, blinks)
+	if err != nil {
+		return "", nil, err
+	}
+	logger.Debug("Blinked", slog.Int("blinks", blinks), slog.Int("distinctStones", len(stones)))
+	return strconv.Itoa(countStones(stones)), stones, nil
 }
 
-func parseDay11Input(input string) map[int]int {
+func parseDay11Input(input string) (map[int]int, error) {
 	stones := make(map[int]int)
 	nums := strings.Fields(input)
 	for _, numString := range nums {
-		num, _ := strconv.Atoi(numString)
+		num, err := strconv.Atoi(numString)
+		if err != nil || num < 0 {
+			return nil, errorAt(1, strings.Index(input, numString)+1, "expected a stone number, found %q", numString)
+		}
 		prev := stones[num]
 		stones[num] = prev + 1
 	}
-	return stones
+	return stones, nil
 }
 
 func countDigits(num int) int {
@@ -90,7 +116,7 @@ func countDigits(num int) int {
 	}
 }
 
-func doDay11Calc(inputStones map[int]int, iterations int) map[int]int {
+func doDay11Calc(ctx context.Context, inputStones map[int]int, iterations int) (map[int]int, error) {
 	// Each time we blink at all the stones, we need to
 	// forget the old stone counts and entirely replace
 	// them with new stone counts. However, to avoid the
@@ -100,6 +126,13 @@ func doDay11Calc(inputStones map[int]int, iterations int) map[int]int {
 	secondStones := make(map[int]int)
 	stones, newStones := &inputStones, &secondStones
 	for i := 0; i < iterations; i++ {
+		// There's no limit on how many times we might be asked
+		// to blink, but a blink doesn't take long, so once per
+		// blink is often enough to check whether to give up.
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		// Go through each unique stone value we currently
 		// have.
 		for num, count := range *stones {
@@ -132,7 +165,7 @@ func doDay11Calc(inputStones map[int]int, iterations int) map[int]int {
 		stones, newStones = newStones, stones
 	}
 
-	return *stones
+	return *stones, nil
 }
 
 func countStones(stones map[int]int) int {
@@ -144,11 +177,18 @@ func countStones(stones map[int]int) int {
 	return sum
 }
 
-func Day11Part2(logger *slog.Logger, input string, part1Context any) string {
-	stones := part1Context.(map[int]int)
-	// We've still got our "what do we have after 25
-	// blinks" state, so we just need to do another
-	// 50 to get to 75.
-	stones = doDay11Calc(stones, 50)
-	return strconv.Itoa(countStones(stones))
+func Day11Part2(ctx context.Context, logger *slog.Logger, input string, stones map[int]int) (string, error) {
+	// We've still got our "what do we have after part
+	// 1's blinks" state, so we just need to do the
+	// rest - usually another 50 to get from 25 to 75.
+	part1Blinks, part2Blinks := d11Part1Blinks.get(ctx), d11Part2Blinks.get(ctx)
+	if part2Blinks < part1Blinks {
+		return "", errorAt(0, 0, "part 2 can't blink fewer times (%d) than part 1 (%d)", part2Blinks, part1Blinks)
+	}
+	stones, err := doDay11Calc(ctx, stones, part2Blinks-part1Blinks)
+	if err != nil {
+		return "", err
+	}
+	logger.Debug("Blinked", slog.Int("blinks", part2Blinks), slog.Int("distinctStones", len(stones)))
+	return strconv.Itoa(countStones(stones)), nil
 }
@@ -1,16 +1,15 @@
 package main
 
 import (
+	"context"
 	"log/slog"
 	"strconv"
 	"strings"
 
 	stack "github.com/golang-collections/collections/stack"
-
-	runner "github.com/ThePants999/advent-of-code-go-runner"
 )
 
-var Day12 = runner.DayImplementation{
+var Day12 = newPuzzle(dayDefinition[*[]region]{
 	DayNumber:    12,
 	ExecutePart1: Day12Part1,
 	ExecutePart2: Day12Part2,
@@ -26,118 +25,115 @@ MIIISIJEEE
 MMMISSJEEE`,
 	ExamplePart1Answer: "1930",
 	ExamplePart2Answer: "1206",
-}
+})
 
 type nothing struct{}
 
 type region struct {
 	char     rune
-	plots    [][]bool
+	plots    Grid[bool]
 	plotsArr []gridPos
 }
 
-func newRegion(char rune, gridSize int) region {
-	r := region{char, make([][]bool, gridSize), make([]gridPos, 0, 20)}
-	for i := range gridSize {
-		r.plots[i] = make([]bool, gridSize)
-	}
-	return r
+func newRegion(char rune, numRows int, numCols int) region {
+	return region{char, newGrid[bool](numRows, numCols), make([]gridPos, 0, 20)}
 }
 
-func Day12Part1(logger *slog.Logger, input string) (string, any) {
-	lines := strings.Fields(input)
-	grid := make([][]rune, len(lines))
-	usedplots := make([][]bool, len(lines))
-	for rowIx, line := range lines {
-		grid[rowIx] = make([]rune, len(line))
-		usedplots[rowIx] = make([]bool, len(line))
-		for colIx, char := range line {
-			grid[rowIx][colIx] = char
-		}
+func Day12Part1(ctx context.Context, logger *slog.Logger, input string) (string, *[]region, error) {
+	grid, err := parseGrid(strings.Fields(input), anyRune)
+	if err != nil {
+		return "", nil, err
 	}
+	usedplots := newGrid[bool](grid.NumRows(), grid.NumCols())
 
 	regions := make([]region, 0, 100)
-	for rowIx, row := range grid {
-		for colIx, char := range row {
-			loc := gridPos{rowIx, colIx}
-			if !usedplots[loc.row][loc.col] {
-				// New region
-				region := newRegion(char, len(grid))
-				s := stack.New()
-				s.Push(loc)
-				for s.Len() > 0 {
-					loc = s.Pop().(gridPos)
-					if grid[loc.row][loc.col] == region.char && !usedplots[loc.row][loc.col] {
-						usedplots[loc.row][loc.col] = true
-						region.plots[loc.row][loc.col] = true
-						region.plotsArr = append(region.plotsArr, loc)
-						adjs := loc.adjacencies(len(grid), len(grid[0]))
-						for _, adj := range adjs {
-							s.Push(adj)
-						}
+	for loc, char := range grid.All() {
+		if !usedplots.At(loc) {
+			// New region
+			region := newRegion(char, grid.NumRows(), grid.NumCols())
+			s := stack.New()
+			s.Push(loc)
+			for s.Len() > 0 {
+				loc = s.Pop().(gridPos)
+				if grid.At(loc) == region.char && !usedplots.At(loc) {
+					usedplots.Set(loc, true)
+					region.plots.Set(loc, true)
+					region.plotsArr = append(region.plotsArr, loc)
+					for adj := range grid.Neighbours(loc) {
+						s.Push(adj)
 					}
 				}
-				regions = append(regions, region)
 			}
+			regions = append(regions, region)
 		}
 	}
+	logger.Debug("Parsed input", slog.Int("rows", grid.NumRows()), slog.Int("cols", grid.NumCols()), slog.Int("regions", len(regions)))
 
-	price := calcTotalPrice(&regions, func(r *region, pos gridPos) int {
+	price, err := calcTotalPrice(ctx, &regions, func(r *region, pos gridPos) int {
 		fences := 4
-		for _, adj := range pos.adjacencies(len(grid), len(grid[0])) {
-			if r.plots[adj.row][adj.col] {
+		for adj := range grid.Neighbours(pos) {
+			if r.plots.At(adj) {
 				fences--
 			}
 		}
 		return fences
 	})
+	if err != nil {
+		return "", nil, err
+	}
 
-	return strconv.Itoa(price), &regions
+	if images := imageRecorderFrom(ctx); images != nil {
+		images.add("regions", drawD12Regions(regions, grid.NumRows(), grid.NumCols()))
+	}
+	return strconv.Itoa(price), &regions, nil
 }
 
-func calcTotalPrice(regions *[]region, plotWeight func(*region, gridPos) int) int {
-	price := 0
-	c := make(chan int)
-	for _, reg := range *regions {
-		go reg.calcPrice(plotWeight, c)
-	}
-	for range *regions {
-		price += <-c
+// Each region in its own colour, with its fences round it.
+func drawD12Regions(regions []region, numRows int, numCols int) *gridImage {
+	img := newGridImage(numRows, numCols, IMAGE_WHITE)
+	for ix, r := range regions {
+		colour := paletteColour(ix)
+		for _, pos := range r.plotsArr {
+			img.fill(pos, colour)
+			for _, dir := range ORTHOGONAL_DIRECTIONS {
+				if inRegion, _ := r.plots.Lookup(pos.move(dir)); !inRegion {
+					img.edge(pos, dir, IMAGE_BLACK)
+				}
+			}
+		}
 	}
-	return price
+	return img
 }
 
-func (r *region) calcPrice(plotWeight func(*region, gridPos) int, c chan int) {
+func calcTotalPrice(ctx context.Context, regions *[]region, plotWeight func(*region, gridPos) int) (int, error) {
+	return parallelSum(ctx, len(*regions), func(ix int) int {
+		return (*regions)[ix].calcPrice(plotWeight)
+	})
+}
+
+func (r *region) calcPrice(plotWeight func(*region, gridPos) int) int {
 	weight := 0
 
 	for _, p := range r.plotsArr {
 		weight += plotWeight(r, p)
 	}
 
-	c <- weight * len(r.plotsArr)
+	return weight * len(r.plotsArr)
 }
 
-func Day12Part2(logger *slog.Logger, input string, part1Context any) string {
-	regions := part1Context.(*[]region)
-	price := calcTotalPrice(regions, func(r *region, pos gridPos) int {
+func Day12Part2(ctx context.Context, logger *slog.Logger, input string, regions *[]region) (string, error) {
+	price, err := calcTotalPrice(ctx, regions, func(r *region, pos gridPos) int {
 		vertices := 0
 
-		allAdj := [9]gridPos{
-			{pos.row - 1, pos.col},
-			{pos.row - 1, pos.col + 1},
-			{pos.row, pos.col + 1},
-			{pos.row + 1, pos.col + 1},
-			{pos.row + 1, pos.col},
-			{pos.row + 1, pos.col - 1},
-			{pos.row, pos.col - 1},
-			{pos.row - 1, pos.col - 1},
-			{pos.row - 1, pos.col},
-		}
-		var inRegion [9]bool
-		for ix := 0; ix < 9; ix++ {
-			adj := allAdj[ix]
-			inRegion[ix] = adj.row >= 0 && adj.row < len(r.plots) && adj.col >= 0 && adj.col < len(r.plots[adj.row]) && r.plots[allAdj[ix].row][allAdj[ix].col]
+		// Whether each of the eight surrounding plots, going
+		// clockwise from the one above, is in the region. The
+		// one above is repeated at the end so that each diagonal
+		// has an entry either side of it.
+		var inRegion [NUM_DIRECTIONS + 1]bool
+		for _, dir := range ALL_DIRECTIONS {
+			inRegion[dir], _ = r.plots.Lookup(pos.move(dir))
 		}
+		inRegion[NUM_DIRECTIONS] = inRegion[UP]
 
 		for dir := UP_RIGHT; dir <= UP_LEFT; dir += 2 {
 			if !inRegion[dir-1] && !inRegion[dir+1] {
@@ -151,6 +147,9 @@ func Day12Part2(logger *slog.Logger, input string, part1Context any) string {
 
 		return vertices
 	})
+	if err != nil {
+		return "", err
+	}
 
-	return strconv.Itoa(price)
+	return strconv.Itoa(price), nil
 }
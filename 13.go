@@ -1,11 +1,10 @@
 package main
 
 import (
+	"context"
 	"log/slog"
 	"strconv"
 	"strings"
-
-	runner "github.com/ThePants999/advent-of-code-go-runner"
 )
 
 type d13machine struct {
@@ -15,9 +14,10 @@ type d13machine struct {
 	b_y     int
 	prize_x int
 	prize_y int
+	line    int
 }
 
-var Day13 = runner.DayImplementation{
+var Day13 = newPuzzle(dayDefinition[[]d13machine]{
 	DayNumber:    13,
 	ExecutePart1: Day13Part1,
 	ExecutePart2: Day13Part2,
@@ -38,55 +38,82 @@ Button B: X+27, Y+71
 Prize: X=18641, Y=10279`,
 	ExamplePart1Answer: "480",
 	ExamplePart2Answer: "875318608908",
-}
+})
 
-func Day13Part1(logger *slog.Logger, input string) (string, any) {
+func Day13Part1(ctx context.Context, logger *slog.Logger, input string) (string, []d13machine, error) {
 	lines := strings.Split(input, "\n")
 	machines := make([]d13machine, 0, len(lines)/4+1)
-	// Input parsing.
-	// This is horrific, but it's fast ;-)
+	// Input parsing. Each machine is three lines, followed
+	// by a blank one.
 	for ix := 0; ix < len(lines); ix += 4 {
-		machine := d13machine{}
-		lineIx := 12
-		for lines[ix][lineIx] != ',' {
-			machine.a_x *= 10
-			machine.a_x += int(lines[ix][lineIx] - '0')
-			lineIx++
-		}
-		lineIx += 4
-		for ; lineIx < len(lines[ix]); lineIx++ {
-			machine.a_y *= 10
-			machine.a_y += int(lines[ix][lineIx] - '0')
+		if ix+2 >= len(lines) {
+			return "", nil, errorAt(ix+1, 0, "incomplete machine, expected three lines")
 		}
-		lineIx = 12
-		for lines[ix+1][lineIx] != ',' {
-			machine.b_x *= 10
-			machine.b_x += int(lines[ix+1][lineIx] - '0')
-			lineIx++
+		machine := d13machine{line: ix + 1}
+		var err error
+		machine.a_x, machine.a_y, err = d13ReadLine(lines[ix], ix+1, "Button A: X+", ", Y+")
+		if err != nil {
+			return "", nil, err
 		}
-		lineIx += 4
-		for ; lineIx < len(lines[ix+1]); lineIx++ {
-			machine.b_y *= 10
-			machine.b_y += int(lines[ix+1][lineIx] - '0')
+		machine.b_x, machine.b_y, err = d13ReadLine(lines[ix+1], ix+2, "Button B: X+", ", Y+")
+		if err != nil {
+			return "", nil, err
 		}
-		lineIx = 9
-		for lines[ix+2][lineIx] != ',' {
-			machine.prize_x *= 10
-			machine.prize_x += int(lines[ix+2][lineIx] - '0')
-			lineIx++
-		}
-		lineIx += 4
-		for ; lineIx < len(lines[ix+2]); lineIx++ {
-			machine.prize_y *= 10
-			machine.prize_y += int(lines[ix+2][lineIx] - '0')
+		machine.prize_x, machine.prize_y, err = d13ReadLine(lines[ix+2], ix+3, "Prize: X=", ", Y=")
+		if err != nil {
+			return "", nil, err
 		}
 		machines = append(machines, machine)
 	}
+	logger.Debug("Parsed input", slog.Int("machines", len(machines)))
+
+	total, err := d13solve(logger, machines)
+	if err != nil {
+		return "", nil, err
+	}
+	return strconv.Itoa(total), machines, nil
+}
+
+// Read a line of the form "<prefix>123<separator>456". We used to
+// just index straight to where the numbers would be, which was
+// fast but fell over on anything unexpected. This is still fast.
+func d13ReadLine(line string, lineNum int, prefix string, separator string) (int, int, error) {
+	if !strings.HasPrefix(line, prefix) {
+		return 0, 0, errorAt(lineNum, 1, "expected line to start %q", prefix)
+	}
+	lineIx := len(prefix)
+	first, lineIx, err := d13ReadNumber(line, lineNum, lineIx)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !strings.HasPrefix(line[lineIx:], separator) {
+		return 0, 0, errorAt(lineNum, lineIx+1, "expected %q", separator)
+	}
+	lineIx += len(separator)
+	second, lineIx, err := d13ReadNumber(line, lineNum, lineIx)
+	if err != nil {
+		return 0, 0, err
+	}
+	if lineIx != len(line) {
+		return 0, 0, errorAt(lineNum, lineIx+1, "unexpected %q after the second number", line[lineIx:])
+	}
+	return first, second, nil
+}
 
-	return strconv.Itoa(d13solve(machines)), machines
+func d13ReadNumber(line string, lineNum int, lineIx int) (int, int, error) {
+	start := lineIx
+	num := 0
+	for ; lineIx < len(line) && line[lineIx] >= '0' && line[lineIx] <= '9'; lineIx++ {
+		num *= 10
+		num += int(line[lineIx] - '0')
+	}
+	if lineIx == start {
+		return 0, lineIx, errorAt(lineNum, lineIx+1, "expected a number")
+	}
+	return num, lineIx, nil
 }
 
-func d13solve(machines []d13machine) int {
+func d13solve(logger *slog.Logger, machines []d13machine) (int, error) {
 	// Pretty trivial day tbh - the configuration
 	// of each machine boils down to a pair of
 	// simultaneous equations over a pair of variables,
@@ -94,22 +121,34 @@ func d13solve(machines []d13machine) int {
 	//
 	// The algebra was done on paper, and here's the
 	// result ;-)
-	total := 0
+	total, winnable := 0, 0
 	for _, machine := range machines {
+		if (machine.a_x*machine.b_y)-(machine.a_y*machine.b_x) == 0 {
+			// The buttons move the claw in the same direction, so
+			// there's either no solution or infinitely many.
+			return 0, errorAt(machine.line, 0, "buttons A and B move the claw in the same direction")
+		}
 		a_presses := ((machine.prize_x * machine.b_y) - (machine.prize_y * machine.b_x)) / ((machine.a_x * machine.b_y) - (machine.a_y * machine.b_x))
 		b_presses := ((machine.prize_x * machine.a_y) - (machine.prize_y * machine.a_x)) / ((machine.b_x * machine.a_y) - (machine.b_y * machine.a_x))
-		if a_presses*machine.a_x+b_presses*machine.b_x == machine.prize_x && a_presses*machine.a_y+b_presses*machine.b_y == machine.prize_y {
+		// The real inputs never seem to need it, but there's no
+		// pressing a button a negative number of times.
+		if a_presses >= 0 && b_presses >= 0 && a_presses*machine.a_x+b_presses*machine.b_x == machine.prize_x && a_presses*machine.a_y+b_presses*machine.b_y == machine.prize_y {
 			total += b_presses + 3*a_presses
+			winnable++
 		}
 	}
-	return total
+	logger.Debug("Solved machines", slog.Int("winnable", winnable), slog.Int("unwinnable", len(machines)-winnable))
+	return total, nil
 }
 
-func Day13Part2(logger *slog.Logger, input string, part1Context any) string {
-	machines := part1Context.([]d13machine)
+func Day13Part2(ctx context.Context, logger *slog.Logger, input string, machines []d13machine) (string, error) {
 	for ix := range machines {
 		machines[ix].prize_x += 10000000000000
 		machines[ix].prize_y += 10000000000000
 	}
-	return strconv.Itoa(d13solve(machines))
+	total, err := d13solve(logger, machines)
+	if err != nil {
+		return "", err
+	}
+	return strconv.Itoa(total), nil
 }
@@ -1,21 +1,49 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log/slog"
 	"math"
 	"regexp"
 	"strconv"
-
-	runner "github.com/ThePants999/advent-of-code-go-runner"
+	"strings"
 )
 
-var Day14 = runner.DayImplementation{
+var Day14 = newPuzzle(dayDefinition[d14context]{
 	DayNumber:          14,
 	ExecutePart1:       Day14Part1,
 	ExecutePart2:       Day14Part2,
 	ExampleInput:       "",
 	ExamplePart1Answer: "",
 	ExamplePart2Answer: "",
+	Parameters:         []*dayParameter{d14Width, d14Height, d14Seconds},
+})
+
+// There's no example for this day - the one in the puzzle has a
+// smaller area, but no answer for part 2 - so the example values
+// here are never used.
+var d14Width = &dayParameter{
+	Name:        "width",
+	Description: "The width of the area the robots move around",
+	Default:     101,
+	Example:     101,
+	Min:         1,
+}
+
+var d14Height = &dayParameter{
+	Name:        "height",
+	Description: "The height of the area the robots move around",
+	Default:     103,
+	Example:     103,
+	Min:         1,
+}
+
+var d14Seconds = &dayParameter{
+	Name:        "seconds",
+	Description: "How many seconds part 1 lets the robots move for",
+	Default:     100,
+	Example:     100,
 }
 
 type d14robot struct {
@@ -29,21 +57,45 @@ type d14context struct {
 	areaWidth  int
 }
 
-func Day14Part1(logger *slog.Logger, input string) (string, any) {
+var d14RobotRegexp = regexp.MustCompile(`^p=(-?\d+),(-?\d+) v=(-?\d+),(-?\d+)$`)
+
+func Day14Part1(ctx context.Context, logger *slog.Logger, input string) (string, d14context, error) {
+	areaWidth, areaHeight := d14Width.get(ctx), d14Height.get(ctx)
+	seconds := d14Seconds.get(ctx)
+
 	// Parse input. Quick bit of regex practice
 	// can't hurt I suppose.
-	re := regexp.MustCompile(`[^\d-]+([-\d]+)[^\d-]+([-\d]+)[^\d-]+([-\d]+)[^\d-]+([-\d]+)`)
-	matches := re.FindAllStringSubmatch(input, -1)
-	robots := make([]d14robot, len(matches))
-	for ix, match := range matches {
-		r := &robots[ix]
+	lines := strings.Split(input, "\n")
+	robots := make([]d14robot, 0, len(lines))
+	for lineIx, line := range lines {
+		if len(line) == 0 {
+			continue
+		}
+		match := d14RobotRegexp.FindStringSubmatch(line)
+		if match == nil {
+			return "", d14context{}, errorAt(lineIx+1, 0, "expected a robot like p=0,4 v=3,-3, found %q", line)
+		}
+		// The regex has already made sure these are numbers.
+		var r d14robot
 		r.pos.col, _ = strconv.Atoi(match[1])
 		r.pos.row, _ = strconv.Atoi(match[2])
 		r.vector.col, _ = strconv.Atoi(match[3])
 		r.vector.row, _ = strconv.Atoi(match[4])
+		if r.pos.col < 0 || r.pos.col >= areaWidth || r.pos.row < 0 || r.pos.row >= areaHeight {
+			return "", d14context{}, errorAt(lineIx+1, 0, "robot starts outside the %dx%d area", areaWidth, areaHeight)
+		}
+		// Part 2 moves robots one second at a time and only
+		// wraps them once per second, so make sure that's
+		// enough.
+		r.vector.col %= areaWidth
+		r.vector.row %= areaHeight
+		robots = append(robots, r)
+	}
+	if len(robots) == 0 {
+		return "", d14context{}, errorAt(0, 0, "no robots found")
 	}
+	logger.Debug("Parsed input", slog.Int("robots", len(robots)))
 
-	areaWidth, areaHeight := 101, 103
 	middleCol, middleRow := areaWidth/2, areaHeight/2
 
 	// We need to simulate each robot independently, but we don't need
@@ -51,14 +103,8 @@ func Day14Part1(logger *slog.Logger, input string) (string, any) {
 	// to the other means we can just multiply and take the modulus.
 	var robotCounts [4]int
 	for _, robot := range robots {
-		finalRow := (robot.pos.row + (robot.vector.row * 100)) % areaHeight
-		finalCol := (robot.pos.col + (robot.vector.col * 100)) % areaWidth
-		if finalRow < 0 {
-			finalRow += areaHeight
-		}
-		if finalCol < 0 {
-			finalCol += areaWidth
-		}
+		final := robot.positionAfter(seconds, areaWidth, areaHeight)
+		finalRow, finalCol := final.row, final.col
 
 		// Calculate which quadrant this robot ends up in.
 		if finalRow < middleRow {
@@ -76,10 +122,43 @@ func Day14Part1(logger *slog.Logger, input string) (string, any) {
 		}
 	}
 
-	return strconv.Itoa(robotCounts[0] * robotCounts[1] * robotCounts[2] * robotCounts[3]), d14context{robots, areaHeight, areaWidth}
+	return strconv.Itoa(robotCounts[0] * robotCounts[1] * robotCounts[2] * robotCounts[3]), d14context{robots, areaHeight, areaWidth}, nil
+}
+
+// Where a robot will be after some number of seconds (which can be
+// negative, to go back in time).
+func (robot d14robot) positionAfter(seconds int, areaWidth int, areaHeight int) gridPos {
+	row := (robot.pos.row + (robot.vector.row * seconds)) % areaHeight
+	col := (robot.pos.col + (robot.vector.col * seconds)) % areaWidth
+	if row < 0 {
+		row += areaHeight
+	}
+	if col < 0 {
+		col += areaWidth
+	}
+	return gridPos{row, col}
 }
 
-func Day14Part2(logger *slog.Logger, input string, part1Context any) string {
+// A picture of where the robots are, with the number of robots on
+// each square, as in the puzzle.
+func renderD14Robots(robots []d14robot, areaWidth int, areaHeight int) string {
+	counts := newGrid[int](areaHeight, areaWidth)
+	for _, robot := range robots {
+		*counts.Ptr(robot.pos)++
+	}
+	return renderPicture(areaHeight, areaWidth, func(pos gridPos) rune {
+		switch count := counts.At(pos); {
+		case count == 0:
+			return '.'
+		case count > 9:
+			return '*'
+		default:
+			return rune('0' + count)
+		}
+	})
+}
+
+func Day14Part2(ctx context.Context, logger *slog.Logger, input string, part1Context d14context) (string, error) {
 	// It's fun time.
 	//
 	// The approach I've taken here is to assume that the picture will
@@ -96,46 +175,55 @@ func Day14Part2(logger *slog.Logger, input string, part1Context any) string {
 	// columns will repeat every 101 seconds, and along rows every 103
 	// seconds. We can therefore model the first 103 seconds to find
 	// the point of lowest variance among row coordinates and among
-	// column coordinates.
-	context := part1Context.(d14context)
+	// column coordinates. (Or whatever the width and height are, if
+	// they've been changed.)
+
+	// The maths at the end only works if the two cycles don't share
+	// a factor, so check that before doing anything else. For 101
+	// and 103, the inverse is 51.
+	widthInverse, ok := modInverse(part1Context.areaWidth, part1Context.areaHeight)
+	if !ok {
+		return "", errorAt(0, 0, "the area's width (%d) and height (%d) mustn't have a common factor", part1Context.areaWidth, part1Context.areaHeight)
+	}
 
 	rowSum, colSum := 0, 0
-	for _, robot := range context.robots {
+	for _, robot := range part1Context.robots {
 		rowSum += robot.pos.row
 		colSum += robot.pos.col
 	}
-	meanRow, meanCol := float64(rowSum)/float64(len(context.robots)), float64(colSum)/float64(len(context.robots))
+	meanRow, meanCol := float64(rowSum)/float64(len(part1Context.robots)), float64(colSum)/float64(len(part1Context.robots))
 
-	numRobots := float64(len(context.robots))
-	maxDimension := context.areaHeight
+	numRobots := float64(len(part1Context.robots))
+	maxDimension := max(part1Context.areaHeight, part1Context.areaWidth)
 
+	vis := visualiserFrom(ctx)
 	minRowVariance, minRowVarianceIteration, minColVariance, minColVarianceIteration := math.MaxFloat64, 0, math.MaxFloat64, 0
 	for i := 1; i <= maxDimension; i++ {
-		for ix := range context.robots {
-			newRow := context.robots[ix].pos.row + context.robots[ix].vector.row
-			if newRow >= context.areaHeight {
-				newRow -= context.areaHeight
+		for ix := range part1Context.robots {
+			newRow := part1Context.robots[ix].pos.row + part1Context.robots[ix].vector.row
+			if newRow >= part1Context.areaHeight {
+				newRow -= part1Context.areaHeight
 			} else if newRow < 0 {
-				newRow += context.areaHeight
+				newRow += part1Context.areaHeight
 			}
 			// Rather than recalculate the mean in full every second, we can
 			// tweak it according to each robot's delta as we process that
 			// robot.
-			meanRow += float64(newRow-context.robots[ix].pos.row) / numRobots
-			context.robots[ix].pos.row = newRow
+			meanRow += float64(newRow-part1Context.robots[ix].pos.row) / numRobots
+			part1Context.robots[ix].pos.row = newRow
 
-			newCol := context.robots[ix].pos.col + context.robots[ix].vector.col
-			if newCol >= context.areaWidth {
-				newCol -= context.areaWidth
+			newCol := part1Context.robots[ix].pos.col + part1Context.robots[ix].vector.col
+			if newCol >= part1Context.areaWidth {
+				newCol -= part1Context.areaWidth
 			} else if newCol < 0 {
-				newCol += context.areaWidth
+				newCol += part1Context.areaWidth
 			}
-			meanCol += float64(newCol-context.robots[ix].pos.col) / numRobots
-			context.robots[ix].pos.col = newCol
+			meanCol += float64(newCol-part1Context.robots[ix].pos.col) / numRobots
+			part1Context.robots[ix].pos.col = newCol
 		}
 
 		var rowVariance, colVariance float64
-		for _, robot := range context.robots {
+		for _, robot := range part1Context.robots {
 			rowDiff := float64(robot.pos.row) - meanRow
 			rowVariance += rowDiff * rowDiff
 			colDiff := float64(robot.pos.col) - meanCol
@@ -150,17 +238,68 @@ func Day14Part2(logger *slog.Logger, input string, part1Context any) string {
 			minColVariance = colVariance
 			minColVarianceIteration = i
 		}
+
+		if vis != nil {
+			second := i
+			vis.show(func() (string, string) {
+				return fmt.Sprintf("After %d seconds", second), renderD14Robots(part1Context.robots, part1Context.areaWidth, part1Context.areaHeight)
+			})
+		}
 	}
 
+	logger.Debug("Found lowest variances", slog.Int("rowIteration", minRowVarianceIteration), slog.Int("colIteration", minColVarianceIteration))
+
 	// The bit above was me. Now comes some maths bullshit that I
 	// shamelessly stole because I do AoC to practice programming,
 	// not maths. We need to find the point where the lowest-row-
 	// variance (which repeats every 103 seconds) coincides with
 	// the lowest-column-variance (which repeats every 101 seconds).
 	// The answer involves Chinese remainder theorem...
-	magicAnswer := minColVarianceIteration + (((51 * (minRowVarianceIteration - minColVarianceIteration)) % context.areaHeight) * context.areaWidth)
+	magicAnswer := minColVarianceIteration + (((widthInverse * (minRowVarianceIteration - minColVarianceIteration)) % part1Context.areaHeight) * part1Context.areaWidth)
 	if magicAnswer < 0 {
-		magicAnswer += (context.areaHeight * context.areaWidth)
+		magicAnswer += (part1Context.areaHeight * part1Context.areaWidth)
+	}
+
+	if images := imageRecorderFrom(ctx); vis != nil || images != nil {
+		// We've only simulated as far as the cycles go, so skip
+		// straight to the answer to see the picture.
+		answer := magicAnswer
+		picture := make([]d14robot, len(part1Context.robots))
+		for ix, robot := range part1Context.robots {
+			picture[ix].pos = robot.positionAfter(answer-maxDimension, part1Context.areaWidth, part1Context.areaHeight)
+		}
+		if vis != nil {
+			vis.show(func() (string, string) {
+				return fmt.Sprintf("After %d seconds, the robots make a picture", answer), renderD14Robots(picture, part1Context.areaWidth, part1Context.areaHeight)
+			})
+		}
+		if images != nil {
+			img := newGridImage(part1Context.areaHeight, part1Context.areaWidth, IMAGE_BLACK)
+			for _, robot := range picture {
+				img.fill(robot.pos, IMAGE_START)
+			}
+			images.add("picture", img)
+		}
+	}
+	return strconv.Itoa(magicAnswer), nil
+}
+
+// The number that, multiplied by a, gives 1 modulo m - if there is
+// one, which there only is if a and m have no common factor. This is
+// the extended Euclidean algorithm, which I also didn't come up with.
+func modInverse(a int, m int) (int, bool) {
+	t, newT := 0, 1
+	r, newR := m, ((a%m)+m)%m
+	for newR != 0 {
+		quotient := r / newR
+		t, newT = newT, t-quotient*newT
+		r, newR = newR, r-quotient*newR
+	}
+	if r != 1 {
+		return 0, false
+	}
+	if t < 0 {
+		t += m
 	}
-	return strconv.Itoa(magicAnswer)
+	return t, true
 }
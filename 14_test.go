@@ -0,0 +1,20 @@
+package main
+
+import "testing"
+
+func TestModInverse(t *testing.T) {
+	for _, test := range []struct {
+		a, m, want int
+		ok         bool
+	}{
+		{101, 103, 51, true},
+		{11, 7, 2, true},
+		{4, 6, 0, false},
+		{5, 1, 0, true},
+	} {
+		got, ok := modInverse(test.a, test.m)
+		if got != test.want || ok != test.ok {
+			t.Errorf("modInverse(%d, %d): got %d, %v, want %d, %v", test.a, test.m, got, ok, test.want, test.ok)
+		}
+	}
+}
@@ -1,14 +1,16 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"slices"
 	"strconv"
 	"strings"
-
-	runner "github.com/ThePants999/advent-of-code-go-runner"
 )
 
-var Day15 = runner.DayImplementation{
+var Day15 = newPuzzle(dayDefinition[nothing]{
 	DayNumber:    15,
 	ExecutePart1: Day15Part1,
 	ExecutePart2: Day15Part2,
@@ -35,7 +37,7 @@ vvv<<^>^v^^><<>>><>^<<><^vv^^<>vvv<>><^^v>^>vv<>v<<<<v<^v>^<^^>>>^<v<v
 v^^>>><<^^<>>^v^<v^vv<>v^<<>^<^v^v><^<<<><<^<v><v<>vv>>v><v^<vv<>v^<<^`,
 	ExamplePart1Answer: "10092",
 	ExamplePart2Answer: "9021",
-}
+})
 
 // There isn't much to say about today, so I'm not going to
 // thoroughly comment throughout. We're just genuinely simulating
@@ -53,222 +55,215 @@ const (
 	CRATE_RIGHT
 )
 
-func Day15Part1(logger *slog.Logger, input string) (string, any) {
-	lines := strings.Fields(input)
-	grid := make([][]gridContents, len(lines))
-	robotRow, robotCol := 0, 0
-	var sb strings.Builder
-	gridFinished := false
-	for rowIx, line := range lines {
-		if !gridFinished {
-			if line[0] != '#' {
-				gridFinished = true
-				grid = grid[:rowIx]
-			} else {
-				grid[rowIx] = make([]gridContents, len(line))
-				for colIx, char := range line {
-					switch char {
-					case '#':
-						grid[rowIx][colIx] = WALL
-					case 'O':
-						grid[rowIx][colIx] = CRATE
-					case '@':
-						robotRow, robotCol = rowIx, colIx
-					}
-				}
-			}
+// Parse the warehouse map and the robot's movements. In part 2,
+// everything except the robot is twice as wide.
+func parseD15Input(input string, wide bool) (grid Grid[gridContents], robot gridPos, movements []Direction, err error) {
+	lines := strings.Split(input, "\n")
+	mapEnd := slices.Index(lines, "")
+	if mapEnd == -1 {
+		return Grid[gridContents]{}, gridPos{}, nil, errorAt(0, 0, "expected a blank line between the map and the movements")
+	}
+	mapLines := lines[:mapEnd]
+	grid, markers, err := parseGridWithMarkers(mapLines, "@", func(char rune) (gridContents, bool) {
+		switch char {
+		case '#':
+			return WALL, true
+		case 'O':
+			return CRATE, true
+		case '.', '@':
+			return EMPTY, true
 		}
+		return EMPTY, false
+	})
+	if err != nil {
+		return Grid[gridContents]{}, gridPos{}, nil, err
+	}
+	// All the movement code relies on the walls stopping
+	// anything leaving the grid.
+	if err := checkBorder(mapLines, '#'); err != nil {
+		return Grid[gridContents]{}, gridPos{}, nil, err
+	}
+	robot = markers[0]
+	if wide {
+		grid = widenD15Grid(grid)
+		robot.col *= 2
+	}
 
-		if gridFinished {
-			sb.WriteString(line)
+	for lineIx := mapEnd + 1; lineIx < len(lines); lineIx++ {
+		for colIx, char := range lines[lineIx] {
+			dir, ok := directionFromArrow(char)
+			if !ok || !dir.IsOrthogonal() {
+				return Grid[gridContents]{}, gridPos{}, nil, errorAt(lineIx+1, colIx+1, "expected a movement, found %q", char)
+			}
+			movements = append(movements, dir)
 		}
 	}
 
-	movements := sb.String()
-	for _, char := range movements {
-		curRow, curCol := robotRow, robotCol
-		var delta int
-		var changingVar, changingRobotVar *int
-		switch char {
-		case '^':
-			delta = -1
-			changingVar = &curRow
-			changingRobotVar = &robotRow
-		case 'v':
-			delta = 1
-			changingVar = &curRow
-			changingRobotVar = &robotRow
-		case '>':
-			delta = 1
-			changingVar = &curCol
-			changingRobotVar = &robotCol
-		case '<':
-			delta = -1
-			changingVar = &curCol
-			changingRobotVar = &robotCol
+	return grid, robot, movements, nil
+}
+
+func widenD15Grid(grid Grid[gridContents]) Grid[gridContents] {
+	wide := newGrid[gridContents](grid.NumRows(), grid.NumCols()*2)
+	for pos, square := range grid.All() {
+		left, right := gridPos{pos.row, pos.col * 2}, gridPos{pos.row, pos.col*2 + 1}
+		switch square {
+		case WALL:
+			wide.Set(left, WALL)
+			wide.Set(right, WALL)
+		case CRATE:
+			wide.Set(left, CRATE_LEFT)
+			wide.Set(right, CRATE_RIGHT)
 		}
+	}
+	return wide
+}
+
+func Day15Part1(ctx context.Context, logger *slog.Logger, input string) (string, nothing, error) {
+	grid, robot, movements, err := parseD15Input(input, false)
+	if err != nil {
+		return "", nothing{}, err
+	}
+	logger.Debug("Parsed input", slog.Int("rows", grid.NumRows()), slog.Int("cols", grid.NumCols()), slog.Int("movements", len(movements)), slog.Any("robot", robot))
 
+	vis := visualiserFrom(ctx)
+	for moveIx, dir := range movements {
+		cur := robot
 		canMove := true
 		for {
-			*changingVar += delta
-			if grid[curRow][curCol] == WALL {
+			cur = cur.move(dir)
+			if grid.At(cur) == WALL {
 				canMove = false
 				break
-			} else if grid[curRow][curCol] == EMPTY {
+			} else if grid.At(cur) == EMPTY {
 				break
 			}
 		}
 		if canMove {
-			*changingRobotVar += delta
-			grid[robotRow][robotCol] = EMPTY
-			if robotRow != curRow || robotCol != curCol {
-				grid[curRow][curCol] = CRATE
+			robot = robot.move(dir)
+			grid.Set(robot, EMPTY)
+			if robot != cur {
+				grid.Set(cur, CRATE)
 			}
 		}
+		if vis != nil {
+			showD15Move(vis, grid, robot, moveIx, len(movements))
+		}
 	}
 
 	sum := 0
-	for rowIx, row := range grid {
-		for colIx, space := range row {
-			if space == CRATE {
-				sum += (rowIx * 100) + colIx
-			}
+	for pos, space := range grid.All() {
+		if space == CRATE {
+			sum += (pos.row * 100) + pos.col
 		}
 	}
-	return strconv.Itoa(sum), nil
+	return strconv.Itoa(sum), nothing{}, nil
 }
 
-func Day15Part2(logger *slog.Logger, input string, part1Context any) string {
-	lines := strings.Fields(input)
-	grid := make([][]gridContents, len(lines))
-	robotRow, robotCol := 0, 0
-	var sb strings.Builder
-	gridFinished := false
-	for rowIx, line := range lines {
-		if !gridFinished {
-			if line[0] != '#' {
-				gridFinished = true
-				grid = grid[:rowIx]
-			} else {
-				grid[rowIx] = make([]gridContents, len(line)*2)
-				for colIx, char := range line {
-					switch char {
-					case '#':
-						grid[rowIx][colIx*2] = WALL
-						grid[rowIx][colIx*2+1] = WALL
-					case 'O':
-						grid[rowIx][colIx*2] = CRATE_LEFT
-						grid[rowIx][colIx*2+1] = CRATE_RIGHT
-					case '@':
-						robotRow, robotCol = rowIx, colIx*2
-					}
-				}
-			}
-		}
-
-		if gridFinished {
-			sb.WriteString(line)
-		}
+func Day15Part2(ctx context.Context, logger *slog.Logger, input string, _ nothing) (string, error) {
+	grid, robot, movements, err := parseD15Input(input, true)
+	if err != nil {
+		return "", err
 	}
+	logger.Debug("Parsed and widened input", slog.Int("rows", grid.NumRows()), slog.Int("cols", grid.NumCols()), slog.Any("robot", robot))
 
-	movements := sb.String()
-	for _, char := range movements {
-		var dir Direction
-		targetRow, targetCol := robotRow, robotCol
-		switch char {
-		case '^':
-			dir = UP
-			targetRow--
-		case 'v':
-			dir = DOWN
-			targetRow++
-		case '>':
-			dir = RIGHT
-			targetCol++
-		case '<':
-			dir = LEFT
-			targetCol--
+	vis := visualiserFrom(ctx)
+	for moveIx, dir := range movements {
+		target := robot.move(dir)
+		if canMove(grid, target, dir) {
+			if err := doMove(grid, target, dir, EMPTY); err != nil {
+				return "", err
+			}
+			robot = target
 		}
-
-		if canMove(grid, targetRow, targetCol, dir) {
-			doMove(grid, targetRow, targetCol, dir, EMPTY)
-			robotRow = targetRow
-			robotCol = targetCol
+		if vis != nil {
+			showD15Move(vis, grid, robot, moveIx, len(movements))
 		}
 	}
 
 	sum := 0
-	for rowIx, row := range grid {
-		for colIx, space := range row {
-			if space == CRATE_LEFT {
-				sum += (rowIx * 100) + colIx
-			}
+	for pos, space := range grid.All() {
+		if space == CRATE_LEFT {
+			sum += (pos.row * 100) + pos.col
 		}
 	}
-	return strconv.Itoa(sum)
+	return strconv.Itoa(sum), nil
+}
+
+// Show the warehouse after a move, drawn the same way as the map in
+// the input.
+func showD15Move(vis *visualiser, grid Grid[gridContents], robot gridPos, moveIx int, numMovements int) {
+	vis.show(func() (string, string) {
+		return fmt.Sprintf("Move %d of %d", moveIx+1, numMovements), renderPicture(grid.NumRows(), grid.NumCols(), func(pos gridPos) rune {
+			if pos == robot {
+				return '@'
+			}
+			return [...]rune{EMPTY: '.', WALL: '#', CRATE: 'O', CRATE_LEFT: '[', CRATE_RIGHT: ']'}[grid.At(pos)]
+		})
+	})
 }
 
-func doMove(grid [][]gridContents, row int, col int, dir Direction, incoming gridContents) {
-	if grid[row][col] == WALL {
-		panic("Hit a wall")
+// canMove() should already have established that this move is
+// possible, so hitting a wall here means we've got our logic wrong
+// rather than anything being wrong with the input.
+func doMove(grid Grid[gridContents], pos gridPos, dir Direction, incoming gridContents) error {
+	square := grid.At(pos)
+	if square == WALL {
+		return errorAt(0, 0, "crate hit a wall at row %d, column %d", pos.row, pos.col)
 	}
-	if grid[row][col] == CRATE {
-		panic("Found a part 1 crate!")
+	if square == CRATE {
+		return errorAt(0, 0, "found a part 1 crate at row %d, column %d", pos.row, pos.col)
 	}
-	if grid[row][col] != EMPTY {
-		rowDelta := 1
+	if square != EMPTY {
+		var err error
 		switch dir {
-		case LEFT:
-			doMove(grid, row, col-1, dir, grid[row][col])
-		case RIGHT:
-			doMove(grid, row, col+1, dir, grid[row][col])
-		case UP:
-			rowDelta = -1
-			fallthrough
-		case DOWN:
-			if grid[row][col] == CRATE_LEFT {
-				grid[row][col] = EMPTY
-				grid[row][col+1] = EMPTY
-				doMove(grid, row+rowDelta, col, dir, CRATE_LEFT)
-				doMove(grid, row+rowDelta, col+1, dir, CRATE_RIGHT)
-			} else {
-				grid[row][col] = EMPTY
-				grid[row][col-1] = EMPTY
-				doMove(grid, row+rowDelta, col-1, dir, CRATE_LEFT)
-				doMove(grid, row+rowDelta, col, dir, CRATE_RIGHT)
+		case LEFT, RIGHT:
+			err = doMove(grid, pos.move(dir), dir, square)
+		case UP, DOWN:
+			// Find both halves of the crate, and push each of them.
+			left := pos
+			if square == CRATE_RIGHT {
+				left.col--
 			}
+			right := gridPos{left.row, left.col + 1}
+			grid.Set(left, EMPTY)
+			grid.Set(right, EMPTY)
+			err = errors.Join(
+				doMove(grid, left.move(dir), dir, CRATE_LEFT),
+				doMove(grid, right.move(dir), dir, CRATE_RIGHT))
+		}
+		if err != nil {
+			return err
 		}
 	}
 
-	grid[row][col] = incoming
+	grid.Set(pos, incoming)
+	return nil
 }
 
-func canMove(grid [][]gridContents, row int, col int, dir Direction) bool {
-	if grid[row][col] == WALL {
+func canMove(grid Grid[gridContents], pos gridPos, dir Direction) bool {
+	square := grid.At(pos)
+	if square == WALL {
 		return false
 	}
-	if grid[row][col] == EMPTY {
+	if square == EMPTY {
 		return true
 	}
-	rowDelta := 1
 	switch dir {
-	case LEFT:
-		return canMove(grid, row, col-2, dir)
-	case RIGHT:
-		return canMove(grid, row, col+2, dir)
-	case UP:
-		rowDelta = -1
-		fallthrough
-	case DOWN:
-		if canMove(grid, row+rowDelta, col, dir) {
-			if grid[row][col] == CRATE_LEFT {
-				return canMove(grid, row+rowDelta, col+1, dir)
+	case LEFT, RIGHT:
+		// Skip over the other half of the crate.
+		return canMove(grid, pos.move(dir).move(dir), dir)
+	case UP, DOWN:
+		ahead := pos.move(dir)
+		if canMove(grid, ahead, dir) {
+			if square == CRATE_LEFT {
+				return canMove(grid, ahead.move(RIGHT), dir)
 			} else {
-				return canMove(grid, row+rowDelta, col-1, dir)
+				return canMove(grid, ahead.move(LEFT), dir)
 			}
 		} else {
 			return false
 		}
 	}
-	panic("Should be unreachable")
+	// Not a direction we know how to move in.
+	return false
 }
@@ -1,17 +1,18 @@
 package main
 
 import (
+	"context"
+	"fmt"
+	"image/color"
 	"log/slog"
 	"math"
 	"strconv"
 	"strings"
 
 	stack "github.com/golang-collections/collections/stack"
-
-	runner "github.com/ThePants999/advent-of-code-go-runner"
 )
 
-var Day16 = runner.DayImplementation{
+var Day16 = newPuzzle(dayDefinition[d16context]{
 	DayNumber:    16,
 	ExecutePart1: Day16Part1,
 	ExecutePart2: Day16Part2,
@@ -32,45 +33,21 @@ var Day16 = runner.DayImplementation{
 ###############`,
 	ExamplePart1Answer: "7036",
 	ExamplePart2Answer: "45",
-}
-
-func (dir direction6) turn(clockwise bool) direction6 {
-	newDir := dir + 1
-	if !clockwise {
-		newDir = dir - 1
-	}
-
-	if newDir > D6_LEFT {
-		newDir = D6_UP
-	} else if newDir < 0 {
-		newDir = D6_LEFT
-	}
-
-	return newDir
-}
-
-func (pos gridPos) move(dir direction6) gridPos {
-	switch dir {
-	case D6_UP:
-		pos.row--
-	case D6_DOWN:
-		pos.row++
-	case D6_LEFT:
-		pos.col--
-	case D6_RIGHT:
-		pos.col++
-	}
-	return pos
-}
+})
 
 type d16GridSquare struct {
 	wall  bool
 	nodes [4]d16Node
 }
 
+// One node per direction we could be facing in this square.
+func (square *d16GridSquare) node(dir Direction) *d16Node {
+	return &square.nodes[dir.orthogonalIndex()]
+}
+
 type d16NodeId struct {
 	pos gridPos
-	dir direction6
+	dir Direction
 }
 
 type d16Node struct {
@@ -172,102 +149,168 @@ func (node *d16Node) UpdateCost(newCost int) {
 }
 
 type d16context struct {
-	grid     [][]d16GridSquare
-	startRow int
-	startCol int
-	endRow   int
-	endCol   int
+	grid  Grid[d16GridSquare]
+	start gridPos
+	end   gridPos
 }
 
-func updateFrom(grid [][]d16GridSquare, node *d16Node) {
+func updateFrom(grid Grid[d16GridSquare], node *d16Node) {
 	// Per Dijkstra's algorithm, update the costs of nodes reachable from this one
 	// (which are: moving forwards, turning left, turning right). However, we don't
 	// bother to assign costs to turning left and right if they would face a wall
 	// as we trivially know that's never going to be optimal.
 	frontPos := node.id.pos.move(node.id.dir)
-	leftDir := node.id.dir.turn(false)
+	leftDir := node.id.dir.TurnLeft()
 	leftPos := node.id.pos.move(leftDir)
-	rightDir := node.id.dir.turn(true)
+	rightDir := node.id.dir.TurnRight()
 	rightPos := node.id.pos.move(rightDir)
-	if !grid[leftPos.row][leftPos.col].wall && node.cost+1000 < grid[node.id.pos.row][node.id.pos.col].nodes[leftDir].cost {
-		grid[node.id.pos.row][node.id.pos.col].nodes[leftDir].UpdateCost(node.cost + 1000)
+	here := grid.Ptr(node.id.pos)
+	if !grid.At(leftPos).wall && node.cost+1000 < here.node(leftDir).cost {
+		here.node(leftDir).UpdateCost(node.cost + 1000)
 	}
-	if !grid[rightPos.row][rightPos.col].wall && node.cost+1000 < grid[node.id.pos.row][node.id.pos.col].nodes[rightDir].cost {
-		grid[node.id.pos.row][node.id.pos.col].nodes[rightDir].UpdateCost(node.cost + 1000)
+	if !grid.At(rightPos).wall && node.cost+1000 < here.node(rightDir).cost {
+		here.node(rightDir).UpdateCost(node.cost + 1000)
 	}
-	if node.cost+1 < grid[frontPos.row][frontPos.col].nodes[node.id.dir].cost {
+	front := grid.Ptr(frontPos)
+	if node.cost+1 < front.node(node.id.dir).cost {
 		// Node that there's no need to check to see whether there's a wall in front
 		// of us - walls nodes never get initialised with an infinite cost, so they'll
 		// always appear to be 0-cost already, but they're also not on the heap.
-		grid[frontPos.row][frontPos.col].nodes[node.id.dir].UpdateCost(node.cost + 1)
+		front.node(node.id.dir).UpdateCost(node.cost + 1)
 	}
 }
 
-func Day16Part1(logger *slog.Logger, input string) (string, any) {
-	lines := strings.Fields(input)
-	grid := make([][]d16GridSquare, len(lines))
-	startRow, startCol := 0, 0
-	endRow, endCol := 0, 0
-	heap := NewHeap()
-	for rowIx, row := range lines {
-		grid[rowIx] = make([]d16GridSquare, len(row))
-		for colIx, square := range row {
-			switch square {
-			case '#':
-				grid[rowIx][colIx].wall = true
-			case 'S':
-				startRow, startCol = rowIx, colIx
-			case 'E':
-				endRow, endCol = rowIx, colIx
+// A picture of the maze showing how far Dijkstra's got: squares
+// it's finished with, and the frontier of squares it's found a way
+// to but hasn't finished with yet. Or, once we know them, the best
+// paths.
+func renderD16Grid(grid Grid[d16GridSquare], start gridPos, end gridPos, bestPaths map[gridPos]nothing) string {
+	return renderPicture(grid.NumRows(), grid.NumCols(), func(pos gridPos) rune {
+		square := grid.Ptr(pos)
+		if _, best := bestPaths[pos]; best {
+			return 'O'
+		}
+		switch {
+		case pos == start:
+			return 'S'
+		case pos == end:
+			return 'E'
+		case square.wall:
+			return '#'
+		case bestPaths != nil:
+			return '.'
+		}
+		picture := '.'
+		for _, dir := range ORTHOGONAL_DIRECTIONS {
+			node := square.node(dir)
+			if node.cost == math.MaxInt {
+				continue
+			}
+			if node.heap != nil {
+				// Still in the queue, so this is the frontier.
+				return '*'
 			}
+			picture = 'o'
+		}
+		return picture
+	})
+}
 
-			// Every space that's not a wall needs to be a graph node
-			// (well, technically, four graph nodes, one per direction)
-			// and to be in the priority queue for the upcoming
-			// Dijkstra.
-			if !grid[rowIx][colIx].wall {
-				for dir := D6_UP; dir <= D6_LEFT; dir++ {
-					grid[rowIx][colIx].nodes[dir].id.pos = gridPos{rowIx, colIx}
-					grid[rowIx][colIx].nodes[dir].id.dir = dir
-					grid[rowIx][colIx].nodes[dir].cost = math.MaxInt
-					heap.Push(&grid[rowIx][colIx].nodes[dir])
-				}
+// The maze with the squares on best paths picked out.
+func drawD16BestPaths(part1Context d16context, bestPaths map[gridPos]nothing) *gridImage {
+	img := newGridImage(part1Context.grid.NumRows(), part1Context.grid.NumCols(), IMAGE_WHITE)
+	for pos, square := range part1Context.grid.All() {
+		if square.wall {
+			img.fill(pos, IMAGE_WALL)
+		}
+	}
+	for pos := range bestPaths {
+		img.fill(pos, color.RGBA{0xf0, 0xc0, 0x30, 0xff})
+	}
+	img.fill(part1Context.start, IMAGE_START)
+	img.fill(part1Context.end, IMAGE_END)
+	return img
+}
+
+func Day16Part1(ctx context.Context, logger *slog.Logger, input string) (string, d16context, error) {
+	lines := strings.Fields(input)
+	grid, markers, err := parseGridWithMarkers(lines, "SE", func(square rune) (d16GridSquare, bool) {
+		return d16GridSquare{wall: square == '#'}, square == '#' || square == '.' || square == 'S' || square == 'E'
+	})
+	if err != nil {
+		return "", d16context{}, err
+	}
+	// updateFrom() relies on walls to stop it leaving the grid.
+	if err := checkBorder(lines, '#'); err != nil {
+		return "", d16context{}, err
+	}
+	start, end := markers[0], markers[1]
+
+	// Every space that's not a wall needs to be a graph node
+	// (well, technically, four graph nodes, one per direction)
+	// and to be in the priority queue for the upcoming
+	// Dijkstra.
+	heap := NewHeap()
+	numNodes := 0
+	for pos := range grid.All() {
+		square := grid.Ptr(pos)
+		if !square.wall {
+			for _, dir := range ORTHOGONAL_DIRECTIONS {
+				node := square.node(dir)
+				node.id.pos = pos
+				node.id.dir = dir
+				node.cost = math.MaxInt
+				heap.Push(node)
+				numNodes++
 			}
 		}
 	}
+	logger.Debug("Parsed input", slog.Int("rows", grid.NumRows()), slog.Int("cols", grid.NumCols()), slog.Int("nodes", numNodes))
 
 	// Run Dijkstra's algorithm over the maze, treating each combination
 	// of grid position and facing as a different node in the graph.
-	grid[startRow][startCol].nodes[D6_RIGHT].UpdateCost(0)
+	grid.Ptr(start).node(RIGHT).UpdateCost(0)
+	vis := visualiserFrom(ctx)
+	popped := 0
 	for !heap.IsEmpty() {
 		node := heap.Pop()
-		if node.id.pos.row == endRow && node.id.pos.col == endCol {
+		popped++
+		if node.id.pos == end {
 			// Once we've popped the end, no other nodes can be on a best
 			// path.
 			break
 		}
 		updateFrom(grid, node)
+		if vis != nil {
+			visitedSoFar := popped
+			vis.show(func() (string, string) {
+				return fmt.Sprintf("Visited %d nodes (o), with the frontier at *", visitedSoFar), renderD16Grid(grid, start, end, nil)
+			})
+		}
 	}
+	logger.Debug("Finished Dijkstra", slog.Int("nodesVisited", popped))
 
 	// Bit of cheekiness - we know the end is in the top-right
 	// corner, let's assume that all best paths end in the up
 	// and/or right directions, so we just need to see which of
 	// those has the lowest cost.
-	bestCost := grid[endRow][endCol].nodes[D6_UP].cost
-	if grid[endRow][endCol].nodes[D6_RIGHT].cost < bestCost {
-		bestCost = grid[endRow][endCol].nodes[D6_RIGHT].cost
+	endSquare := grid.At(end)
+	bestCost := min(endSquare.node(UP).cost, endSquare.node(RIGHT).cost)
+
+	if bestCost == math.MaxInt {
+		return "", d16context{}, errorAt(end.row+1, end.col+1, "the end can't be reached (facing up or right) from the start")
 	}
 
-	return strconv.Itoa(bestCost), d16context{grid, startRow, startCol, endRow, endCol}
+	return strconv.Itoa(bestCost), d16context{grid, start, end}, nil
 }
 
-func Day16Part2(logger *slog.Logger, input string, part1Context any) string {
-	context := part1Context.(d16context)
+func Day16Part2(ctx context.Context, logger *slog.Logger, input string, part1Context d16context) (string, error) {
 	s := stack.New()
 	visited := make(map[d16NodeId]nothing)
 	// Repeat of aforementioned cheekiness.
-	s.Push(&context.grid[context.endRow][context.endCol].nodes[D6_RIGHT])
-	s.Push(&context.grid[context.endRow][context.endCol].nodes[D6_UP])
+	endSquare := part1Context.grid.Ptr(part1Context.end)
+	s.Push(endSquare.node(RIGHT))
+	s.Push(endSquare.node(UP))
 
 	// What we're going to do now is run a simple DFS, but we're going to
 	// do it in reverse from the end, and we're only going to allow
@@ -285,13 +328,13 @@ func Day16Part2(logger *slog.Logger, input string, part1Context any) string {
 		}
 		visited[node.id] = nothing{}
 
-		thisGrid := &context.grid[node.id.pos.row][node.id.pos.col]
-		backPos := node.id.pos.move(node.id.dir.turn(false).turn(false))
-		backNode := &context.grid[backPos.row][backPos.col].nodes[node.id.dir]
-		leftDir := node.id.dir.turn(false)
-		leftNode := &thisGrid.nodes[leftDir]
-		rightDir := node.id.dir.turn(true)
-		rightNode := &thisGrid.nodes[rightDir]
+		thisGrid := part1Context.grid.Ptr(node.id.pos)
+		backPos := node.id.pos.move(node.id.dir.Reverse())
+		backNode := part1Context.grid.Ptr(backPos).node(node.id.dir)
+		leftDir := node.id.dir.TurnLeft()
+		leftNode := thisGrid.node(leftDir)
+		rightDir := node.id.dir.TurnRight()
+		rightNode := thisGrid.node(rightDir)
 		if leftNode.cost == node.cost-1000 {
 			s.Push(leftNode)
 		}
@@ -307,6 +350,16 @@ func Day16Part2(logger *slog.Logger, input string, part1Context any) string {
 	for node := range visited {
 		bestPaths[node.pos] = nothing{}
 	}
+	logger.Debug("Traced best paths", slog.Int("nodes", len(visited)))
+
+	if images := imageRecorderFrom(ctx); images != nil {
+		images.add("best-paths", drawD16BestPaths(part1Context, bestPaths))
+	}
+	if vis := visualiserFrom(ctx); vis != nil {
+		vis.show(func() (string, string) {
+			return fmt.Sprintf("%d squares are on a best path (O)", len(bestPaths)), renderD16Grid(part1Context.grid, part1Context.start, part1Context.end, bestPaths)
+		})
+	}
 
-	return strconv.Itoa(len(bestPaths))
+	return strconv.Itoa(len(bestPaths)), nil
 }
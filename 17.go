@@ -1,15 +1,15 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log/slog"
 	"slices"
 	"strconv"
 	"strings"
-
-	runner "github.com/ThePants999/advent-of-code-go-runner"
 )
 
-var Day17 = runner.DayImplementation{
+var Day17 = newPuzzle(dayDefinition[d17Program]{
 	DayNumber:    17,
 	ExecutePart1: Day17Part1,
 	ExecutePart2: Day17Part2,
@@ -20,7 +20,7 @@ Register C: 0
 Program: 0,3,5,4,3,0`,
 	ExamplePart1Answer: "5,7,3,0",
 	ExamplePart2Answer: "117440",
-}
+})
 
 const (
 	INS_ADV int = iota
@@ -40,28 +40,49 @@ type d17Program struct {
 	regC int
 }
 
-func (prog *d17Program) Execute() []int {
+// Run the program. Nothing guarantees that it ever halts, so we
+// also stop if checker says we've been cancelled.
+func (prog *d17Program) Execute(checker *cancelChecker) ([]int, error) {
 	insPtr := 0
 	output := make([]int, 0, len(prog.data))
 
-	for insPtr < len(prog.data) {
+	// The program halts when it tries to read an opcode past the
+	// end, and we treat a missing operand the same way.
+	for insPtr+1 < len(prog.data) {
+		if err := checker.check(); err != nil {
+			return nil, err
+		}
 		instruction := prog.data[insPtr]
 		literal := prog.data[insPtr+1]
-		combo := comboOperand(literal, prog.regA, prog.regB, prog.regC)
 
 		switch instruction {
-		case INS_ADV:
-			prog.regA >>= combo
 		case INS_BXL:
 			prog.regB ^= literal
-		case INS_BST:
-			prog.regB = combo % 8
+			insPtr += 2
+			continue
 		case INS_JNZ:
 			if prog.regA != 0 {
-				insPtr = literal - 2
+				insPtr = literal
+			} else {
+				insPtr += 2
 			}
+			continue
 		case INS_BXC:
 			prog.regB = prog.regB ^ prog.regC
+			insPtr += 2
+			continue
+		}
+
+		// Everything else takes a combo operand.
+		combo, err := comboOperand(literal, prog.regA, prog.regB, prog.regC)
+		if err != nil {
+			return nil, errorAt(5, 0, "instruction %d: %v", insPtr, err)
+		}
+		switch instruction {
+		case INS_ADV:
+			prog.regA >>= combo
+		case INS_BST:
+			prog.regB = combo % 8
 		case INS_OUT:
 			output = append(output, combo%8)
 		case INS_BDV:
@@ -72,82 +93,146 @@ func (prog *d17Program) Execute() []int {
 		insPtr += 2
 	}
 
-	return output
+	return output, nil
 }
 
-func Day17Part1(logger *slog.Logger, input string) (string, any) {
+// Read a line of the form "<prefix><number>". The registers can't
+// be negative - nothing the program does can make them so, and
+// adv and friends would be shifting by a negative amount.
+func d17ReadRegister(lines []string, lineIx int, prefix string) (int, error) {
+	if lineIx >= len(lines) || !strings.HasPrefix(lines[lineIx], prefix) {
+		return 0, errorAt(lineIx+1, 1, "expected a line starting %q", prefix)
+	}
+	value, err := atoiAt(lines[lineIx][len(prefix):], lineIx+1, len(prefix)+1)
+	if err == nil && value < 0 {
+		return 0, errorAt(lineIx+1, len(prefix)+1, "a register can't be negative, found %d", value)
+	}
+	return value, err
+}
+
+func Day17Part1(ctx context.Context, logger *slog.Logger, input string) (string, d17Program, error) {
 	// Parse the input.
 	lines := strings.Split(input, "\n")
 	prog := d17Program{}
-	prog.regA, _ = strconv.Atoi(lines[0][12:])
-	prog.regB, _ = strconv.Atoi(lines[1][12:])
-	prog.regC, _ = strconv.Atoi(lines[2][12:])
+	var err error
+	if prog.regA, err = d17ReadRegister(lines, 0, "Register A: "); err != nil {
+		return "", d17Program{}, err
+	}
+	if prog.regB, err = d17ReadRegister(lines, 1, "Register B: "); err != nil {
+		return "", d17Program{}, err
+	}
+	if prog.regC, err = d17ReadRegister(lines, 2, "Register C: "); err != nil {
+		return "", d17Program{}, err
+	}
+	if len(lines) < 5 || !strings.HasPrefix(lines[4], "Program: ") {
+		return "", d17Program{}, errorAt(5, 1, "expected a line starting \"Program: \"")
+	}
 	dataStrs := strings.Split(lines[4][9:], ",")
 	prog.data = make([]int, len(dataStrs))
+	column := 10
 	for ix, str := range dataStrs {
-		prog.data[ix], _ = strconv.Atoi(str)
+		if len(str) != 1 || str[0] < '0' || str[0] > '7' {
+			return "", d17Program{}, errorAt(5, column, "expected a 3-bit number, found %q", str)
+		}
+		prog.data[ix] = int(str[0] - '0')
+		column += len(str) + 1
 	}
+	logger.Debug("Parsed input", slog.Int("programLength", len(prog.data)), slog.Int("regA", prog.regA))
 
 	// Part 1 is simple enough - genuinely run the
 	// program.
 	progCopy := prog
-	output := progCopy.Execute()
+	output, err := progCopy.Execute(newCancelChecker(ctx))
+	if err != nil {
+		return "", d17Program{}, err
+	}
 	var result strings.Builder
-	for _, val := range output {
+	for ix, val := range output {
+		if ix > 0 {
+			result.WriteString(",")
+		}
 		result.WriteString(strconv.Itoa(val))
-		result.WriteString(",")
 	}
-	resStr := result.String()
-	return resStr[:len(resStr)-1], prog
+	return result.String(), prog, nil
 }
 
-func comboOperand(operand int, regA int, regB int, regC int) int {
+func comboOperand(operand int, regA int, regB int, regC int) (int, error) {
 	switch {
 	case operand >= 0 && operand <= 3:
-		return operand
+		return operand, nil
 	case operand == 4:
-		return regA
+		return regA, nil
 	case operand == 5:
-		return regB
+		return regB, nil
 	case operand == 6:
-		return regC
+		return regC, nil
 	default:
-		panic("Unexpected operand")
+		return 0, fmt.Errorf("invalid combo operand %d", operand)
+	}
+}
+
+func Day17Part2(ctx context.Context, logger *slog.Logger, input string, prog d17Program) (string, error) {
+	answer, found, tried, err := prog.findSelfReplicatingA(newCancelChecker(ctx))
+	if err != nil {
+		return "", err
 	}
+	logger.Debug("Searched", slog.Int("candidatesTried", tried))
+	if !found {
+		return "", errorAt(5, 0, "no value of register A makes the program output itself")
+	}
+	return strconv.Itoa(answer), nil
 }
 
-func Day17Part2(logger *slog.Logger, input string, part1Context any) string {
-	prog := part1Context.(d17Program)
-
-	candidateA := 0
-	// It is approximately the case that each 3 bits of register A will
-	// determine one output value, with the least significant bits
-	// corresponding to the first output value. So what we're going to do
-	// looks like this:
-	// -  Try a set of small A values until we find one that outputs the
-	//    LAST output value.
-	// -  When we find it, "lock it in" by shifting 3 bits left. We don't
-	//    technically lock it in - we can change those bits further - but
-	//    they're our starting point for what follows.
-	// -  We then start trying candidates incrementally to find one that
-	//    outputs the last TWO output values. It's possible for bits
-	//    more significant than the last three to influence the last
-	//    output value, so the bits we've "locked in" might be wrong, and
-	//    that's why we need to check the full set of output values so far
-	//    on every iteration. But we shouldn't need to change too much of
-	//    what we've "locked in" before we find the right combo.
+// Find the lowest positive value of register A that makes the program
+// output itself, if there is one, and say how many we tried.
+func (prog d17Program) findSelfReplicatingA(checker *cancelChecker) (int, bool, int, error) {
+	// Each time round the loop, the program works out one output value
+	// from register A and then shifts A right by 3 bits. So each 3
+	// bits of A determine one output value (with some help from the
+	// bits above them), with the least significant bits corresponding
+	// to the first output value - and the output for A is the output
+	// for A>>3 with one more value on the front. So what we're going to
+	// do looks like this:
+	// -  Try the eight 3-bit values of A and see which output the LAST
+	//    output value.
+	// -  For each one that does, shift it 3 bits left and try the eight
+	//    values of the new bottom 3 bits, to see which output the last
+	//    TWO output values.
 	// -  Repeat until we've got the whole set of output values.
-	for pos := len(prog.data) - 1; pos >= 0; pos-- {
-		candidateA <<= 3
-		for {
-			prog.regA = candidateA
-			output := prog.Execute()
+	//
+	// I used to just take the first candidate that worked at each
+	// stage and then count upwards from there if nothing built on it
+	// did, but the reference tests turned up programs where that
+	// meant counting for ever. So now it's a depth-first search that
+	// backs up and tries the next candidate. Trying the bits in
+	// increasing order means the first full match is the smallest.
+	tried := 0
+	var search func(pos int, candidateA int) (int, bool, error)
+	search = func(pos int, candidateA int) (int, bool, error) {
+		if pos < 0 {
+			return candidateA >> 3, true, nil
+		}
+		for bits := range 8 {
+			prog.regA = candidateA | bits
+			if prog.regA == 0 {
+				// Zero outputs one value without looping, but it's
+				// not a positive answer and nothing builds on it.
+				continue
+			}
+			output, err := prog.Execute(checker)
+			if err != nil {
+				return 0, false, err
+			}
+			tried++
 			if slices.Equal(output, prog.data[pos:]) {
-				break
+				if answer, found, err := search(pos-1, (candidateA|bits)<<3); found || err != nil {
+					return answer, found, err
+				}
 			}
-			candidateA++
 		}
+		return 0, false, nil
 	}
 
-	return strconv.Itoa(candidateA)
+	answer, found, err := search(len(prog.data)-1, 0)
+	return answer, found, tried, err
 }
@@ -1,16 +1,16 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log/slog"
 	"strconv"
 	"strings"
 
 	"github.com/golang-collections/collections/queue"
-
-	runner "github.com/ThePants999/advent-of-code-go-runner"
 )
 
-var Day18 = runner.DayImplementation{
+var Day18 = newPuzzle(dayDefinition[d18Context]{
 	DayNumber:    18,
 	ExecutePart1: Day18Part1,
 	ExecutePart2: Day18Part2,
@@ -41,12 +41,23 @@ var Day18 = runner.DayImplementation{
 2,0`,
 	ExamplePart1Answer: "22",
 	ExamplePart2Answer: "6,1",
+	Parameters:         []*dayParameter{d18GridSize, d18StartAfter},
+})
+
+var d18GridSize = &dayParameter{
+	Name:        "gridSize",
+	Description: "The width and height of the memory space",
+	Default:     71,
+	Example:     7,
+	Min:         1,
 }
 
-const GRID_SIZE_EXAMPLE int = 7
-const GRID_SIZE_REAL int = 71
-const START_AFTER_EXAMPLE int = 12
-const START_AFTER_REAL int = 1024
+var d18StartAfter = &dayParameter{
+	Name:        "startAfter",
+	Description: "How many bytes have fallen when part 1 looks for a path",
+	Default:     1024,
+	Example:     12,
+}
 
 type WallSetStatus int
 
@@ -69,40 +80,12 @@ type d18MergeResult struct {
 }
 
 type d18Context struct {
-	grid     [][]d18GridSquare
+	grid     Grid[d18GridSquare]
 	gridSize int
+	bytes    []gridPos
 	lines    []string
 }
 
-func (p gridPos) allAdjacencies(numRows int, numCols int) []gridPos {
-	adj := make([]gridPos, 0, 8)
-	if p.row > 0 {
-		adj = append(adj, gridPos{p.row - 1, p.col})
-		if p.col > 0 {
-			adj = append(adj, gridPos{p.row - 1, p.col - 1})
-		}
-		if p.col < numCols-1 {
-			adj = append(adj, gridPos{p.row - 1, p.col + 1})
-		}
-	}
-	if p.row < numRows-1 {
-		adj = append(adj, gridPos{p.row + 1, p.col})
-		if p.col > 0 {
-			adj = append(adj, gridPos{p.row + 1, p.col - 1})
-		}
-		if p.col < numCols-1 {
-			adj = append(adj, gridPos{p.row + 1, p.col + 1})
-		}
-	}
-	if p.col > 0 {
-		adj = append(adj, gridPos{p.row, p.col - 1})
-	}
-	if p.col < numCols-1 {
-		adj = append(adj, gridPos{p.row, p.col + 1})
-	}
-	return adj
-}
-
 func findDisjointSetRoot(square *d18GridSquare) *d18GridSquare {
 	if square.disjointSetParent != nil {
 		// We take this opportunity to flatten the tree by replacing
@@ -164,22 +147,18 @@ func mergeDisjointSets(squareA *d18GridSquare, squareB *d18GridSquare) d18MergeR
 // bottom-left-connected set with a top-right-connected set, that's the
 // moment we make it impossible to traverse from top left to bottom right.
 // (This function returns true when that happens.)
-func createWall(grid [][]d18GridSquare, wallStr string, gridSize int) bool {
-	commaIx := strings.IndexRune(wallStr, ',')
-	col, _ := strconv.Atoi(wallStr[:commaIx])
-	row, _ := strconv.Atoi(wallStr[commaIx+1:])
-	square := &grid[row][col]
+func createWall(grid Grid[d18GridSquare], wall gridPos, gridSize int) bool {
+	square := grid.Ptr(wall)
 	square.wall = true
 
-	if row == 0 || col == gridSize-1 {
+	if wall.row == 0 || wall.col == gridSize-1 {
 		square.status = TOP_RIGHT
-	} else if row == gridSize-1 || col == 0 {
+	} else if wall.row == gridSize-1 || wall.col == 0 {
 		square.status = BOTTOM_LEFT
 	}
 
-	adjs := gridPos{row, col}.allAdjacencies(gridSize, gridSize)
-	for _, adj := range adjs {
-		otherSquare := &grid[adj.row][adj.col]
+	for adj := range grid.AllNeighbours(wall) {
+		otherSquare := grid.Ptr(adj)
 		if otherSquare.wall {
 			// There's a wall here, we'll need to merge sets.
 			result := mergeDisjointSets(square, otherSquare)
@@ -193,34 +172,59 @@ func createWall(grid [][]d18GridSquare, wallStr string, gridSize int) bool {
 	return false
 }
 
-func Day18Part1(logger *slog.Logger, input string) (string, any) {
-	lines := strings.Fields(input)
-	gridSize := GRID_SIZE_EXAMPLE
-	startAfter := START_AFTER_EXAMPLE
-	if len(lines) > 100 {
-		gridSize = GRID_SIZE_REAL
-		startAfter = START_AFTER_REAL
+// Parse the list of falling bytes, each given as "X,Y".
+func parseD18Bytes(lines []string, gridSize int) ([]gridPos, error) {
+	bytes := make([]gridPos, len(lines))
+	for lineIx, line := range lines {
+		colStr, rowStr, found := strings.Cut(line, ",")
+		if !found {
+			return nil, errorAt(lineIx+1, 0, "expected X,Y, found %q", line)
+		}
+		col, err := atoiAt(colStr, lineIx+1, 1)
+		if err != nil {
+			return nil, err
+		}
+		row, err := atoiAt(rowStr, lineIx+1, len(colStr)+2)
+		if err != nil {
+			return nil, err
+		}
+		if row < 0 || row >= gridSize || col < 0 || col >= gridSize {
+			return nil, errorAt(lineIx+1, 0, "%s is outside the %dx%d grid", line, gridSize, gridSize)
+		}
+		bytes[lineIx] = gridPos{row, col}
 	}
+	return bytes, nil
+}
 
-	grid := make([][]d18GridSquare, gridSize)
-	for rowIx := range gridSize {
-		grid[rowIx] = make([]d18GridSquare, gridSize)
+func Day18Part1(ctx context.Context, logger *slog.Logger, input string) (string, d18Context, error) {
+	lines := strings.Fields(input)
+	gridSize, startAfter := d18GridSize.get(ctx), d18StartAfter.get(ctx)
+	bytes, err := parseD18Bytes(lines, gridSize)
+	if err != nil {
+		return "", d18Context{}, err
+	}
+	if len(bytes) < startAfter {
+		return "", d18Context{}, errorAt(0, 0, "expected at least %d bytes, found %d", startAfter, len(bytes))
 	}
+	logger.Debug("Parsed input", slog.Int("bytes", len(bytes)), slog.Int("gridSize", gridSize), slog.Int("startAfter", startAfter))
+
+	grid := newGrid[d18GridSquare](gridSize, gridSize)
 
-	for lineIx := range startAfter {
-		_ = createWall(grid, lines[lineIx], gridSize)
+	vis := visualiserFrom(ctx)
+	for ix, wall := range bytes[:startAfter] {
+		_ = createWall(grid, wall, gridSize)
+		if vis != nil {
+			showD18Wall(vis, grid, ix+1)
+		}
 	}
 
 	pathLen := runMaze(grid, gridSize)
-	return strconv.Itoa(pathLen), d18Context{grid, gridSize, lines[startAfter:]}
+	return strconv.Itoa(pathLen), d18Context{grid, gridSize, bytes[startAfter:], lines[startAfter:]}, nil
 }
 
 // Just a basic BFS that returns the length of the best path.
-func runMaze(grid [][]d18GridSquare, gridSize int) int {
-	visited := make([][]bool, gridSize)
-	for i := range gridSize {
-		visited[i] = make([]bool, gridSize)
-	}
+func runMaze(grid Grid[d18GridSquare], gridSize int) int {
+	visited := newGrid[bool](gridSize, gridSize)
 
 	pathLen := 0
 	solved := false
@@ -237,12 +241,11 @@ outer:
 				solved = true
 				break outer
 			}
-			visited[pos.row][pos.col] = true
-			adjs := pos.adjacencies(gridSize, gridSize)
-			for _, adj := range adjs {
-				if !grid[adj.row][adj.col].wall && !visited[adj.row][adj.col] {
+			visited.Set(pos, true)
+			for adj := range grid.Neighbours(pos) {
+				if !grid.At(adj).wall && !visited.At(adj) {
 					// Open space that we've not visited yet
-					visited[adj.row][adj.col] = true
+					visited.Set(adj, true)
 					q.Enqueue(adj)
 				}
 			}
@@ -257,14 +260,34 @@ outer:
 	return pathLen
 }
 
-func Day18Part2(logger *slog.Logger, input string, part1Context any) string {
-	context := part1Context.(d18Context)
-	for _, line := range context.lines {
+// Show the memory space once some number of bytes have fallen, with
+// each wall marked according to which edges the set of walls it's
+// part of is touching.
+func showD18Wall(vis *visualiser, grid Grid[d18GridSquare], fallen int) {
+	vis.show(func() (string, string) {
+		return fmt.Sprintf("%d bytes have fallen. Walls joined to the top or right edge are R, to the bottom or left edge L", fallen), renderPicture(grid.NumRows(), grid.NumCols(), func(pos gridPos) rune {
+			square := grid.Ptr(pos)
+			if !square.wall {
+				return '.'
+			}
+			return [...]rune{UNCONNECTED: '#', TOP_RIGHT: 'R', BOTTOM_LEFT: 'L'}[findDisjointSetRoot(square).status]
+		})
+	})
+}
+
+func Day18Part2(ctx context.Context, logger *slog.Logger, input string, part1Context d18Context) (string, error) {
+	vis := visualiserFrom(ctx)
+	for ix, wall := range part1Context.bytes {
 		// See the comment above createWall() for an explanation of the
 		// algorithm we use in this part.
-		if createWall(context.grid, line, context.gridSize) {
-			return line
+		blocked := createWall(part1Context.grid, wall, part1Context.gridSize)
+		if vis != nil {
+			showD18Wall(vis, part1Context.grid, d18StartAfter.get(ctx)+ix+1)
+		}
+		if blocked {
+			logger.Debug("Exit cut off", slog.Int("extraBytes", ix+1))
+			return part1Context.lines[ix], nil
 		}
 	}
-	return ""
+	return "", errorAt(0, 0, "the exit never becomes unreachable")
 }
@@ -1,16 +1,15 @@
 package main
 
 import (
+	"context"
 	"log/slog"
 	"strconv"
 	"strings"
 
 	cmap "github.com/orcaman/concurrent-map/v2"
-
-	runner "github.com/ThePants999/advent-of-code-go-runner"
 )
 
-var Day19 = runner.DayImplementation{
+var Day19 = newPuzzle(dayDefinition[int]{
 	DayNumber:    19,
 	ExecutePart1: Day19Part1,
 	ExecutePart2: Day19Part2,
@@ -26,102 +25,121 @@ brgr
 bbrgwb`,
 	ExamplePart1Answer: "6",
 	ExamplePart2Answer: "16",
-}
+})
 
 // Today is simply "fun with memoisation". There's a tonne
 // of combinations to try, but also massive overlap between
 // them, so caching what we've already calculated enormously
 // reduces the amount of work we need to do.
 
-var towels map[string]nothing
-var minTowelLen, maxTowelLen int
-
-// cmap.ConcurrentMap is a performant thread-safe map that
-// splits keys into a number of different ranges, and stores
-// different ranges on separate "shards". Only the one shard
-// you end up accessing needs to be locked, so different
-// threads that access different shards simultaneously
-// require no locking.
-var solutions cmap.ConcurrentMap[string, int]
-
-func Day19Part1(logger *slog.Logger, input string) (string, any) {
-	solutions = cmap.New[int]()
-	minTowelLen = 99
-	towels = make(map[string]nothing)
+type d19Solver struct {
+	towels      map[string]nothing
+	minTowelLen int
+	maxTowelLen int
+
+	// cmap.ConcurrentMap is a performant thread-safe map that
+	// splits keys into a number of different ranges, and stores
+	// different ranges on separate "shards". Only the one shard
+	// you end up accessing needs to be locked, so different
+	// threads that access different shards simultaneously
+	// require no locking.
+	solutions cmap.ConcurrentMap[string, int]
+}
+
+// How well the memoisation is working, for logging. Each pattern
+// counts its own so that threads don't fight over the counters.
+type d19CacheStats struct {
+	hits   int
+	misses int
+}
+
+func Day19Part1(ctx context.Context, logger *slog.Logger, input string) (string, int, error) {
+	solver := &d19Solver{
+		towels:      make(map[string]nothing),
+		minTowelLen: 99,
+		solutions:   cmap.New[int](),
+	}
 	lines := strings.Split(input, "\n")
+	if len(lines) < 2 || len(lines[1]) != 0 {
+		return "", 0, errorAt(2, 0, "expected a blank line after the list of towels")
+	}
 	towelsStr := strings.Split(lines[0], ", ")
+	column := 1
 	for _, towel := range towelsStr {
-		towels[towel] = nothing{}
-		if len(towel) < minTowelLen {
-			minTowelLen = len(towel)
-		}
-		if len(towel) > maxTowelLen {
-			maxTowelLen = len(towel)
+		// An empty towel would match forever without consuming
+		// any of the pattern.
+		if len(towel) == 0 {
+			return "", 0, errorAt(1, column, "empty towel")
 		}
+		column += len(towel) + 2
+		solver.towels[towel] = nothing{}
+		solver.minTowelLen = min(solver.minTowelLen, len(towel))
+		solver.maxTowelLen = max(solver.maxTowelLen, len(towel))
 	}
 	patterns := lines[2:]
-
-	// Solve each pattern on a separate thread.
-	c := make(chan int)
-	for _, pattern := range patterns {
-		go solvePattern(pattern, c)
+	for ix, pattern := range patterns {
+		if len(pattern) == 0 {
+			return "", 0, errorAt(ix+3, 0, "empty pattern")
+		}
+	}
+	logger.Debug("Parsed input", slog.Int("towels", len(solver.towels)), slog.Int("patterns", len(patterns)))
+
+	// Solve each pattern in parallel.
+	results := make([]int, len(patterns))
+	stats := make([]d19CacheStats, len(patterns))
+	err := parallelFor(ctx, len(patterns), func(ix int) {
+		results[ix] = solver.solvePattern(patterns[ix], &stats[ix])
+	})
+	if err != nil {
+		return "", 0, err
 	}
 
 	// Calculate both parts simultaneously.
 	sum := 0
 	count := 0
-	for range patterns {
-		result := <-c
+	var total d19CacheStats
+	for ix, result := range results {
 		sum += result
 		if result > 0 {
 			count++
 		}
+		total.hits += stats[ix].hits
+		total.misses += stats[ix].misses
 	}
+	logger.Debug("Solved patterns", slog.Int("cacheHits", total.hits), slog.Int("cacheMisses", total.misses), slog.Float64("cacheHitRate", hitRate(total.hits, total.misses)))
 
-	return strconv.Itoa(count), sum
+	return strconv.Itoa(count), sum, nil
 }
 
-func solvePattern(pattern string, c chan int) {
-	// The input may have blank lines.
-	if len(pattern) == 0 {
-		c <- 0
-		return
-	}
-
-	c <- solvePatternRecursive(pattern)
-}
-
-func solvePatternRecursive(pattern string) int {
+func (solver *d19Solver) solvePattern(pattern string, stats *d19CacheStats) int {
 	// If we get down to an empty string, we've found a match.
 	if len(pattern) == 0 {
 		return 1
 	}
 
 	// See whether we've deconstructed exactly this sub-pattern before.
-	result, found := solutions.Get(pattern)
+	result, found := solver.solutions.Get(pattern)
 	if found {
+		stats.hits++
 		return result
 	}
+	stats.misses++
 
 	// Check each head length of the current sub-pattern that might
 	// match a towel.
-	maxLen := maxTowelLen
-	if len(pattern) < maxLen {
-		maxLen = len(pattern)
-	}
-	for i := minTowelLen; i <= maxLen; i++ {
-		_, found := towels[pattern[:i]]
+	maxLen := min(solver.maxTowelLen, len(pattern))
+	for i := solver.minTowelLen; i <= maxLen; i++ {
+		_, found := solver.towels[pattern[:i]]
 		if found {
-			result += solvePatternRecursive(pattern[i:])
+			result += solver.solvePattern(pattern[i:], stats)
 		}
 	}
 
 	// Remember the result for this sub-pattern.
-	solutions.Set(pattern, result)
+	solver.solutions.Set(pattern, result)
 	return result
 }
 
-func Day19Part2(logger *slog.Logger, input string, part1Context any) string {
-	sum := part1Context.(int)
-	return strconv.Itoa(sum)
+func Day19Part2(ctx context.Context, logger *slog.Logger, input string, sum int) (string, error) {
+	return strconv.Itoa(sum), nil
 }
@@ -1,121 +1,227 @@
 package main
 
 import (
+	"context"
 	"log/slog"
 	"strconv"
 	"strings"
-
-	runner "github.com/ThePants999/advent-of-code-go-runner"
 )
 
-var Day2 = runner.DayImplementation{
+var Day2 = newPuzzle(dayDefinition[[][]int]{
 	DayNumber:          2,
 	ExecutePart1:       Day2Part1,
 	ExecutePart2:       Day2Part2,
 	ExampleInput:       "7 6 4 2 1\n1 2 7 8 9\n9 7 6 2 1\n1 3 2 4 5\n8 6 4 4 1\n1 3 6 7 9",
 	ExamplePart1Answer: "2",
 	ExamplePart2Answer: "4",
+	Parameters:         []*dayParameter{d2MinStep, d2MaxStep, d2Monotonic, d2MaxRemovals},
+})
+
+func Day2Part1(ctx context.Context, logger *slog.Logger, input string) (string, [][]int, error) {
+	reports, err := parseD2Reports(input)
+	if err != nil {
+		return "", nil, err
+	}
+	logger.Debug("Parsed input", slog.Int("reports", len(reports)))
+
+	// Check each report in turn to count safe reports.
+	rules := d2RulesFrom(ctx)
+	safeCount := 0
+	for _, report := range reports {
+		if safe, _ := rules.checkReport(report); safe {
+			safeCount++
+		}
+	}
+
+	return strconv.Itoa(safeCount), reports, nil
 }
 
-func Day2Part1(logger *slog.Logger, input string) (string, any) {
-	// Parse input into a slice of slices of numbers.
+// Parse the input into a slice of slices of numbers.
+func parseD2Reports(input string) ([][]int, error) {
 	reportStrings := strings.Split(input, "\n")
-	// Cope with a blank line at the end.
-	if len(reportStrings[len(reportStrings)-1]) == 0 {
-		reportStrings = reportStrings[:len(reportStrings)-1]
-	}
 	reports := make([][]int, len(reportStrings))
 	for ix, report := range reportStrings {
+		// A report with only one level has no steps to break the
+		// rules, so it's safe - and a blank line isn't a report at
+		// all, which atoiAt will tell us when it finds no number.
 		levels := strings.Split(report, " ")
 		reports[ix] = make([]int, len(levels))
+		column := 1
 		for iy, level := range levels {
-			levelInt, err := strconv.Atoi(level)
+			levelInt, err := atoiAt(level, ix+1, column)
 			if err != nil {
-				panic(err)
+				return nil, err
 			}
 			reports[ix][iy] = levelInt
+			column += len(level) + 1
 		}
 	}
+	return reports, nil
+}
 
-	// Check each report in turn to count safe reports.
+func Day2Part2(ctx context.Context, logger *slog.Logger, input string, reports [][]int) (string, error) {
+	// I used to do this by working out where the first problem
+	// was, and trying again without that level, then without the
+	// one before, then (in one special case) without the first -
+	// which only worked for one removal, and took some convincing
+	// that it was all the cases. Now it's the general version
+	// below, which works for any number.
+	rules, maxRemovals := d2RulesFrom(ctx), d2MaxRemovals.get(ctx)
 	safeCount := 0
+	removalCounts := make([]int, maxRemovals+1)
 	for _, report := range reports {
-		if safe, _ := checkReport(report); safe {
+		if removed, safe := rules.dampen(report, maxRemovals); safe {
 			safeCount++
+			removalCounts[len(removed)]++
 		}
 	}
+	logger.Debug("Dampened reports", slog.Any("safeByRemovals", removalCounts))
 
-	return strconv.Itoa(safeCount), reports
+	return strconv.Itoa(safeCount), nil
 }
 
-func Day2Part2(logger *slog.Logger, input string, part1Context any) string {
-	reports := part1Context.([][]int)
-	safeCount := 0
-	for _, report := range reports {
-		safe, problemIx := checkReport(report)
-		if !safe {
-			// Try again without the level that caused a problem.
-			newReport := make([]int, problemIx, len(report))
-			copy(newReport, report[:problemIx])
-			newReport = append(newReport, report[problemIx+1:]...)
-			safe, _ = checkReport(newReport)
-		}
-		if !safe {
-			// It's also possible that it was the previous level
-			// that was the real problem. E.g. in the sequence
-			// 1 5 6 7..., the 5 is what we identify as unsafe,
-			// but it's really the 1 that's the issue.
-			newReport := make([]int, problemIx-1, len(report))
-			copy(newReport, report[:problemIx-1])
-			newReport = append(newReport, report[problemIx:]...)
-			safe, _ = checkReport(newReport)
-		}
-		if !safe && problemIx == 2 {
-			// Finally, there's a special case where if a problem
-			// is detected with the third element, it might actually
-			// be removing the FIRST that helps. For example, in
-			// the sequence 3 1 2 4..., the drop from 3 to 1 causes
-			// us to think it's a decreasing report, at which point
-			// the 2 looks problematic, when it's actually an
-			// increasing report where the 3 is problematic.
-			safe, _ = checkReport(report[1:])
-		}
-		if safe {
-			safeCount++
+// What makes a report safe: each step from one level to the next
+// must be between minStep and maxStep in size and, if monotonic,
+// all in the same direction. With a minStep of 0, that means levels
+// can stay the same, so the report only has to be monotonic rather
+// than strictly so.
+type d2Rules struct {
+	minStep, maxStep int
+	monotonic        bool
+}
+
+var d2MinStep = &dayParameter{
+	Name:        "minStep",
+	Description: "The smallest change allowed between one level and the next",
+	Default:     1,
+	Example:     1,
+}
+
+var d2MaxStep = &dayParameter{
+	Name:        "maxStep",
+	Description: "The biggest change allowed between one level and the next",
+	Default:     3,
+	Example:     3,
+}
+
+var d2Monotonic = &dayParameter{
+	Name:        "monotonic",
+	Description: "Whether a report's levels must all go the same way (1) or can go up and down (0)",
+	Default:     1,
+	Example:     1,
+	Max:         1,
+}
+
+var d2MaxRemovals = &dayParameter{
+	Name:        "maxRemovals",
+	Description: "How many levels part 2's Problem Dampener can remove from a report",
+	Default:     1,
+	Example:     1,
+}
+
+func d2RulesFrom(ctx context.Context) d2Rules {
+	return d2Rules{d2MinStep.get(ctx), d2MaxStep.get(ctx), d2Monotonic.get(ctx) != 0}
+}
+
+// Whether a step from one level to the next is allowed, if the
+// report's going in the given direction (1 for up, -1 for down).
+// Without monotonic, the direction doesn't matter.
+func (rules d2Rules) stepOK(from int, to int, direction int) bool {
+	step := (to - from) * direction
+	if !rules.monotonic && step < 0 {
+		step = -step
+	}
+	return step >= rules.minStep && step <= rules.maxStep
+}
+
+// Check a report as it is, returning the index of the first level
+// that breaks the rules if it isn't safe.
+func (rules d2Rules) checkReport(report []int) (bool, int) {
+	direction := d2Direction(report)
+	for ix := 1; ix < len(report); ix++ {
+		if !rules.stepOK(report[ix-1], report[ix], direction) {
+			return false, ix
 		}
 	}
+	return true, -1
+}
 
-	return strconv.Itoa(safeCount)
+// Which way a report's going (1 for up, -1 for down), assuming that
+// the first change of level tells us.
+func d2Direction(report []int) int {
+	for ix := 1; ix < len(report); ix++ {
+		if report[ix] != report[ix-1] {
+			if report[ix] < report[ix-1] {
+				return -1
+			}
+			break
+		}
+	}
+	return 1
 }
 
-func checkReport(report []int) (bool, int) {
-	increasing := true
-	safe := true
-	problemIx := -1
-	if report[1] < report[0] {
-		// Assume that a decrease from the first to
-		// the second level means this is a
-		// decreasing report.
-		increasing = false
+// Work out whether a report can be made safe by removing at most
+// maxRemovals levels, and if so, which levels to remove - as few as
+// possible, which is none if it's safe already.
+//
+// For each level in turn, we work out the fewest removals it takes
+// for the report up to and including that level to be safe, if we
+// keep that level. That's either removing everything before it, or
+// the fewest removals for some earlier level we keep, plus removing
+// everything in between - and as we can only remove maxRemovals,
+// there are only that many earlier levels to choose from, plus the
+// one just before. So this is linear in the length of the report,
+// however many levels can be removed. At the end, the best we can
+// do is the best for some level, plus removing everything after it.
+//
+// If the report has to be monotonic, we do all that once for each
+// direction.
+func (rules d2Rules) dampen(report []int, maxRemovals int) (removed []int, safe bool) {
+	directions := []int{1}
+	if rules.monotonic {
+		directions = []int{1, -1}
 	}
-out:
-	for ix := 1; ix < len(report); ix++ {
-		// Report a problem any time the level goes up
-		// in a decreasing report or down in an
-		// increasing report, or any time it changes by
-		// more than 3 in either direction.
-		switch {
-		case increasing && report[ix] <= report[ix-1]:
-			fallthrough
-		case !increasing && report[ix] >= report[ix-1]:
-			fallthrough
-		case report[ix]-report[ix-1] > 3:
-			fallthrough
-		case report[ix]-report[ix-1] < -3:
-			safe = false
-			problemIx = ix
-			break out
+	fewest := make([]int, len(report))
+	previous := make([]int, len(report))
+	for _, direction := range directions {
+		for ix := range report {
+			// Removing everything before this level, if we can,
+			// is a start.
+			fewest[ix], previous[ix] = maxRemovals+1, -1
+			if ix <= maxRemovals {
+				fewest[ix] = ix
+			}
+			for prevIx := ix - 1; prevIx >= 0 && prevIx >= ix-1-maxRemovals; prevIx-- {
+				removals := fewest[prevIx] + (ix - prevIx - 1)
+				if removals < fewest[ix] && rules.stepOK(report[prevIx], report[ix], direction) {
+					fewest[ix], previous[ix] = removals, prevIx
+				}
+			}
+		}
+
+		bestLast, bestRemovals := -1, maxRemovals+1
+		for ix := len(report) - 1; ix >= 0 && ix >= len(report)-1-maxRemovals; ix-- {
+			if removals := fewest[ix] + (len(report) - 1 - ix); removals < bestRemovals {
+				bestLast, bestRemovals = ix, removals
+			}
+		}
+		if bestLast == -1 || (safe && bestRemovals >= len(removed)) {
+			continue
+		}
+
+		// Follow the levels we're keeping back from the last,
+		// and remove everything else.
+		kept := make([]bool, len(report))
+		for ix := bestLast; ix != -1; ix = previous[ix] {
+			kept[ix] = true
+		}
+		removed = removed[:0]
+		for ix := range report {
+			if !kept[ix] {
+				removed = append(removed, ix)
+			}
 		}
+		safe = true
 	}
-	return safe, problemIx
+	return removed, safe
 }
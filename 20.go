@@ -1,14 +1,15 @@
 package main
 
 import (
+	"context"
+	"fmt"
+	"image/color"
 	"log/slog"
 	"strconv"
 	"strings"
-
-	runner "github.com/ThePants999/advent-of-code-go-runner"
 )
 
-var Day20 = runner.DayImplementation{
+var Day20 = newPuzzle(dayDefinition[Grid[int]]{
 	DayNumber:    20,
 	ExecutePart1: Day20Part1,
 	ExecutePart2: Day20Part2,
@@ -29,6 +30,21 @@ var Day20 = runner.DayImplementation{
 ###############`,
 	ExamplePart1Answer: "44",
 	ExamplePart2Answer: "285",
+	Parameters:         []*dayParameter{d20Part1Threshold, d20Part2Threshold},
+})
+
+var d20Part1Threshold = &dayParameter{
+	Name:        "part1Threshold",
+	Description: "How many picoseconds a cheat must save to count in part 1",
+	Default:     100,
+	Example:     0,
+}
+
+var d20Part2Threshold = &dayParameter{
+	Name:        "part2Threshold",
+	Description: "How many picoseconds a cheat must save to count in part 2",
+	Default:     100,
+	Example:     50,
 }
 
 const (
@@ -36,74 +52,76 @@ const (
 	D20_UNREACHED_SPACE int = -2
 )
 
-func (p gridPos) adjacenciesUnbounded() []gridPos {
-	adj := make([]gridPos, 4)
-	adj[0] = gridPos{p.row - 1, p.col}
-	adj[1] = gridPos{p.row + 1, p.col}
-	adj[2] = gridPos{p.row, p.col - 1}
-	adj[3] = gridPos{p.row, p.col + 1}
-	return adj
-}
-
-func Day20Part1(logger *slog.Logger, input string) (string, any) {
+func Day20Part1(ctx context.Context, logger *slog.Logger, input string) (string, Grid[int], error) {
 	// Parse the input - build up the complete grid,
 	// and also record the start and end co-ordinates.
 	lines := strings.Fields(input)
-	numRows := len(lines)
-	numCols := len(lines[0])
-	grid := make([][]int, len(lines))
-	var start, end gridPos
-	for rowIx, row := range lines {
-		grid[rowIx] = make([]int, len(row))
-		for colIx, square := range row {
-			var val int
-			switch square {
-			case '#':
-				val = D20_WALL
-			case '.':
-				val = D20_UNREACHED_SPACE
-			case 'S':
-				start = gridPos{rowIx, colIx}
-				val = 0
-			case 'E':
-				end = gridPos{rowIx, colIx}
-				val = D20_UNREACHED_SPACE
-			}
-			grid[rowIx][colIx] = val
+	grid, markers, err := parseGridWithMarkers(lines, "SE", func(square rune) (int, bool) {
+		switch square {
+		case '#':
+			return D20_WALL, true
+		case '.', 'E':
+			return D20_UNREACHED_SPACE, true
+		case 'S':
+			return 0, true
 		}
+		return 0, false
+	})
+	if err != nil {
+		return "", Grid[int]{}, err
 	}
+	// We follow the track and look for cheats either side of
+	// walls without worrying about leaving the grid, relying
+	// on the walls around the edge.
+	if err := checkBorder(lines, '#'); err != nil {
+		return "", Grid[int]{}, err
+	}
+	numRows, numCols := grid.NumRows(), grid.NumCols()
+	start, end := markers[0], markers[1]
 
 	// Figure out the path through, and record the
 	// point in time at which we reach each grid square.
+	vis := visualiserFrom(ctx)
 	cur := start
 	dist := 0
 	for cur != end {
 		dist++
-		adjs := cur.adjacenciesUnbounded()
-		for _, adj := range adjs {
-			if grid[adj.row][adj.col] == D20_UNREACHED_SPACE {
-				grid[adj.row][adj.col] = dist
+		prev := cur
+		for adj := range grid.Neighbours(cur) {
+			if grid.At(adj) == D20_UNREACHED_SPACE {
+				grid.Set(adj, dist)
 				cur = adj
 				break
 			}
 		}
+		if cur == prev {
+			return "", Grid[int]{}, errorAt(cur.row+1, cur.col+1, "the track comes to a dead end before reaching E")
+		}
+		if vis != nil {
+			runner, time := cur, dist
+			vis.show(func() (string, string) {
+				return fmt.Sprintf("Following the track: %d picoseconds", time), renderD20Track(grid, start, end, func(pos gridPos) (rune, bool) {
+					return '@', pos == runner
+				})
+			})
+		}
 	}
+	logger.Debug("Parsed input", slog.Int("rows", numRows), slog.Int("cols", numCols), slog.Int("trackLength", dist))
 
 	// The approach we take for part 1 is simplistic - we're going to
 	// look at every wall, and figure out whether the spaces above and
 	// below and/or left and right constitute a cheat that saves time
 	// over the threshold.
 	sum := 0
-	threshold := 0
-	if numRows > 20 {
-		// Real input
-		threshold = 100
-	}
+	threshold := d20Part1Threshold.get(ctx)
+	images := imageRecorderFrom(ctx)
+	var cheats [][2]gridPos
+	logger.Debug("Looking for cheats", slog.Int("maxCheatLength", 2), slog.Int("threshold", threshold))
 	for rowIx := 1; rowIx < numRows-1; rowIx++ {
 		for colIx := 1; colIx < numCols-1; colIx++ {
-			if grid[rowIx][colIx] == D20_WALL {
-				top, bottom := grid[rowIx-1][colIx], grid[rowIx+1][colIx]
-				left, right := grid[rowIx][colIx-1], grid[rowIx][colIx+1]
+			if grid.At(gridPos{rowIx, colIx}) == D20_WALL {
+				top, bottom := grid.At(gridPos{rowIx - 1, colIx}), grid.At(gridPos{rowIx + 1, colIx})
+				left, right := grid.At(gridPos{rowIx, colIx - 1}), grid.At(gridPos{rowIx, colIx + 1})
 				if top >= 0 && bottom >= 0 {
 					diff := bottom - top
 					if top > bottom {
@@ -112,6 +130,12 @@ func Day20Part1(logger *slog.Logger, input string) (string, any) {
 					diff -= 2
 					if diff >= threshold {
 						sum++
+						if images != nil {
+							cheats = append(cheats, [2]gridPos{{rowIx - 1, colIx}, {rowIx + 1, colIx}})
+						}
+						if vis != nil {
+							showD20Cheat(vis, grid, start, end, gridPos{rowIx, colIx}, UP, diff, sum)
+						}
 					}
 				}
 				if left >= 0 && right >= 0 {
@@ -122,21 +146,90 @@ func Day20Part1(logger *slog.Logger, input string) (string, any) {
 					diff -= 2
 					if diff >= threshold {
 						sum++
+						if images != nil {
+							cheats = append(cheats, [2]gridPos{{rowIx, colIx - 1}, {rowIx, colIx + 1}})
+						}
+						if vis != nil {
+							showD20Cheat(vis, grid, start, end, gridPos{rowIx, colIx}, LEFT, diff, sum)
+						}
 					}
 				}
 			}
 		}
 	}
 
-	return strconv.Itoa(sum), grid
+	if images != nil {
+		images.add("cheats", drawD20Cheats(grid, start, end, dist, cheats))
+	}
+
+	return strconv.Itoa(sum), grid, nil
 }
 
-func Day20Part2(logger *slog.Logger, input string, part1Context any) string {
-	grid := part1Context.([][]int)
-	threshold := 50
-	if len(grid) > 20 {
-		threshold = 100
+// The track, with the walls as #, the parts we've reached as o and
+// the rest as ., and anything else the caller wants on top.
+func renderD20Track(grid Grid[int], start gridPos, end gridPos, overlay func(gridPos) (rune, bool)) string {
+	return renderPicture(grid.NumRows(), grid.NumCols(), func(pos gridPos) rune {
+		if char, found := overlay(pos); found {
+			return char
+		}
+		switch time := grid.At(pos); {
+		case pos == start:
+			return 'S'
+		case pos == end:
+			return 'E'
+		case time == D20_WALL:
+			return '#'
+		case time == D20_UNREACHED_SPACE:
+			return '.'
+		default:
+			return 'o'
+		}
+	})
+}
+
+// A part 1 cheat through the given wall, going across it from the
+// given direction to the opposite one.
+func showD20Cheat(vis *visualiser, grid Grid[int], start gridPos, end gridPos, wall gridPos, from Direction, saving int, found int) {
+	before, after := wall.move(from), wall.move(from.Reverse())
+	vis.show(func() (string, string) {
+		return fmt.Sprintf("Cheat %d saves %d picoseconds", found, saving), renderD20Track(grid, start, end, func(pos gridPos) (rune, bool) {
+			switch pos {
+			case wall:
+				return '*', true
+			case before, after:
+				return '+', true
+			}
+			return 0, false
+		})
+	})
+}
+
+// The track, shaded from light at the start to dark at the end, with
+// part 1's cheats drawn across the walls they go through.
+func drawD20Cheats(grid Grid[int], start gridPos, end gridPos, trackLength int, cheats [][2]gridPos) *gridImage {
+	img := newGridImage(grid.NumRows(), grid.NumCols(), IMAGE_WALL)
+	light, dark := color.RGBA{0xd0, 0xe8, 0xff, 0xff}, color.RGBA{0x40, 0x80, 0xc0, 0xff}
+	shade := func(from uint8, to uint8, fraction float64) uint8 {
+		return uint8(float64(from) + (float64(to)-float64(from))*fraction)
+	}
+	for pos, time := range grid.All() {
+		if time >= 0 {
+			fraction := float64(time) / float64(max(trackLength, 1))
+			img.fill(pos, color.RGBA{shade(light.R, dark.R, fraction), shade(light.G, dark.G, fraction), shade(light.B, dark.B, fraction), 0xff})
+		}
+	}
+	img.fill(start, IMAGE_START)
+	img.fill(end, IMAGE_END)
+	for _, cheat := range cheats {
+		img.link(cheat[0], cheat[1], IMAGE_END)
 	}
+	return img
+}
+
+func Day20Part2(ctx context.Context, logger *slog.Logger, input string, grid Grid[int]) (string, error) {
+	threshold := d20Part2Threshold.get(ctx)
+
+	logger.Debug("Looking for cheats", slog.Int("maxCheatLength", 20), slog.Int("threshold", threshold))
 
 	// Our approach to part 2 is a little different, though only
 	// a little. This time, we're going to look at every point
@@ -146,33 +239,33 @@ func Day20Part2(logger *slog.Logger, input string, part1Context any) string {
 	// qualifying cheat. So pretty much brute force, except
 	// that we can consider different starting points
 	// independently and in parallel - to take straightforward
-	// advantage of that, we give each row of the grid to a
-	// separate goroutine.
-	c := make(chan int)
-	for rowIx := 1; rowIx < len(grid)-1; rowIx++ {
-		go day20Part2HandleRow(grid, threshold, rowIx, c)
-	}
-
-	sum := 0
-	for range len(grid) - 2 {
-		sum += <-c
+	// advantage of that, we hand each row of the grid to the
+	// worker pool separately. (The top and bottom rows are all
+	// wall, so we skip them.)
+	sum, err := parallelSum(ctx, grid.NumRows()-2, func(ix int) int {
+		return day20Part2HandleRow(grid, threshold, ix+1)
+	})
+	if err != nil {
+		return "", err
 	}
 
-	return strconv.Itoa(sum)
+	return strconv.Itoa(sum), nil
 }
 
-func day20Part2HandleRow(grid [][]int, threshold int, rowIx int, c chan int) {
+func day20Part2HandleRow(grid Grid[int], threshold int, rowIx int) int {
 	sum := 0
-	for colIx := 1; colIx < len(grid[0])-1; colIx++ {
-		if grid[rowIx][colIx] >= 0 {
+	numRows, numCols := grid.NumRows(), grid.NumCols()
+	row := grid.Row(rowIx)
+	for colIx := 1; colIx < numCols-1; colIx++ {
+		if row[colIx] >= 0 {
 			// Non-wall
 			minRowIx := rowIx - 20
 			if minRowIx < 1 {
 				minRowIx = 1
 			}
 			maxRowIx := rowIx + 20
-			if maxRowIx >= len(grid) {
-				maxRowIx = len(grid) - 1
+			if maxRowIx >= numRows {
+				maxRowIx = numRows - 1
 			}
 			for targetRowIx := minRowIx; targetRowIx <= maxRowIx; targetRowIx++ {
 				var rowDiff int
@@ -182,17 +275,18 @@ func day20Part2HandleRow(grid [][]int, threshold int, rowIx int, c chan int) {
 					rowDiff = rowIx - targetRowIx
 				}
 				remainingDist := 20 - rowDiff
+				targetRow := grid.Row(targetRowIx)
 
 				minColIx := colIx - remainingDist
 				if minColIx < 1 {
 					minColIx = 1
 				}
 				maxColIx := colIx + remainingDist
-				if maxColIx >= len(grid[0]) {
-					maxColIx = len(grid[0]) - 1
+				if maxColIx >= numCols {
+					maxColIx = numCols - 1
 				}
 				for targetColIx := minColIx; targetColIx <= maxColIx; targetColIx++ {
-					if grid[targetRowIx][targetColIx] > grid[rowIx][colIx] {
+					if targetRow[targetColIx] > row[colIx] {
 						// This is a cheat
 						var colDiff int
 						if targetColIx >= colIx {
@@ -201,7 +295,7 @@ func day20Part2HandleRow(grid [][]int, threshold int, rowIx int, c chan int) {
 							colDiff = colIx - targetColIx
 						}
 						dist := rowDiff + colDiff
-						if grid[targetRowIx][targetColIx]-grid[rowIx][colIx]-dist >= threshold {
+						if targetRow[targetColIx]-row[colIx]-dist >= threshold {
 							// Legal and qualifying cheat
 							sum++
 						}
@@ -210,5 +304,5 @@ func day20Part2HandleRow(grid [][]int, threshold int, rowIx int, c chan int) {
 			}
 		}
 	}
-	c <- sum
+	return sum
 }
@@ -1,15 +1,15 @@
 package main
 
 import (
+	"context"
 	"log/slog"
 	"strconv"
 	"strings"
 	"sync"
-
-	runner "github.com/ThePants999/advent-of-code-go-runner"
+	"sync/atomic"
 )
 
-var Day21 = runner.DayImplementation{
+var Day21 = newPuzzle(dayDefinition[d21context]{
 	DayNumber:    21,
 	ExecutePart1: Day21Part1,
 	ExecutePart2: Day21Part2,
@@ -20,17 +20,34 @@ var Day21 = runner.DayImplementation{
 379A`,
 	ExamplePart1Answer: "126384",
 	ExamplePart2Answer: "154115708116294",
+	Parameters:         []*dayParameter{d21Part1Robots, d21Part2Robots},
+})
+
+// Answers grow exponentially with the number of robots. With 35,
+// five of the worst code there is (861A, as it happens) still fit
+// in an int, but with 36 they don't.
+const D21_MAX_ROBOTS int = 35
+
+var d21Part1Robots = &dayParameter{
+	Name:        "part1Robots",
+	Description: "How many robots using directional keypads there are in part 1",
+	Default:     2,
+	Example:     2,
+	Max:         D21_MAX_ROBOTS,
 }
 
-type d21DirKeypadButton int
+var d21Part2Robots = &dayParameter{
+	Name:        "part2Robots",
+	Description: "How many robots using directional keypads there are in part 2",
+	Default:     25,
+	Example:     25,
+	Max:         D21_MAX_ROBOTS,
+}
 
-const (
-	D21_UP d21DirKeypadButton = iota
-	D21_LEFT
-	D21_DOWN
-	D21_RIGHT
-	D21_PRESS
-)
+// The directional keypad has a button for each of the four
+// directions, plus A. We treat A as a ninth direction, so that
+// button sequences are simply []Direction.
+const D21_PRESS Direction = Direction(NUM_DIRECTIONS)
 
 // The optimal sequence of moves to get from any given button
 // to any other given button is fixed, so we can hardcode it
@@ -38,51 +55,46 @@ const (
 // not writing code to try permutations first - getting this
 // hardcoding right wasted a lot of time!)
 
-var directionDirections [5][5][]d21DirKeypadButton = [5][5][]d21DirKeypadButton{
-	// From UP
-	{
-		{D21_PRESS},                      // UP
-		{D21_DOWN, D21_LEFT, D21_PRESS},  // LEFT
-		{D21_DOWN, D21_PRESS},            // DOWN
-		{D21_DOWN, D21_RIGHT, D21_PRESS}, // RIGHT
-		{D21_RIGHT, D21_PRESS},           // PRESS
+var directionDirections [D21_PRESS + 1][D21_PRESS + 1][]Direction = [D21_PRESS + 1][D21_PRESS + 1][]Direction{
+	UP: {
+		UP:        {D21_PRESS},
+		LEFT:      {DOWN, LEFT, D21_PRESS},
+		DOWN:      {DOWN, D21_PRESS},
+		RIGHT:     {DOWN, RIGHT, D21_PRESS},
+		D21_PRESS: {RIGHT, D21_PRESS},
 	},
-	// From LEFT
-	{
-		{D21_RIGHT, D21_UP, D21_PRESS},            // UP
-		{D21_PRESS},                               // LEFT
-		{D21_RIGHT, D21_PRESS},                    // DOWN
-		{D21_RIGHT, D21_RIGHT, D21_PRESS},         // RIGHT
-		{D21_RIGHT, D21_RIGHT, D21_UP, D21_PRESS}, // PRESS
+	LEFT: {
+		UP:        {RIGHT, UP, D21_PRESS},
+		LEFT:      {D21_PRESS},
+		DOWN:      {RIGHT, D21_PRESS},
+		RIGHT:     {RIGHT, RIGHT, D21_PRESS},
+		D21_PRESS: {RIGHT, RIGHT, UP, D21_PRESS},
 	},
-	// From DOWN
-	{
-		{D21_UP, D21_PRESS},            // UP
-		{D21_LEFT, D21_PRESS},          // LEFT
-		{D21_PRESS},                    // DOWN
-		{D21_RIGHT, D21_PRESS},         // RIGHT
-		{D21_UP, D21_RIGHT, D21_PRESS}, // PRESS
+	DOWN: {
+		UP:        {UP, D21_PRESS},
+		LEFT:      {LEFT, D21_PRESS},
+		DOWN:      {D21_PRESS},
+		RIGHT:     {RIGHT, D21_PRESS},
+		D21_PRESS: {UP, RIGHT, D21_PRESS},
 	},
-	// From RIGHT
-	{
-		{D21_LEFT, D21_UP, D21_PRESS},   // UP
-		{D21_LEFT, D21_LEFT, D21_PRESS}, // LEFT
-		{D21_LEFT, D21_PRESS},           // DOWN
-		{D21_PRESS},                     // RIGHT
-		{D21_UP, D21_PRESS},             // PRESS
+	RIGHT: {
+		UP:        {LEFT, UP, D21_PRESS},
+		LEFT:      {LEFT, LEFT, D21_PRESS},
+		DOWN:      {LEFT, D21_PRESS},
+		RIGHT:     {D21_PRESS},
+		D21_PRESS: {UP, D21_PRESS},
 	},
-	// From PRESS
-	{
-		{D21_LEFT, D21_PRESS},                     // UP
-		{D21_DOWN, D21_LEFT, D21_LEFT, D21_PRESS}, // LEFT
-		{D21_LEFT, D21_DOWN, D21_PRESS},           // DOWN
-		{D21_DOWN, D21_PRESS},                     // RIGHT
-		{D21_PRESS},                               // PRESS
+	D21_PRESS: {
+		UP:        {LEFT, D21_PRESS},
+		LEFT:      {DOWN, LEFT, LEFT, D21_PRESS},
+		DOWN:      {LEFT, DOWN, D21_PRESS},
+		RIGHT:     {DOWN, D21_PRESS},
+		D21_PRESS: {D21_PRESS},
 	},
 }
 
 type numKeypadPress struct {
-	button     d21DirKeypadButton
+	button     Direction
 	numPresses int
 }
 
@@ -103,160 +115,160 @@ var numericDirections [11][11][]numKeypadPress = [11][11][]numKeypadPress{
 		// two lefts consecutive and keep "press" at the end. In the end,
 		// I realised I didn't need such code, but couldn't be bothered to
 		// unwind this scheme.
-		{numKeypadPress{D21_UP, 1}, numKeypadPress{D21_LEFT, 1}},  // 1
-		{numKeypadPress{D21_UP, 1}},                               // 2
-		{numKeypadPress{D21_UP, 1}, numKeypadPress{D21_RIGHT, 1}}, // 3
-		{numKeypadPress{D21_UP, 2}, numKeypadPress{D21_LEFT, 1}},  // 4
-		{numKeypadPress{D21_UP, 2}},                               // 5
-		{numKeypadPress{D21_UP, 2}, numKeypadPress{D21_RIGHT, 1}}, // 6
-		{numKeypadPress{D21_UP, 3}, numKeypadPress{D21_LEFT, 1}},  // 7
-		{numKeypadPress{D21_UP, 3}},                               // 8
-		{numKeypadPress{D21_UP, 3}, numKeypadPress{D21_RIGHT, 1}}, // 9
-		{numKeypadPress{D21_RIGHT, 1}},                            // PRESS
+		{numKeypadPress{UP, 1}, numKeypadPress{LEFT, 1}},  // 1
+		{numKeypadPress{UP, 1}},                           // 2
+		{numKeypadPress{UP, 1}, numKeypadPress{RIGHT, 1}}, // 3
+		{numKeypadPress{UP, 2}, numKeypadPress{LEFT, 1}},  // 4
+		{numKeypadPress{UP, 2}},                           // 5
+		{numKeypadPress{UP, 2}, numKeypadPress{RIGHT, 1}}, // 6
+		{numKeypadPress{UP, 3}, numKeypadPress{LEFT, 1}},  // 7
+		{numKeypadPress{UP, 3}},                           // 8
+		{numKeypadPress{UP, 3}, numKeypadPress{RIGHT, 1}}, // 9
+		{numKeypadPress{RIGHT, 1}},                        // PRESS
 	},
 	// From 1
 	{
-		{numKeypadPress{D21_RIGHT, 1}, numKeypadPress{D21_DOWN, 1}}, // 0
-		{},                             // 1
-		{numKeypadPress{D21_RIGHT, 1}}, // 2
-		{numKeypadPress{D21_RIGHT, 2}}, // 3
-		{numKeypadPress{D21_UP, 1}},    // 4
-		{numKeypadPress{D21_UP, 1}, numKeypadPress{D21_RIGHT, 1}},   // 5
-		{numKeypadPress{D21_UP, 1}, numKeypadPress{D21_RIGHT, 2}},   // 6
-		{numKeypadPress{D21_UP, 2}},                                 // 7
-		{numKeypadPress{D21_UP, 2}, numKeypadPress{D21_RIGHT, 1}},   // 8
-		{numKeypadPress{D21_UP, 2}, numKeypadPress{D21_RIGHT, 2}},   // 9
-		{numKeypadPress{D21_RIGHT, 2}, numKeypadPress{D21_DOWN, 1}}, // PRESS
+		{numKeypadPress{RIGHT, 1}, numKeypadPress{DOWN, 1}}, // 0
+		{},                         // 1
+		{numKeypadPress{RIGHT, 1}}, // 2
+		{numKeypadPress{RIGHT, 2}}, // 3
+		{numKeypadPress{UP, 1}},    // 4
+		{numKeypadPress{UP, 1}, numKeypadPress{RIGHT, 1}},   // 5
+		{numKeypadPress{UP, 1}, numKeypadPress{RIGHT, 2}},   // 6
+		{numKeypadPress{UP, 2}},                             // 7
+		{numKeypadPress{UP, 2}, numKeypadPress{RIGHT, 1}},   // 8
+		{numKeypadPress{UP, 2}, numKeypadPress{RIGHT, 2}},   // 9
+		{numKeypadPress{RIGHT, 2}, numKeypadPress{DOWN, 1}}, // PRESS
 	},
 	// From 2
 	{
-		{numKeypadPress{D21_DOWN, 1}},  // 0
-		{numKeypadPress{D21_LEFT, 1}},  // 1
-		{},                             // 2
-		{numKeypadPress{D21_RIGHT, 1}}, // 3
-		{numKeypadPress{D21_LEFT, 1}, numKeypadPress{D21_UP, 1}},    // 4
-		{numKeypadPress{D21_UP, 1}},                                 // 5
-		{numKeypadPress{D21_UP, 1}, numKeypadPress{D21_RIGHT, 1}},   // 6
-		{numKeypadPress{D21_LEFT, 1}, numKeypadPress{D21_UP, 2}},    // 7
-		{numKeypadPress{D21_UP, 2}},                                 // 8
-		{numKeypadPress{D21_UP, 2}, numKeypadPress{D21_RIGHT, 1}},   // 9
-		{numKeypadPress{D21_DOWN, 1}, numKeypadPress{D21_RIGHT, 1}}, // PRESS
+		{numKeypadPress{DOWN, 1}},  // 0
+		{numKeypadPress{LEFT, 1}},  // 1
+		{},                         // 2
+		{numKeypadPress{RIGHT, 1}}, // 3
+		{numKeypadPress{LEFT, 1}, numKeypadPress{UP, 1}},    // 4
+		{numKeypadPress{UP, 1}},                             // 5
+		{numKeypadPress{UP, 1}, numKeypadPress{RIGHT, 1}},   // 6
+		{numKeypadPress{LEFT, 1}, numKeypadPress{UP, 2}},    // 7
+		{numKeypadPress{UP, 2}},                             // 8
+		{numKeypadPress{UP, 2}, numKeypadPress{RIGHT, 1}},   // 9
+		{numKeypadPress{DOWN, 1}, numKeypadPress{RIGHT, 1}}, // PRESS
 	},
 	// From 3
 	{
-		{numKeypadPress{D21_LEFT, 1}, numKeypadPress{D21_DOWN, 1}}, // 0
-		{numKeypadPress{D21_LEFT, 2}},                              // 1
-		{numKeypadPress{D21_LEFT, 1}},                              // 2
-		{},                                                         // 3
-		{numKeypadPress{D21_LEFT, 2}, numKeypadPress{D21_UP, 1}}, // 4
-		{numKeypadPress{D21_LEFT, 1}, numKeypadPress{D21_UP, 1}}, // 5
-		{numKeypadPress{D21_UP, 1}},                              // 6
-		{numKeypadPress{D21_LEFT, 2}, numKeypadPress{D21_UP, 2}}, // 7
-		{numKeypadPress{D21_LEFT, 1}, numKeypadPress{D21_UP, 2}}, // 8
-		{numKeypadPress{D21_UP, 2}},                              // 9
-		{numKeypadPress{D21_DOWN, 1}},                            // PRESS
+		{numKeypadPress{LEFT, 1}, numKeypadPress{DOWN, 1}}, // 0
+		{numKeypadPress{LEFT, 2}},                          // 1
+		{numKeypadPress{LEFT, 1}},                          // 2
+		{},                                                 // 3
+		{numKeypadPress{LEFT, 2}, numKeypadPress{UP, 1}}, // 4
+		{numKeypadPress{LEFT, 1}, numKeypadPress{UP, 1}}, // 5
+		{numKeypadPress{UP, 1}},                          // 6
+		{numKeypadPress{LEFT, 2}, numKeypadPress{UP, 2}}, // 7
+		{numKeypadPress{LEFT, 1}, numKeypadPress{UP, 2}}, // 8
+		{numKeypadPress{UP, 2}},                          // 9
+		{numKeypadPress{DOWN, 1}},                        // PRESS
 	},
 	// From 4
 	{
-		{numKeypadPress{D21_RIGHT, 1}, numKeypadPress{D21_DOWN, 2}}, // 0
-		{numKeypadPress{D21_DOWN, 1}},                               // 1
-		{numKeypadPress{D21_DOWN, 1}, numKeypadPress{D21_RIGHT, 1}}, // 2
-		{numKeypadPress{D21_DOWN, 1}, numKeypadPress{D21_RIGHT, 2}}, // 3
-		{},                             // 4
-		{numKeypadPress{D21_RIGHT, 1}}, // 5
-		{numKeypadPress{D21_RIGHT, 2}}, // 6
-		{numKeypadPress{D21_UP, 1}},    // 7
-		{numKeypadPress{D21_UP, 1}, numKeypadPress{D21_RIGHT, 1}},   // 8
-		{numKeypadPress{D21_UP, 1}, numKeypadPress{D21_RIGHT, 2}},   // 9
-		{numKeypadPress{D21_RIGHT, 2}, numKeypadPress{D21_DOWN, 2}}, // PRESS
+		{numKeypadPress{RIGHT, 1}, numKeypadPress{DOWN, 2}}, // 0
+		{numKeypadPress{DOWN, 1}},                           // 1
+		{numKeypadPress{DOWN, 1}, numKeypadPress{RIGHT, 1}}, // 2
+		{numKeypadPress{DOWN, 1}, numKeypadPress{RIGHT, 2}}, // 3
+		{},                         // 4
+		{numKeypadPress{RIGHT, 1}}, // 5
+		{numKeypadPress{RIGHT, 2}}, // 6
+		{numKeypadPress{UP, 1}},    // 7
+		{numKeypadPress{UP, 1}, numKeypadPress{RIGHT, 1}},   // 8
+		{numKeypadPress{UP, 1}, numKeypadPress{RIGHT, 2}},   // 9
+		{numKeypadPress{RIGHT, 2}, numKeypadPress{DOWN, 2}}, // PRESS
 	},
 	// From 5
 	{
-		{numKeypadPress{D21_DOWN, 2}},                               // 0
-		{numKeypadPress{D21_DOWN, 1}, numKeypadPress{D21_LEFT, 1}},  // 1
-		{numKeypadPress{D21_DOWN, 1}},                               // 2
-		{numKeypadPress{D21_DOWN, 1}, numKeypadPress{D21_RIGHT, 1}}, // 3
-		{numKeypadPress{D21_LEFT, 1}},                               // 4
-		{},                                                          // 5
-		{numKeypadPress{D21_RIGHT, 1}},                              // 6
-		{numKeypadPress{D21_LEFT, 1}, numKeypadPress{D21_UP, 1}},    // 7
-		{numKeypadPress{D21_UP, 1}},                                 // 8
-		{numKeypadPress{D21_UP, 1}, numKeypadPress{D21_RIGHT, 1}},   // 9
-		{numKeypadPress{D21_RIGHT, 1}, numKeypadPress{D21_DOWN, 2}}, // PRESS
+		{numKeypadPress{DOWN, 2}},                           // 0
+		{numKeypadPress{DOWN, 1}, numKeypadPress{LEFT, 1}},  // 1
+		{numKeypadPress{DOWN, 1}},                           // 2
+		{numKeypadPress{DOWN, 1}, numKeypadPress{RIGHT, 1}}, // 3
+		{numKeypadPress{LEFT, 1}},                           // 4
+		{},                                                  // 5
+		{numKeypadPress{RIGHT, 1}},                          // 6
+		{numKeypadPress{LEFT, 1}, numKeypadPress{UP, 1}},    // 7
+		{numKeypadPress{UP, 1}},                             // 8
+		{numKeypadPress{UP, 1}, numKeypadPress{RIGHT, 1}},   // 9
+		{numKeypadPress{RIGHT, 1}, numKeypadPress{DOWN, 2}}, // PRESS
 	},
 	// From 6
 	{
-		{numKeypadPress{D21_LEFT, 1}, numKeypadPress{D21_DOWN, 2}}, // 0
-		{numKeypadPress{D21_DOWN, 1}, numKeypadPress{D21_LEFT, 2}}, // 1
-		{numKeypadPress{D21_DOWN, 1}, numKeypadPress{D21_LEFT, 1}}, // 2
-		{numKeypadPress{D21_DOWN, 1}},                              // 3
-		{numKeypadPress{D21_LEFT, 2}},                              // 4
-		{numKeypadPress{D21_LEFT, 1}},                              // 5
-		{},                                                         // 6
-		{numKeypadPress{D21_LEFT, 2}, numKeypadPress{D21_UP, 1}},   // 7
-		{numKeypadPress{D21_LEFT, 1}, numKeypadPress{D21_UP, 1}},   // 8
-		{numKeypadPress{D21_UP, 1}},                                // 9
-		{numKeypadPress{D21_DOWN, 2}},                              // PRESS
+		{numKeypadPress{LEFT, 1}, numKeypadPress{DOWN, 2}}, // 0
+		{numKeypadPress{DOWN, 1}, numKeypadPress{LEFT, 2}}, // 1
+		{numKeypadPress{DOWN, 1}, numKeypadPress{LEFT, 1}}, // 2
+		{numKeypadPress{DOWN, 1}},                          // 3
+		{numKeypadPress{LEFT, 2}},                          // 4
+		{numKeypadPress{LEFT, 1}},                          // 5
+		{},                                                 // 6
+		{numKeypadPress{LEFT, 2}, numKeypadPress{UP, 1}},   // 7
+		{numKeypadPress{LEFT, 1}, numKeypadPress{UP, 1}},   // 8
+		{numKeypadPress{UP, 1}},                            // 9
+		{numKeypadPress{DOWN, 2}},                          // PRESS
 	},
 	// From 7
 	{
-		{numKeypadPress{D21_RIGHT, 1}, numKeypadPress{D21_DOWN, 3}}, // 0
-		{numKeypadPress{D21_DOWN, 2}},                               // 1
-		{numKeypadPress{D21_DOWN, 2}, numKeypadPress{D21_RIGHT, 1}}, // 2
-		{numKeypadPress{D21_DOWN, 2}, numKeypadPress{D21_RIGHT, 2}}, // 3
-		{numKeypadPress{D21_DOWN, 1}},                               // 4
-		{numKeypadPress{D21_DOWN, 1}, numKeypadPress{D21_RIGHT, 1}}, // 5
-		{numKeypadPress{D21_DOWN, 1}, numKeypadPress{D21_RIGHT, 2}}, // 6
-		{},                             // 7
-		{numKeypadPress{D21_RIGHT, 1}}, // 8
-		{numKeypadPress{D21_RIGHT, 2}}, // 9
-		{numKeypadPress{D21_RIGHT, 2}, numKeypadPress{D21_DOWN, 3}}, // PRESS
+		{numKeypadPress{RIGHT, 1}, numKeypadPress{DOWN, 3}}, // 0
+		{numKeypadPress{DOWN, 2}},                           // 1
+		{numKeypadPress{DOWN, 2}, numKeypadPress{RIGHT, 1}}, // 2
+		{numKeypadPress{DOWN, 2}, numKeypadPress{RIGHT, 2}}, // 3
+		{numKeypadPress{DOWN, 1}},                           // 4
+		{numKeypadPress{DOWN, 1}, numKeypadPress{RIGHT, 1}}, // 5
+		{numKeypadPress{DOWN, 1}, numKeypadPress{RIGHT, 2}}, // 6
+		{},                         // 7
+		{numKeypadPress{RIGHT, 1}}, // 8
+		{numKeypadPress{RIGHT, 2}}, // 9
+		{numKeypadPress{RIGHT, 2}, numKeypadPress{DOWN, 3}}, // PRESS
 	},
 	// From 8
 	{
-		{numKeypadPress{D21_DOWN, 3}},                               // 0
-		{numKeypadPress{D21_LEFT, 1}, numKeypadPress{D21_DOWN, 2}},  // 1
-		{numKeypadPress{D21_DOWN, 2}},                               // 2
-		{numKeypadPress{D21_DOWN, 2}, numKeypadPress{D21_RIGHT, 1}}, // 3
-		{numKeypadPress{D21_LEFT, 1}, numKeypadPress{D21_DOWN, 1}},  // 4
-		{numKeypadPress{D21_DOWN, 1}},                               // 5
-		{numKeypadPress{D21_DOWN, 1}, numKeypadPress{D21_RIGHT, 1}}, // 6
-		{numKeypadPress{D21_LEFT, 1}},                               // 7
-		{},                                                          // 8
-		{numKeypadPress{D21_RIGHT, 1}},                              // 9
-		{numKeypadPress{D21_DOWN, 3}, numKeypadPress{D21_RIGHT, 1}}, // PRESS
+		{numKeypadPress{DOWN, 3}},                           // 0
+		{numKeypadPress{LEFT, 1}, numKeypadPress{DOWN, 2}},  // 1
+		{numKeypadPress{DOWN, 2}},                           // 2
+		{numKeypadPress{DOWN, 2}, numKeypadPress{RIGHT, 1}}, // 3
+		{numKeypadPress{LEFT, 1}, numKeypadPress{DOWN, 1}},  // 4
+		{numKeypadPress{DOWN, 1}},                           // 5
+		{numKeypadPress{DOWN, 1}, numKeypadPress{RIGHT, 1}}, // 6
+		{numKeypadPress{LEFT, 1}},                           // 7
+		{},                                                  // 8
+		{numKeypadPress{RIGHT, 1}},                          // 9
+		{numKeypadPress{DOWN, 3}, numKeypadPress{RIGHT, 1}}, // PRESS
 	},
 	// From 9
 	{
-		{numKeypadPress{D21_LEFT, 1}, numKeypadPress{D21_DOWN, 3}}, // 0
-		{numKeypadPress{D21_LEFT, 2}, numKeypadPress{D21_DOWN, 2}}, // 1
-		{numKeypadPress{D21_LEFT, 1}, numKeypadPress{D21_DOWN, 2}}, // 2
-		{numKeypadPress{D21_DOWN, 2}},                              // 3
-		{numKeypadPress{D21_LEFT, 2}, numKeypadPress{D21_DOWN, 1}}, // 4
-		{numKeypadPress{D21_LEFT, 1}, numKeypadPress{D21_DOWN, 1}}, // 5
-		{numKeypadPress{D21_DOWN, 1}},                              // 6
-		{numKeypadPress{D21_LEFT, 2}},                              // 7
-		{numKeypadPress{D21_LEFT, 1}},                              // 8
-		{},                                                         // 9
-		{numKeypadPress{D21_DOWN, 3}},                              // PRESS
+		{numKeypadPress{LEFT, 1}, numKeypadPress{DOWN, 3}}, // 0
+		{numKeypadPress{LEFT, 2}, numKeypadPress{DOWN, 2}}, // 1
+		{numKeypadPress{LEFT, 1}, numKeypadPress{DOWN, 2}}, // 2
+		{numKeypadPress{DOWN, 2}},                          // 3
+		{numKeypadPress{LEFT, 2}, numKeypadPress{DOWN, 1}}, // 4
+		{numKeypadPress{LEFT, 1}, numKeypadPress{DOWN, 1}}, // 5
+		{numKeypadPress{DOWN, 1}},                          // 6
+		{numKeypadPress{LEFT, 2}},                          // 7
+		{numKeypadPress{LEFT, 1}},                          // 8
+		{},                                                 // 9
+		{numKeypadPress{DOWN, 3}},                          // PRESS
 	},
 	// From PRESS
 	{
-		{numKeypadPress{D21_LEFT, 1}},                            // 0
-		{numKeypadPress{D21_UP, 1}, numKeypadPress{D21_LEFT, 2}}, // 1
-		{numKeypadPress{D21_LEFT, 1}, numKeypadPress{D21_UP, 1}}, // 2
-		{numKeypadPress{D21_UP, 1}},                              // 3
-		{numKeypadPress{D21_UP, 2}, numKeypadPress{D21_LEFT, 2}}, // 4
-		{numKeypadPress{D21_LEFT, 1}, numKeypadPress{D21_UP, 2}}, // 5
-		{numKeypadPress{D21_UP, 2}},                              // 6
-		{numKeypadPress{D21_UP, 3}, numKeypadPress{D21_LEFT, 2}}, // 7
-		{numKeypadPress{D21_UP, 3}, numKeypadPress{D21_LEFT, 1}}, // 8
-		{numKeypadPress{D21_UP, 3}},                              // 9
-		{},                                                       // PRESS
+		{numKeypadPress{LEFT, 1}},                        // 0
+		{numKeypadPress{UP, 1}, numKeypadPress{LEFT, 2}}, // 1
+		{numKeypadPress{LEFT, 1}, numKeypadPress{UP, 1}}, // 2
+		{numKeypadPress{UP, 1}},                          // 3
+		{numKeypadPress{UP, 2}, numKeypadPress{LEFT, 2}}, // 4
+		{numKeypadPress{LEFT, 1}, numKeypadPress{UP, 2}}, // 5
+		{numKeypadPress{UP, 2}},                          // 6
+		{numKeypadPress{UP, 3}, numKeypadPress{LEFT, 2}}, // 7
+		{numKeypadPress{UP, 3}, numKeypadPress{LEFT, 1}}, // 8
+		{numKeypadPress{UP, 3}},                          // 9
+		{},                                               // PRESS
 	},
 }
 
-func findCodeComplexity(codeStr string, dirsLevels int, c chan int) {
+func (solver *d21Solver) findCodeComplexity(codeStr string, dirsLevels int) int {
 	code := make([]int, len(codeStr)+1)
 	code[0] = D21_PRESS_NUM
 	code[len(code)-1] = D21_PRESS_NUM
@@ -267,36 +279,29 @@ func findCodeComplexity(codeStr string, dirsLevels int, c chan int) {
 		number += code[ix+1]
 	}
 
-	sequenceLen := convertNumsToDirs(code, dirsLevels)
+	sequenceLen := solver.convertNumsToDirs(code, dirsLevels)
 
-	c <- number * sequenceLen
+	return number * sequenceLen
 }
 
 // Convert a sequence of numerically-encoded directions
 // to a string, primarily so it can be easily used as
 // a key in a hash map.
-func dirSeqToString(seq []d21DirKeypadButton) string {
+func dirSeqToString(seq []Direction) string {
 	runes := make([]rune, len(seq))
 	for ix, dir := range seq {
-		switch dir {
-		case D21_UP:
-			runes[ix] = '^'
-		case D21_DOWN:
-			runes[ix] = 'v'
-		case D21_LEFT:
-			runes[ix] = '<'
-		case D21_RIGHT:
-			runes[ix] = '>'
-		case D21_PRESS:
+		if dir == D21_PRESS {
 			runes[ix] = 'A'
+		} else {
+			runes[ix] = dir.Arrow()
 		}
 	}
 	return string(runes)
 }
 
-func convertNumsToDirs(nums []int, dirsLevels int) int {
+func (solver *d21Solver) convertNumsToDirs(nums []int, dirsLevels int) int {
 	// 6 is max presses to get from one num to another
-	sequence := make([]d21DirKeypadButton, 1, len(nums)*6+1)
+	sequence := make([]Direction, 1, len(nums)*6+1)
 
 	// We always put PRESS at the start of a sequence because sequence
 	// conversion depends on knowing what the previous button was, and
@@ -315,7 +320,7 @@ func convertNumsToDirs(nums []int, dirsLevels int) int {
 		sequence = append(sequence, D21_PRESS)
 	}
 
-	return convertDirsToDirs(sequence, dirsLevels)
+	return solver.convertDirsToDirs(sequence, dirsLevels)
 }
 
 type d21CacheKey struct {
@@ -323,9 +328,27 @@ type d21CacheKey struct {
 	remainingLevels int
 }
 
-var d21Cache sync.Map = sync.Map{}
+// Everything we've worked out so far. The cache is valid for any
+// code, and for both parts, so one solver is shared across a run.
+type d21Solver struct {
+	cache sync.Map
+
+	// How well the cache is doing, for logging.
+	cacheHits   atomic.Int64
+	cacheMisses atomic.Int64
+}
+
+func (solver *d21Solver) logCacheStats(logger *slog.Logger) {
+	hits, misses := int(solver.cacheHits.Load()), int(solver.cacheMisses.Load())
+	logger.Debug("Cache stats so far", slog.Int("cacheHits", hits), slog.Int("cacheMisses", misses), slog.Float64("cacheHitRate", hitRate(hits, misses)))
+}
+
+type d21context struct {
+	codes  []string
+	solver *d21Solver
+}
 
-func convertDirsToDirs(dirs []d21DirKeypadButton, remainingLevels int) int {
+func (solver *d21Solver) convertDirsToDirs(dirs []Direction, remainingLevels int) int {
 	if remainingLevels == 0 {
 		return len(dirs) - 1
 	}
@@ -333,10 +356,12 @@ func convertDirsToDirs(dirs []d21DirKeypadButton, remainingLevels int) int {
 	// See if we've already answered this question.
 	dirsStr := dirSeqToString(dirs)
 	key := d21CacheKey{dirsStr, remainingLevels}
-	val, found := d21Cache.Load(key)
+	val, found := solver.cache.Load(key)
 	if found {
+		solver.cacheHits.Add(1)
 		return val.(int)
 	}
+	solver.cacheMisses.Add(1)
 
 	// This code will break if dirs doesn't both start and end with D21_PRESS,
 	// but that should always be the case.
@@ -355,7 +380,7 @@ func convertDirsToDirs(dirs []d21DirKeypadButton, remainingLevels int) int {
 		// It takes max 4 keypresses to get from one direction to another, so
 		// max length of converted sequence is 4 times unconverted sequence
 		// plus an opening PRESS.
-		sequence := make([]d21DirKeypadButton, 1, (endIx-startIx)*4+1)
+		sequence := make([]Direction, 1, (endIx-startIx)*4+1)
 		sequence[0] = D21_PRESS
 		for ; startIx < endIx; startIx++ {
 			// Add to the sequence
@@ -364,36 +389,55 @@ func convertDirsToDirs(dirs []d21DirKeypadButton, remainingLevels int) int {
 
 		// Pass this input to the next robot up the chain; it'll return the
 		// length of the sequence at the end of the chain.
-		totalLen += convertDirsToDirs(sequence, remainingLevels-1)
+		totalLen += solver.convertDirsToDirs(sequence, remainingLevels-1)
 	}
 
 	// Cache this result.
-	d21Cache.Store(key, totalLen)
+	solver.cache.Store(key, totalLen)
 	return totalLen
 }
 
-func Day21Part1(logger *slog.Logger, input string) (string, any) {
+// Each code is some digits followed by an A.
+func checkD21Codes(lines []string) error {
+	for lineIx, line := range lines {
+		if len(line) < 2 || line[len(line)-1] != 'A' {
+			return errorAt(lineIx+1, 0, "expected a code ending in A, found %q", line)
+		}
+		for colIx := 0; colIx < len(line)-1; colIx++ {
+			if line[colIx] < '0' || line[colIx] > '9' {
+				return errorAt(lineIx+1, colIx+1, "expected a digit, found %q", line[colIx])
+			}
+		}
+	}
+	return nil
+}
+
+func Day21Part1(ctx context.Context, logger *slog.Logger, input string) (string, d21context, error) {
 	lines := strings.Fields(input)
-	c := make(chan int)
-	sum := 0
-	for _, line := range lines {
-		go findCodeComplexity(line, 2, c)
+	if err := checkD21Codes(lines); err != nil {
+		return "", d21context{}, err
 	}
-	for range lines {
-		sum += <-c
+	logger.Debug("Parsed input", slog.Int("codes", len(lines)))
+	solver := &d21Solver{}
+	robots := d21Part1Robots.get(ctx)
+	sum, err := parallelSum(ctx, len(lines), func(ix int) int {
+		return solver.findCodeComplexity(lines[ix], robots)
+	})
+	if err != nil {
+		return "", d21context{}, err
 	}
-	return strconv.Itoa(sum), lines
+	solver.logCacheStats(logger)
+	return strconv.Itoa(sum), d21context{lines, solver}, nil
 }
 
-func Day21Part2(logger *slog.Logger, input string, part1Context any) string {
-	lines := part1Context.([]string)
-	c := make(chan int)
-	sum := 0
-	for _, line := range lines {
-		go findCodeComplexity(line, 25, c)
-	}
-	for range lines {
-		sum += <-c
+func Day21Part2(ctx context.Context, logger *slog.Logger, input string, part1Context d21context) (string, error) {
+	robots := d21Part2Robots.get(ctx)
+	sum, err := parallelSum(ctx, len(part1Context.codes), func(ix int) int {
+		return part1Context.solver.findCodeComplexity(part1Context.codes[ix], robots)
+	})
+	if err != nil {
+		return "", err
 	}
-	return strconv.Itoa(sum)
+	part1Context.solver.logCacheStats(logger)
+	return strconv.Itoa(sum), nil
 }
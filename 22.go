@@ -1,15 +1,15 @@
 package main
 
 import (
+	"context"
 	"log/slog"
+	"slices"
 	"strconv"
 	"strings"
 	"sync/atomic"
-
-	runner "github.com/ThePants999/advent-of-code-go-runner"
 )
 
-var Day22 = runner.DayImplementation{
+var Day22 = newPuzzle(dayDefinition[[]uint32]{
 	DayNumber:    22,
 	ExecutePart1: Day22Part1,
 	ExecutePart2: Day22Part2,
@@ -19,6 +19,14 @@ var Day22 = runner.DayImplementation{
 2024`,
 	ExamplePart1Answer: "37990510",
 	ExamplePart2Answer: "23",
+	Parameters:         []*dayParameter{d22Iterations},
+})
+
+var d22Iterations = &dayParameter{
+	Name:        "iterations",
+	Description: "How many new secret numbers each buyer generates",
+	Default:     2000,
+	Example:     2000,
 }
 
 const PRUNE_BITS int = 0b111111111111111111111111
@@ -26,21 +34,16 @@ const PRUNE_BITS int = 0b111111111111111111111111
 const MAX_DELTAS int = 1 << 20
 const DELTA_MASK uint32 = 0b11111111111111111111
 
-var deltasSeenBy [MAX_DELTAS][100]uint32
-var priceForDeltas [MAX_DELTAS]uint32
-
 type d22Buyer struct {
-	seenByIndex int
-	seenByBit   uint32
-	secret      int
-	deltas      uint32
+	index  int32
+	secret int
+	deltas uint32
 }
 
 func newBuyer(index int, initialSecret int) d22Buyer {
 	return d22Buyer{
-		seenByIndex: index / 32,
-		seenByBit:   1 << (index % 32),
-		secret:      initialSecret}
+		index:  int32(index),
+		secret: initialSecret}
 }
 
 func (buyer *d22Buyer) updateSecretAndDelta() int {
@@ -62,50 +65,61 @@ func (buyer *d22Buyer) updateSecretAndDelta() int {
 	return price
 }
 
-func (buyer *d22Buyer) hasSeenCurrentDelta() bool {
+// The state for one thread modelling buyers, which it does one
+// after another.
+type d22Worker struct {
 	// Bit of shenanigans here. We want a data structure
 	// that allows us to very efficiently answer the
-	// question "has buyer X seen delta sequence Y before".
-	// Maps are slower than I'd like. But because we store
-	// delta sequences as 20-bit values, if we can store
-	// the answer to this question as a single bit, then
-	// we can do so for every possible delta sequence
-	// (2^20) for 3200 buyers in 400MB RAM, which is
-	// reasonable. So what we have here is an array of
-	// 2^20 arrays of 100 uint32s, where each buyer
-	// corresponds to one bit in one of those uint32s.
-	return deltasSeenBy[buyer.deltas][buyer.seenByIndex]&buyer.seenByBit != 0
+	// question "has this buyer seen delta sequence Y
+	// before". Maps are slower than I'd like. But because
+	// we store delta sequences as 20-bit values, we can
+	// have an array with an entry for every possible delta
+	// sequence (2^20). Each entry records which buyer last
+	// saw that sequence (plus one, so that zero means
+	// nobody has). As a thread handles its buyers one at
+	// a time, that's all we need to know whether the
+	// current buyer has seen it - and we never need to
+	// clear the array out between buyers.
+	lastSeenBy []int32
+
+	// The total price all buyers pay for each delta
+	// sequence. This one's shared between all threads.
+	priceForDeltas []uint32
+}
+
+func (worker *d22Worker) hasSeenCurrentDelta(buyer *d22Buyer) bool {
+	return worker.lastSeenBy[buyer.deltas] == buyer.index+1
 }
 
-func (buyer *d22Buyer) recordCurrentPrice(price int) {
-	// Use atomic operations to allow us to update these
-	// from different threads without any locking.
-	atomic.OrUint32(&deltasSeenBy[buyer.deltas][buyer.seenByIndex], buyer.seenByBit)
-	atomic.AddUint32(&priceForDeltas[buyer.deltas], uint32(price))
+func (worker *d22Worker) recordCurrentPrice(buyer *d22Buyer, price int) {
+	worker.lastSeenBy[buyer.deltas] = buyer.index + 1
+	// Use atomic operations to allow us to update the
+	// shared totals from different threads without any
+	// locking.
+	atomic.AddUint32(&worker.priceForDeltas[buyer.deltas], uint32(price))
 }
 
-func (buyer *d22Buyer) generateAllSecrets(c chan int) {
+func (worker *d22Worker) generateAllSecrets(buyer *d22Buyer, iterations int) int {
 	// The procedure here is that we do go through each of the
-	// 2000 secret number updates, but we figure everything out
-	// in that single pass.
+	// (usually 2000) secret number updates, but we figure
+	// everything out in that single pass.
 	//
 	// We maintain the last four price deltas as a single
 	// 20-bit number. We can then use that as a key into
-	// an effectively per-buyer record of whether this buyer
-	// has seen that sequence before, and a global record of
-	// what the total price all buyers pay when they see that
-	// sequence is.
-	for ix := range 2000 {
+	// a record of whether this buyer has seen that sequence
+	// before, and a record of what the total price all
+	// buyers pay when they see that sequence is.
+	for ix := range iterations {
 		price := buyer.updateSecretAndDelta()
 
-		if ix > 2 && !buyer.hasSeenCurrentDelta() {
+		if ix > 2 && !worker.hasSeenCurrentDelta(buyer) {
 			// This is the first time we've seen this delta sequence for this buyer.
 			// Record that we've seen it, and add the current price to the total
 			// price that you get for this delta sequence.
-			buyer.recordCurrentPrice(price)
+			worker.recordCurrentPrice(buyer, price)
 		}
 	}
-	c <- buyer.secret
+	return buyer.secret
 }
 
 func calcNextSecret(secret int) int {
@@ -115,40 +129,45 @@ func calcNextSecret(secret int) int {
 	return secret
 }
 
-func Day22Part1(logger *slog.Logger, input string) (string, any) {
+func Day22Part1(ctx context.Context, logger *slog.Logger, input string) (string, []uint32, error) {
 	lines := strings.Fields(input)
-	buyers := make([]d22Buyer, 0, len(lines))
-
-	// Each buyer is completely independent. So as we parse
-	// each one from the input, kick off a goroutine to start
-	// modelling it.
-	c := make(chan int)
+	secrets := make([]int, len(lines))
 	for ix, line := range lines {
-		secret, _ := strconv.Atoi(line)
-		buyer := newBuyer(ix, secret)
-		buyers = append(buyers, buyer)
-		go buyer.generateAllSecrets(c)
+		secret, err := atoiAt(line, ix+1, 1)
+		if err != nil {
+			return "", nil, err
+		}
+		if secret < 0 {
+			return "", nil, errorAt(ix+1, 1, "secret numbers can't be negative")
+		}
+		secrets[ix] = secret
 	}
-
-	// Collate results.
-	sum := 0
-	for range buyers {
-		sum += <-c
+	logger.Debug("Parsed input", slog.Int("buyers", len(secrets)))
+
+	// Each buyer is completely independent, so we split them
+	// into one chunk per worker pool goroutine, with each chunk
+	// working through its share one at a time.
+	priceForDeltas := make([]uint32, MAX_DELTAS)
+	iterations := d22Iterations.get(ctx)
+	sum, err := parallelSumChunks(ctx, len(secrets), func(first int, last int) int {
+		worker := d22Worker{make([]int32, MAX_DELTAS), priceForDeltas}
+		sum := 0
+		for ix := first; ix < last; ix++ {
+			buyer := newBuyer(ix, secrets[ix])
+			sum += worker.generateAllSecrets(&buyer, iterations)
+		}
+		return sum
+	})
+	if err != nil {
+		return "", nil, err
 	}
 
-	return strconv.Itoa(sum), nil
+	return strconv.Itoa(sum), priceForDeltas, nil
 }
 
-func Day22Part2(logger *slog.Logger, input string, part1Context any) string {
+func Day22Part2(ctx context.Context, logger *slog.Logger, input string, priceForDeltas []uint32) (string, error) {
 	// We already calculated, during part 1, the total
 	// price buyers pay for every delta they see. So
 	// all we need to do now is find the highest.
-	var bestResult uint32
-	for deltas := range MAX_DELTAS {
-		if priceForDeltas[deltas] > bestResult {
-			bestResult = priceForDeltas[deltas]
-		}
-	}
-
-	return strconv.Itoa(int(bestResult))
+	return strconv.Itoa(int(slices.Max(priceForDeltas))), nil
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"iter"
 	"log/slog"
 	"maps"
@@ -9,11 +10,9 @@ import (
 	"strings"
 
 	"github.com/golang-collections/collections/set"
-
-	runner "github.com/ThePants999/advent-of-code-go-runner"
 )
 
-var Day23 = runner.DayImplementation{
+var Day23 = newPuzzle(dayDefinition[ComputerSet]{
 	DayNumber:    23,
 	ExecutePart1: Day23Part1,
 	ExecutePart2: Day23Part2,
@@ -51,7 +50,7 @@ tb-vc
 td-yn`,
 	ExamplePart1Answer: "7",
 	ExamplePart2Answer: "co,de,ka,ta",
-}
+})
 
 // Bloody Go not having a set data structure in its
 // standard library. Here's a hand-rolled set implementation.
@@ -116,18 +115,23 @@ func newComputer(name string) d23Computer {
 	return d23Computer{name, make(ComputerSet)}
 }
 
-func Day23Part1(logger *slog.Logger, input string) (string, any) {
+func Day23Part1(ctx context.Context, logger *slog.Logger, input string) (string, ComputerSet, error) {
 	// Parse input. We end up with a set of computer
 	// structs, each of which knows the set of other
 	// computers it's connected to.
 	lines := strings.Fields(input)
 	computers := make(ComputerSet)
-	for _, line := range lines {
-		comp1 := computers.findOrAdd(line[0:2])
-		comp2 := computers.findOrAdd(line[3:5])
+	for lineIx, line := range lines {
+		name1, name2, found := strings.Cut(line, "-")
+		if !found || len(name1) == 0 || len(name2) == 0 {
+			return "", ComputerSet{}, errorAt(lineIx+1, 0, "expected a connection like kh-tc, found %q", line)
+		}
+		comp1 := computers.findOrAdd(name1)
+		comp2 := computers.findOrAdd(name2)
 		comp1.connections[comp2.name] = comp2
 		comp2.connections[comp1.name] = comp1
 	}
+	logger.Debug("Parsed input", slog.Int("connections", len(lines)), slog.Int("computers", len(computers)))
 
 	// Brute force part 1. Go through all computers starting
 	// T, then consider each of their neighbours in turn,
@@ -172,38 +176,54 @@ func Day23Part1(logger *slog.Logger, input string) (string, any) {
 		handled.Insert(comp1.name)
 	}
 
-	return strconv.Itoa(sum), computers
+	return strconv.Itoa(sum), computers, nil
 }
 
-func Day23Part2(logger *slog.Logger, input string, part1Context any) string {
+func Day23Part2(ctx context.Context, logger *slog.Logger, input string, computers ComputerSet) (string, error) {
 	// For part 2, we just use
 	// https://en.wikipedia.org/wiki/Bron%E2%80%93Kerbosch_algorithm,
 	// with a slight enhancement to give up whenever we're considering
 	// a set too small to exceed the biggest clique we've already
 	// found.
-	bestSet := make(ComputerSet)
-	computers := part1Context.(ComputerSet)
-	bronKerbosch(make(ComputerSet), computers, make(ComputerSet), &bestSet)
+	//
+	// The worst case is exponential, so this also gives up if we're
+	// cancelled.
+	search := d23Search{make(ComputerSet), newCancelChecker(ctx), logger}
+	if err := search.bronKerbosch(make(ComputerSet), computers, make(ComputerSet)); err != nil {
+		return "", err
+	}
 
-	finalSet := slices.Collect(maps.Keys(bestSet))
+	finalSet := slices.Collect(maps.Keys(search.best))
 	slices.Sort(finalSet)
-	return strings.Join(finalSet, ",")
+	return strings.Join(finalSet, ","), nil
 }
 
-func bronKerbosch(r ComputerSet, p ComputerSet, x ComputerSet, best *ComputerSet) {
-	if len(r)+len(p) <= len(*best) {
+// The state of the search for the biggest clique.
+type d23Search struct {
+	best    ComputerSet
+	checker *cancelChecker
+	logger  *slog.Logger
+}
+
+func (search *d23Search) bronKerbosch(r ComputerSet, p ComputerSet, x ComputerSet) error {
+	if err := search.checker.check(); err != nil {
+		return err
+	}
+
+	if len(r)+len(p) <= len(search.best) {
 		// We don't have enough candidate vertices left to exceed the
 		// biggest we've already found, give up here
-		return
+		return nil
 	}
 
 	if len(p) == 0 && len(x) == 0 {
 		// There's nothing more we could add, r is a maximal clique
-		if len(r) > len(*best) {
+		if len(r) > len(search.best) {
 			// And it's bigger than any other we've found so far
-			*best = r
+			search.logger.Debug("Found a bigger clique", slog.Int("size", len(r)))
+			search.best = r
 		}
-		return
+		return nil
 	}
 
 	// We could perhaps be more efficient with an
@@ -223,8 +243,11 @@ func bronKerbosch(r ComputerSet, p ComputerSet, x ComputerSet, best *ComputerSet
 		newR[k] = v
 		pIntersectNeighbours := p.Intersection(v.connections)
 		xIntersectNeighbours := x.Intersection(v.connections)
-		bronKerbosch(newR, pIntersectNeighbours, xIntersectNeighbours, best)
+		if err := search.bronKerbosch(newR, pIntersectNeighbours, xIntersectNeighbours); err != nil {
+			return err
+		}
 		delete(p, k)
 		x[k] = v
 	}
+	return nil
 }
@@ -1,22 +1,31 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"slices"
 	"strconv"
 	"strings"
-
-	runner "github.com/ThePants999/advent-of-code-go-runner"
 )
 
-var Day24 = runner.DayImplementation{
+var Day24 = newPuzzle(dayDefinition[*d24Circuit]{
 	DayNumber:          24,
 	ExecutePart1:       Day24Part1,
 	ExecutePart2:       Day24Part2,
 	ExampleInput:       ``,
 	ExamplePart1Answer: "",
 	ExamplePart2Answer: "",
+	Parameters:         []*dayParameter{d24SwappedPairs},
+})
+
+// The puzzle's examples aren't adders, so there's nothing for part 2
+// to find in them - or in any other input that isn't an adder. Use
+// --param 24.swappedPairs=0 to skip part 2 for those.
+var d24SwappedPairs = &dayParameter{
+	Name:        "swappedPairs",
+	Description: "How many pairs of gates have had their outputs swapped, or 0 to skip part 2",
+	Default:     4,
 }
 
 type d24Operator int
@@ -50,6 +59,7 @@ type d24FixedInput struct {
 	name   string
 	value  bool
 	output *d24Wire
+	line   int
 }
 
 func (input *d24FixedInput) provide() bool {
@@ -85,6 +95,7 @@ type d24Gate struct {
 	input1   *d24Wire
 	input2   *d24Wire
 	output   *d24Wire
+	line     int
 }
 
 func (gate *d24Gate) provide() bool {
@@ -106,8 +117,8 @@ func (gate *d24Gate) provide() bool {
 // Instantiate a new gate. Will also set up any of the corresponding
 // wires that haven't already been created, as well as establishing
 // the relationships between them.
-func newGate(operator d24Operator, input1 string, input2 string, output string, wires map[string]*d24Wire) *d24Gate {
-	gate := d24Gate{operator, input1, input2, output, nil, nil, nil}
+func newGate(operator d24Operator, input1 string, input2 string, output string, wires map[string]*d24Wire, line int) *d24Gate {
+	gate := d24Gate{operator, input1, input2, output, nil, nil, nil, line}
 	var found bool
 
 	gate.input1, found = wires[input1]
@@ -140,6 +151,7 @@ type d24Circuit struct {
 	xInputs     []*d24FixedInput
 	yInputs     []*d24FixedInput
 	outputWires []*d24Wire
+	gates       []*d24Gate
 	correctZ    int64
 	originalZ   int64
 }
@@ -149,8 +161,17 @@ type d24Circuit struct {
 // and can be found, courtesy of the connectivity
 // between all the elements. We just store the
 // externally-facing elements to facilitate
-// interaction.
-func newCircuit(wires map[string]*d24Wire, xInputs []*d24FixedInput, yInputs []*d24FixedInput) *d24Circuit {
+// interaction (plus a list of gates, so we can check
+// the circuit for loops).
+func newCircuit(wires map[string]*d24Wire, xInputs []*d24FixedInput, yInputs []*d24FixedInput, gates []*d24Gate) (*d24Circuit, error) {
+	// Quick double-check of some assumptions.
+	if len(xInputs) == 0 {
+		return nil, errorAt(0, 0, "no X inputs")
+	}
+	if len(xInputs) >= 64 {
+		return nil, errorAt(0, 0, "too many X inputs to add in 64 bits")
+	}
+
 	outputWires := make([]*d24Wire, 64)
 	var x, y int64
 	var maxXWire, maxYWire int
@@ -176,29 +197,68 @@ func newCircuit(wires map[string]*d24Wire, xInputs []*d24FixedInput, yInputs []*
 		}
 	}
 
-	circuit := &d24Circuit{xInputs, yInputs, outputWires, x + y, 0}
-	circuit.originalZ = circuit.zValue()
-
-	// Quick double-check of some assumptions.
 	if maxXWire != maxYWire {
-		panic("Different numbers of X and Y inputs")
+		return nil, errorAt(0, 0, "different numbers of X and Y inputs")
 	}
 	if len(xInputs) != maxXWire+1 {
-		panic("Unexpected numbering of X inputs")
+		return nil, errorAt(0, 0, "unexpected numbering of X inputs")
 	}
 	if len(yInputs) != maxYWire+1 {
-		panic("Unexpected numbering of Y inputs")
+		return nil, errorAt(0, 0, "unexpected numbering of Y inputs")
 	}
 	_, found := wires[fmt.Sprintf("z%02d", len(xInputs))]
 	if !found {
-		panic("Not enough Z wires")
+		return nil, errorAt(0, 0, "not enough Z wires")
 	}
 	_, found = wires[fmt.Sprintf("z%02d", len(xInputs)+1)]
 	if found {
-		panic("Too many Z wires")
+		return nil, errorAt(0, 0, "too many Z wires")
+	}
+
+	circuit := &d24Circuit{xInputs, yInputs, outputWires, gates, x + y, 0}
+	if err := circuit.checkForLoops(); err != nil {
+		return nil, err
+	}
+	circuit.originalZ = circuit.zValue()
+
+	return circuit, nil
+}
+
+// Calculating a value recurses upstream through the gates, so
+// a loop in the circuit would recurse forever.
+func (circuit *d24Circuit) checkForLoops() error {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[*d24Gate]int, len(circuit.gates))
+	var visit func(gate *d24Gate) error
+	visit = func(gate *d24Gate) error {
+		switch state[gate] {
+		case visiting:
+			return errorAt(gate.line, 0, "circuit loops back on itself at wire %s", gate.outputN)
+		case done:
+			return nil
+		}
+		state[gate] = visiting
+		for _, wire := range []*d24Wire{gate.input1, gate.input2} {
+			if upstream, isGate := wire.provider.(*d24Gate); isGate {
+				if err := visit(upstream); err != nil {
+					return err
+				}
+			}
+		}
+		state[gate] = done
+		return nil
 	}
 
-	return circuit
+	for _, gate := range circuit.gates {
+		if err := visit(gate); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // Calculate the value currently being output on the
@@ -214,72 +274,94 @@ func (circuit *d24Circuit) zValue() int64 {
 }
 
 // Construct the entire circuit from the day's input.
-func parseD24Input(input string) *d24Circuit {
+func parseD24Input(input string) (*d24Circuit, error) {
 	lines := strings.Split(input, "\n")
 
 	// Parse the first section - wires corresponding
-	// to fixed inputs.
+	// to fixed inputs, like "x00: 1".
 	wires := make(map[string]*d24Wire)
 	xInputs := make([]*d24FixedInput, 0, 64)
 	yInputs := make([]*d24FixedInput, 0, 64)
-	for ix, line := range lines {
-		if len(line) == 0 {
-			lines = lines[ix+1:]
-			break
+	lineIx := 0
+	for ; lineIx < len(lines) && len(lines[lineIx]) > 0; lineIx++ {
+		line := lines[lineIx]
+		name, valStr, found := strings.Cut(line, ": ")
+		if !found || (valStr != "0" && valStr != "1") {
+			return nil, errorAt(lineIx+1, 0, "expected an initial value like x00: 1, found %q", line)
 		}
-
-		val := false
-		if line[5] == '1' {
-			val = true
+		if len(name) == 0 || (name[0] != 'x' && name[0] != 'y') {
+			return nil, errorAt(lineIx+1, 1, "initial values are only expected for x and y wires, found %s", name)
+		}
+		if _, found := wires[name]; found {
+			return nil, errorAt(lineIx+1, 1, "wire %s given more than one initial value", name)
 		}
 
-		input := &d24FixedInput{line[0:3], val, nil}
-		wire := newWire(line[0:3], input)
-		wires[line[0:3]] = wire
+		input := &d24FixedInput{name, valStr == "1", nil, lineIx + 1}
+		wire := newWire(name, input)
+		wires[name] = wire
 		input.output = wire
-		if line[0] == 'x' {
+		if name[0] == 'x' {
 			xInputs = append(xInputs, input)
 		} else {
 			yInputs = append(yInputs, input)
 		}
 	}
 
-	// Cope with blank line at end
-	if len(lines[len(lines)-1]) == 0 {
-		lines = lines[0 : len(lines)-1]
-	}
-
-	// Parse the second section - gates.
-	gates := make(map[string]*d24Gate)
-	for _, line := range lines {
+	// Parse the second section - gates, like
+	// "x00 AND y00 -> z00".
+	gates := make([]*d24Gate, 0, len(lines))
+	for lineIx++; lineIx < len(lines); lineIx++ {
+		line := lines[lineIx]
+		if len(line) == 0 {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 5 || fields[3] != "->" {
+			return nil, errorAt(lineIx+1, 0, "expected a gate like x00 AND y00 -> z00, found %q", line)
+		}
 		var op d24Operator
-		postOpIndex := 7
-		switch line[4] {
-		case 'X':
+		switch fields[1] {
+		case "XOR":
 			op = D24_XOR
-		case 'A':
+		case "AND":
 			op = D24_AND
-		case 'O':
+		case "OR":
 			op = D24_OR
-			postOpIndex--
+		default:
+			return nil, errorAt(lineIx+1, len(fields[0])+2, "unknown operator %q", fields[1])
+		}
+		if existing, found := wires[fields[4]]; found && existing.provider != nil {
+			return nil, errorAt(lineIx+1, 0, "wire %s has more than one input", fields[4])
+		}
+		gates = append(gates, newGate(op, fields[0], fields[2], fields[4], wires, lineIx+1))
+	}
+
+	// Every wire needs something providing its value.
+	for _, wire := range wires {
+		if wire.provider == nil {
+			return nil, errorAt(wire.downstreamGates[0].line, 0, "wire %s is never given a value", wire.name)
 		}
-		gates[line[postOpIndex+8:]] = newGate(op, line[0:3], line[postOpIndex+1:postOpIndex+4], line[postOpIndex+8:], wires)
 	}
 
-	return newCircuit(wires, xInputs, yInputs)
+	return newCircuit(wires, xInputs, yInputs, gates)
 }
 
-func Day24Part1(logger *slog.Logger, input string) (string, any) {
+func Day24Part1(ctx context.Context, logger *slog.Logger, input string) (string, *d24Circuit, error) {
 	// Part 1 is trivial - after constructing the circuit,
 	// just return the initial Z value.
-	circuit := parseD24Input(input)
-	return strconv.Itoa(int(circuit.originalZ)), circuit
+	circuit, err := parseD24Input(input)
+	if err != nil {
+		return "", nil, err
+	}
+	logger.Debug("Parsed input", slog.Int("inputBits", len(circuit.xInputs)), slog.Int("gates", len(circuit.gates)))
+	return strconv.Itoa(int(circuit.originalZ)), circuit, nil
 }
 
-func Day24Part2(logger *slog.Logger, input string, part1Context any) string {
-	// Skip example input, which isn't useful for part 2.
-	if len(input) < 100 {
-		return ""
+func Day24Part2(ctx context.Context, logger *slog.Logger, input string, circuit *d24Circuit) (string, error) {
+	swappedPairs := d24SwappedPairs.get(ctx)
+	if swappedPairs == 0 {
+		logger.Info("Skipping part 2, as there are no swapped pairs to find")
+		return "", nil
 	}
 
 	// The approach we're going to take here is to firmly
@@ -304,21 +386,22 @@ func Day24Part2(logger *slog.Logger, input string, part1Context any) string {
 	// validate it against this pattern, identifying what's
 	// wrong if it doesn't conform.
 
-	circuit := part1Context.(*d24Circuit)
-	gatesToSwap := make([]*d24Gate, 0, 8)
+	gatesToSwap := make([]*d24Gate, 0, 2*swappedPairs)
 
 	for ix, xInput := range circuit.xInputs {
+		foundSoFar := len(gatesToSwap)
+
 		// X and Y inputs are paired together and should connect to
 		// the same downstream gates.
 		yInput := circuit.yInputs[ix]
 		if yInput.name[1:] != xInput.name[1:] {
-			panic("X and Y inputs don't match up")
+			return "", errorAt(yInput.line, 0, "X and Y inputs don't match up: %s and %s", xInput.name, yInput.name)
 		}
 		if len(xInput.output.downstreamGates) != 2 || len(yInput.output.downstreamGates) != 2 {
-			panic("X or Y input not connected to 2 gates")
+			return "", errorAt(xInput.line, 0, "%s or %s not connected to 2 gates", xInput.name, yInput.name)
 		}
 		if (xInput.output.downstreamGates[0] != yInput.output.downstreamGates[0] && xInput.output.downstreamGates[0] != yInput.output.downstreamGates[1]) || (xInput.output.downstreamGates[1] != yInput.output.downstreamGates[0] && xInput.output.downstreamGates[1] != yInput.output.downstreamGates[1]) {
-			panic("X and Y inputs not connected to same gates")
+			return "", errorAt(xInput.line, 0, "%s and %s not connected to the same gates", xInput.name, yInput.name)
 		}
 
 		if ix == 0 {
@@ -337,16 +420,16 @@ func Day24Part2(logger *slog.Logger, input string, part1Context any) string {
 			upperXor = xInput.output.downstreamGates[0]
 			upperAnd = xInput.output.downstreamGates[1]
 			if upperAnd.operator != D24_AND {
-				panic("X input connected to XOR but not AND")
+				return "", errorAt(upperAnd.line, 0, "%s connected to XOR but not AND", xInput.name)
 			}
 		} else {
 			upperXor = xInput.output.downstreamGates[1]
 			upperAnd = xInput.output.downstreamGates[0]
 			if upperXor.operator != D24_XOR {
-				panic("X input not connected to XOR")
+				return "", errorAt(upperXor.line, 0, "%s not connected to XOR", xInput.name)
 			}
 			if upperAnd.operator != D24_AND {
-				panic("X input not connected to AND")
+				return "", errorAt(upperAnd.line, 0, "%s not connected to AND", xInput.name)
 			}
 		}
 
@@ -365,8 +448,11 @@ func Day24Part2(logger *slog.Logger, input string, part1Context any) string {
 		lowerAnd = findDownstream(upperXor, upperAnd, D24_AND)
 		lowerOr = findDownstream(upperXor, upperAnd, D24_OR)
 
-		if lowerXor != nil && len(lowerXor.output.downstreamGates) > 0 {
-			// Lower XOR should output just to a z wire.
+		if lowerXor != nil && (len(lowerXor.output.downstreamGates) > 0 || lowerXor.outputN != "z"+xInput.name[1:]) {
+			// Lower XOR should output just to this bit's z wire. (Any
+			// z wire isn't enough - in the last sub-circuit, it can be
+			// swapped with the final OR, which outputs to the extra z
+			// wire at the top.)
 			gatesToSwap = append(gatesToSwap, lowerXor)
 		}
 
@@ -393,35 +479,41 @@ func Day24Part2(logger *slog.Logger, input string, part1Context any) string {
 			}
 		}
 
+		for _, gate := range gatesToSwap[foundSoFar:] {
+			logger.Debug("Found a miswired gate", slog.String("subCircuit", xInput.name[1:]), slog.String("output", gate.outputN), slog.Int("line", gate.line))
+		}
 		if len(gatesToSwap)%2 != 0 {
-			panic("Odd number of gates swapped from one sub-circuit")
+			return "", errorAt(xInput.line, 0, "odd number of gates to swap in the sub-circuit for %s", xInput.name)
 		}
 	}
 
 	// Let's just check this all adds up before we
 	// confidently proclaim it the final result!
-	if len(gatesToSwap) < 8 {
-		panic("Haven't found all gates to swap")
+	if len(gatesToSwap) < 2*swappedPairs {
+		return "", errorAt(0, 0, "only found %d of the %d gates to swap", len(gatesToSwap), 2*swappedPairs)
+	}
+	if len(gatesToSwap) > 2*swappedPairs {
+		return "", errorAt(0, 0, "found %d gates to swap, expected %d", len(gatesToSwap), 2*swappedPairs)
+	}
+	for ix := 0; ix < len(gatesToSwap); ix += 2 {
+		logger.Info("Swapping gates", slog.String("output1", gatesToSwap[ix].outputN), slog.String("output2", gatesToSwap[ix+1].outputN))
+		swapGates(gatesToSwap[ix], gatesToSwap[ix+1])
 	}
-	if len(gatesToSwap) > 8 {
-		panic("Found too many gates to swap")
+	if err := circuit.checkForLoops(); err != nil {
+		return "", err
 	}
-	swapGates(gatesToSwap[0], gatesToSwap[1])
-	swapGates(gatesToSwap[2], gatesToSwap[3])
-	swapGates(gatesToSwap[4], gatesToSwap[5])
-	swapGates(gatesToSwap[6], gatesToSwap[7])
 	if circuit.zValue() != circuit.correctZ {
-		panic("Circuit still not outputting correct value after swaps")
+		return "", errorAt(0, 0, "circuit still not outputting the correct value after swaps")
 	}
 
 	// Output in the correct format - comma-separated,
 	// alphabetically ordered list of output names.
-	namesToSwap := make([]string, 8)
-	for ix := range 8 {
-		namesToSwap[ix] = gatesToSwap[ix].outputN
+	namesToSwap := make([]string, len(gatesToSwap))
+	for ix, gate := range gatesToSwap {
+		namesToSwap[ix] = gate.outputN
 	}
 	slices.Sort(namesToSwap)
-	return strings.Join(namesToSwap, ",")
+	return strings.Join(namesToSwap, ","), nil
 }
 
 func findDownstream(upstream1 *d24Gate, upstream2 *d24Gate, operator d24Operator) *d24Gate {
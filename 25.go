@@ -1,14 +1,13 @@
 package main
 
 import (
+	"context"
 	"log/slog"
 	"strconv"
 	"strings"
-
-	runner "github.com/ThePants999/advent-of-code-go-runner"
 )
 
-var Day25 = runner.DayImplementation{
+var Day25 = newPuzzle(dayDefinition[nothing]{
 	DayNumber:    25,
 	ExecutePart1: Day25Part1,
 	ExecutePart2: Day25Part2,
@@ -53,29 +52,44 @@ var Day25 = runner.DayImplementation{
 #####`,
 	ExamplePart1Answer: "3",
 	ExamplePart2Answer: "",
-}
+})
 
 // You don't need comments today. Merry Christmas!
 
-func Day25Part1(logger *slog.Logger, input string) (string, any) {
-	lines := strings.Fields(input)
-	locks := make([][]int, 0, len(lines)/7)
-	keys := make([][]int, 0, len(lines)/7)
-	for ix := 0; ix < len(lines); ix += 7 {
-		seq := make([]int, 5)
-		isALock := lines[ix][0] == '#'
-		first := ix
-		last := ix + 7
-		if isALock {
-			first++
-		} else {
-			last--
+func Day25Part1(ctx context.Context, logger *slog.Logger, input string) (string, nothing, error) {
+	lines := strings.Split(input, "\n")
+	locks := make([][]int, 0, len(lines)/8)
+	keys := make([][]int, 0, len(lines)/8)
+	// Each schematic is seven rows of five, followed by a
+	// blank line.
+	for ix := 0; ix < len(lines); ix += 8 {
+		if len(lines[ix]) == 0 {
+			continue
 		}
-		for _, row := range lines[first:last] {
-			for colIx, char := range row {
-				if char == '#' {
-					seq[colIx]++
-				}
+		if ix+7 > len(lines) {
+			return "", nothing{}, errorAt(ix+1, 0, "incomplete schematic, expected 7 rows")
+		}
+		schematic, err := parseGrid(lines[ix:ix+7], func(char rune) (bool, bool) {
+			return char == '#', char == '#' || char == '.'
+		})
+		if err != nil {
+			return "", nothing{}, offsetErrorLine(err, ix)
+		}
+		if schematic.NumCols() != 5 {
+			return "", nothing{}, errorAt(ix+1, 0, "expected 5 columns, found %d", schematic.NumCols())
+		}
+		isALock := schematic.At(gridPos{0, 0})
+		baseRow := 0
+		if !isALock {
+			baseRow = 6
+		}
+		// Transposing turns each column into a row, so each
+		// pin's height is the number of #s in that row, not
+		// counting the lock's top or the key's bottom.
+		seq := make([]int, 5)
+		for pos, filled := range schematic.Transpose().All() {
+			if filled && pos.col != baseRow {
+				seq[pos.row]++
 			}
 		}
 		if isALock {
@@ -84,6 +98,7 @@ func Day25Part1(logger *slog.Logger, input string) (string, any) {
 			keys = append(keys, seq)
 		}
 	}
+	logger.Debug("Parsed input", slog.Int("locks", len(locks)), slog.Int("keys", len(keys)))
 
 	sum := 0
 	for _, lock := range locks {
@@ -98,10 +113,10 @@ func Day25Part1(logger *slog.Logger, input string) (string, any) {
 		}
 	}
 
-	return strconv.Itoa(sum), nil
+	return strconv.Itoa(sum), nothing{}, nil
 }
 
-func Day25Part2(logger *slog.Logger, input string, part1Context any) string {
+func Day25Part2(ctx context.Context, logger *slog.Logger, input string, _ nothing) (string, error) {
 
-	return ""
+	return "", nil
 }
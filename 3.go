@@ -1,205 +1,104 @@
 package main
 
 import (
+	"context"
 	"log/slog"
 	"strconv"
-
-	runner "github.com/ThePants999/advent-of-code-go-runner"
 )
 
-var Day3 = runner.DayImplementation{
+var Day3 = newPuzzle(dayDefinition[*d3Stream]{
 	DayNumber:          3,
 	ExecutePart1:       Day3Part1,
 	ExecutePart2:       Day3Part2,
 	ExampleInput:       "xmul(2,4)&mul[3,7]!^don't()_mul(5,5)+mul(32,64](mul(11,8)undo()?mul(8,5))",
 	ExamplePart1Answer: "161",
 	ExamplePart2Answer: "48",
+})
+
+// The instructions hidden in the corrupted memory. I used to find
+// these with a hand-written state machine that knew about nothing
+// but mul, do and don't - and, as it turned out, let mul have as
+// many digits as it liked, not just the one to three it's meant to.
+// Now they're simply listed here, and scanner.go does the rest.
+var d3Instructions = [...]instructionSignature{
+	D3_MUL:  {"mul", 2, 1, 3},
+	D3_DO:   {"do", 0, 0, 0},
+	D3_DONT: {"don't", 0, 0, 0},
 }
 
-// Who needs regular expressions when you can simply
-// implement a hilariously overcomplicated finite
-// state machine? (By virtue of being specialised, this
-// approach is unsurprisingly faster.)
-
-type Action int
-
 const (
-	// Clear any buffers, as we won't be in the
-	// middle of anything after this character.
-	ACTION_RESET Action = iota
-
-	// Take no action.
-	ACTION_ACCEPTED
-
-	// Append this item to the buffer for operand
-	// 1.
-	ACTION_OPERAND_1
-
-	// Append this item to the buffer for operand
-	// 2.
-	ACTION_OPERAND_2
-
-	// Record that we're now disabled (i.e. start
-	// passing disabled=true into handleCharacter.)
-	ACTION_DISABLE
-
-	// Record that we're enabled (i.e. undo the above).
-	ACTION_ENABLE
-
-	// The current operation has been fully parsed
-	// and is valid; execute it.
-	ACTION_COMPLETED
+	D3_MUL int = iota
+	D3_DO
+	D3_DONT
 )
 
-type State int
+var d3Scanner = mustCompileInstructionScanner(d3Instructions[:])
 
-const (
-	STATE_INITIAL State = iota
-	STATE_M
-	STATE_U
-	STATE_L
-	STATE_D
-	STATE_O
-	STATE_N
-	STATE_APOSTROPHE
-	STATE_T
-	STATE_DO
-	STATE_DONT
-	STATE_OPERAND_1
-	STATE_OPERAND_2
-)
+func Day3Part1(ctx context.Context, logger *slog.Logger, input string) (string, *d3Stream, error) {
+	// Both parts' totals come out of the same scan, so part 2 has
+	// nothing left to do.
+	memory := newD3Stream()
+	memory.WriteString(input)
+	logger.Debug("Scanned input", slog.Int64("bytes", memory.bytes), slog.Int("muls", memory.muls))
 
-// Given the combination of what state we're in and
-// what character we just found, move to a new state
-// and take an appropriate action.
-func handleCharacter(char rune, state State, disabled bool) (Action, State) {
-	if disabled && state == STATE_INITIAL && char != 'd' {
-		// We're currently inside a "don't()" block, so we ignore
-		// everything until we find a "do()".
-		return ACTION_RESET, STATE_INITIAL
-	}
+	return strconv.Itoa(memory.total), memory, nil
+}
 
-	switch {
-	case char == 'm':
-		return ACTION_RESET, STATE_M
-	case char == 'u':
-		if state == STATE_M {
-			return ACTION_ACCEPTED, STATE_U
-		}
-	case char == 'l':
-		if state == STATE_U {
-			return ACTION_ACCEPTED, STATE_L
-		}
-	case char == 'd':
-		return ACTION_RESET, STATE_D
-	case char == 'o':
-		if state == STATE_D {
-			return ACTION_ACCEPTED, STATE_O
-		}
-	case char == 'n':
-		if state == STATE_O {
-			return ACTION_ACCEPTED, STATE_N
-		}
-	case char == '\'':
-		if state == STATE_N {
-			return ACTION_ACCEPTED, STATE_APOSTROPHE
-		}
-	case char == 't':
-		if state == STATE_APOSTROPHE {
-			return ACTION_ACCEPTED, STATE_T
-		}
-	case char == '(':
-		switch state {
-		case STATE_L:
-			return ACTION_ACCEPTED, STATE_OPERAND_1
-		case STATE_O:
-			return ACTION_ACCEPTED, STATE_DO
-		case STATE_T:
-			return ACTION_ACCEPTED, STATE_DONT
-		}
-	case char == ',':
-		if state == STATE_OPERAND_1 {
-			return ACTION_ACCEPTED, STATE_OPERAND_2
-		}
-	case char == ')':
-		switch state {
-		case STATE_OPERAND_2:
-			return ACTION_COMPLETED, STATE_INITIAL
-		case STATE_DO:
-			return ACTION_ENABLE, STATE_INITIAL
-		case STATE_DONT:
-			return ACTION_DISABLE, STATE_INITIAL
-		}
-	case char >= '0' && char <= '9':
-		if state == STATE_OPERAND_1 {
-			return ACTION_OPERAND_1, STATE_OPERAND_1
-		} else if state == STATE_OPERAND_2 {
-			return ACTION_OPERAND_2, STATE_OPERAND_2
-		}
-	}
-	return ACTION_RESET, STATE_INITIAL
+func Day3Part2(ctx context.Context, logger *slog.Logger, input string, memory *d3Stream) (string, error) {
+	logger.Debug("Scanned input", slog.Int("enabledMuls", memory.enabledMuls), slog.Int("disables", memory.disables))
+
+	return strconv.Itoa(memory.enabledTotal), nil
 }
 
-func Day3Part1(logger *slog.Logger, input string) (string, any) {
-	var operand1, operand2, sum int
-	state := STATE_INITIAL
-	var action Action
+// Corrupted memory arriving a chunk at a time, e.g. a dump far too
+// big to read in all at once, with both parts' totals kept up to
+// date as it goes. Only the scanner's state is carried from one
+// chunk to the next, so it takes the same memory however much it's
+// given, and an instruction split between chunks still counts.
+type d3Stream struct {
+	scan  *scanState
+	bytes int64
+
+	// Part 1 adds up every mul, part 2 only the ones that aren't
+	// after a don't() without a do() in between.
+	total, enabledTotal int
+	muls, enabledMuls   int
+	disables            int
+	enabled             bool
+}
 
-	// Pretty simple - handle one character at a time,
-	// maintaining buffers for operands 1 and 2, and then
-	// adding their product to our cumulative total once
-	// fully parsed.
-	for _, char := range input {
-		action, state = handleCharacter(char, state, false)
-		switch action {
-		case ACTION_OPERAND_1:
-			operand1 *= 10
-			operand1 += int(char - '0')
-		case ACTION_OPERAND_2:
-			operand2 *= 10
-			operand2 += int(char - '0')
-		case ACTION_COMPLETED:
-			sum += (operand1 * operand2)
-			fallthrough
-		case ACTION_RESET:
-			operand1 = 0
-			operand2 = 0
-		}
-	}
+func newD3Stream() *d3Stream {
+	return &d3Stream{scan: d3Scanner.newScanState(), enabled: true}
+}
 
-	return strconv.Itoa(sum), nil
+// Scan the next chunk. Never fails - it's all just memory, however
+// corrupted.
+func (memory *d3Stream) Write(chunk []byte) (int, error) {
+	scanChunk(d3Scanner, memory.scan, chunk, memory.execute)
+	memory.bytes += int64(len(chunk))
+	return len(chunk), nil
 }
 
-func Day3Part2(logger *slog.Logger, input string, part1Context any) string {
-	var operand1, operand2, sum int
-	state := STATE_INITIAL
-	disabled := false
-	var action Action
+func (memory *d3Stream) WriteString(chunk string) (int, error) {
+	scanChunk(d3Scanner, memory.scan, chunk, memory.execute)
+	memory.bytes += int64(len(chunk))
+	return len(chunk), nil
+}
 
-	// A bit lazy, I know - we ideally ought to commonalise
-	// the implementation. As it is, here's a copy-paste of
-	// part 1 that adds disable/enable handling.
-	for _, char := range input {
-		action, state = handleCharacter(char, state, disabled)
-		switch action {
-		case ACTION_OPERAND_1:
-			operand1 *= 10
-			operand1 += int(char - '0')
-		case ACTION_OPERAND_2:
-			operand2 *= 10
-			operand2 += int(char - '0')
-		case ACTION_COMPLETED:
-			sum += (operand1 * operand2)
-			fallthrough
-		case ACTION_RESET:
-			operand1 = 0
-			operand2 = 0
-		case ACTION_DISABLE:
-			disabled = true
-		case ACTION_ENABLE:
-			disabled = false
+func (memory *d3Stream) execute(instruction int, operands []int) {
+	switch instruction {
+	case D3_MUL:
+		product := operands[0] * operands[1]
+		memory.total += product
+		memory.muls++
+		if memory.enabled {
+			memory.enabledTotal += product
+			memory.enabledMuls++
 		}
+	case D3_DO:
+		memory.enabled = true
+	case D3_DONT:
+		memory.enabled = false
+		memory.disables++
 	}
-
-	return strconv.Itoa(sum)
 }
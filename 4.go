@@ -1,39 +1,20 @@
 package main
 
 import (
+	"context"
 	"log/slog"
 	"strconv"
 	"strings"
-
-	runner "github.com/ThePants999/advent-of-code-go-runner"
 )
 
-var Day4 = runner.DayImplementation{
+var Day4 = newPuzzle(dayDefinition[Grid[rune]]{
 	DayNumber:          4,
 	ExecutePart1:       Day4Part1,
 	ExecutePart2:       Day4Part2,
 	ExampleInput:       "MMMSXXMASM\nMSAMXMSMSA\nAMXSXMAAMM\nMSAMASMSMX\nXMASAMXAMM\nXXAMMXXAMA\nSMSMSASXSS\nSAXAMASAAA\nMAMMMXMMMM\nMXMXAXMASX",
 	ExamplePart1Answer: "18",
 	ExamplePart2Answer: "9",
-}
-
-type coords struct {
-	row int
-	col int
-}
-
-type Direction int
-
-const (
-	UP Direction = iota
-	UP_RIGHT
-	RIGHT
-	DOWN_RIGHT
-	DOWN
-	DOWN_LEFT
-	LEFT
-	UP_LEFT
-)
+})
 
 var nextLetter = map[rune]rune{
 	'X': 'M',
@@ -41,75 +22,51 @@ var nextLetter = map[rune]rune{
 	'A': 'S',
 }
 
-func Day4Part1(logger *slog.Logger, input string) (string, any) {
+func Day4Part1(ctx context.Context, logger *slog.Logger, input string) (string, Grid[rune], error) {
 	// Parse the input, building up both the
 	// full grid and also a list of Xs.
-	inputRows := strings.Fields(input)
-	grid := make([][]rune, len(inputRows))
-	xs := make([]coords, 0, len(input))
-	for rowIx, inputRow := range inputRows {
-		grid[rowIx] = make([]rune, len(inputRow))
-		for colIx, inputRune := range inputRow {
-			grid[rowIx][colIx] = inputRune
-			if inputRune == 'X' {
-				xs = append(xs, coords{rowIx, colIx})
-			}
+	grid, err := parseGrid(strings.Fields(input), anyRune)
+	if err != nil {
+		return "", Grid[rune]{}, err
+	}
+	xs := make([]gridPos, 0, len(input))
+	for pos, char := range grid.All() {
+		if char == 'X' {
+			xs = append(xs, pos)
 		}
 	}
+	logger.Debug("Parsed input", slog.Int("rows", grid.NumRows()), slog.Int("cols", grid.NumCols()), slog.Int("xs", len(xs)))
 
 	// Go through each X, and for each one, go through
 	// each of the 8 directions, looking to see if there's
 	// an M, then an A, then an S in that direction.
 	sum := 0
 	for _, x := range xs {
-		for dir := UP; dir <= UP_LEFT; dir++ {
-			if testDirection(grid, x.row, x.col, dir) {
+		for _, dir := range ALL_DIRECTIONS {
+			if testDirection(grid, x, dir) {
 				sum++
 			}
 		}
 	}
-	return strconv.Itoa(sum), grid
+	return strconv.Itoa(sum), grid, nil
 }
 
-func testDirection(grid [][]rune, row int, col int, dir Direction) bool {
+func testDirection(grid Grid[rune], pos gridPos, dir Direction) bool {
 	// Determine what letter we're expecting next, based
 	// on what letter we've got here.
-	expectedNextLetter := nextLetter[grid[row][col]]
+	expectedNextLetter := nextLetter[grid.At(pos)]
 	if expectedNextLetter == 0 {
 		return true
 	}
 
 	// Now determine where "next" is - the coordinates of the
 	// next square along in the specified direction.
-	newRow := row
-	if dir == UP || dir == UP_LEFT || dir == UP_RIGHT {
-		newRow -= 1
-		if newRow < 0 {
-			return false
-		}
-	} else if dir == DOWN || dir == DOWN_LEFT || dir == DOWN_RIGHT {
-		newRow += 1
-		if newRow == len(grid) {
-			return false
-		}
-	}
-	newCol := col
-	if dir == LEFT || dir == UP_LEFT || dir == DOWN_LEFT {
-		newCol -= 1
-		if newCol < 0 {
-			return false
-		}
-	} else if dir == RIGHT || dir == UP_RIGHT || dir == DOWN_RIGHT {
-		newCol += 1
-		if newCol == len(grid[newRow]) {
-			return false
-		}
-	}
+	next := pos.move(dir)
 
-	if expectedNextLetter == grid[newRow][newCol] {
+	if letter, inBounds := grid.Lookup(next); inBounds && letter == expectedNextLetter {
 		// This letter was correct - recurse to check
 		// the next one.
-		return testDirection(grid, newRow, newCol, dir)
+		return testDirection(grid, next, dir)
 	} else {
 		return false
 	}
@@ -117,27 +74,29 @@ func testDirection(grid [][]rune, row int, col int, dir Direction) bool {
 
 // Determine whether a co-ordinate already determined to contain
 // an A is the center of two diagonal MASes.
-func testMAS(grid [][]rune, row int, col int) bool {
-	if ((grid[row-1][col-1] == 'M' && grid[row+1][col+1] == 'S') || (grid[row-1][col-1] == 'S' && grid[row+1][col+1] == 'M')) &&
-		((grid[row+1][col-1] == 'M' && grid[row-1][col+1] == 'S') || (grid[row+1][col-1] == 'S' && grid[row-1][col+1] == 'M')) {
+func testMAS(grid Grid[rune], pos gridPos) bool {
+	upLeft, downRight := grid.At(gridPos{pos.row - 1, pos.col - 1}), grid.At(gridPos{pos.row + 1, pos.col + 1})
+	downLeft, upRight := grid.At(gridPos{pos.row + 1, pos.col - 1}), grid.At(gridPos{pos.row - 1, pos.col + 1})
+	if ((upLeft == 'M' && downRight == 'S') || (upLeft == 'S' && downRight == 'M')) &&
+		((downLeft == 'M' && upRight == 'S') || (downLeft == 'S' && upRight == 'M')) {
 		return true
 	}
 	return false
 }
 
-func Day4Part2(logger *slog.Logger, input string, part1Context any) string {
+func Day4Part2(ctx context.Context, logger *slog.Logger, input string, grid Grid[rune]) (string, error) {
 	// You'd think that we'd have built a list of As during
 	// initial grid construction. Nope, didn't bother, we'll
 	// go through the whole grid looking for them. It runs in
 	// less than 100 microseconds anyway.
-	grid := part1Context.([][]rune)
 	sum := 0
-	for rowIx := 1; rowIx < len(grid)-1; rowIx++ {
-		for colIx := 1; colIx < len(grid[rowIx])-1; colIx++ {
-			if grid[rowIx][colIx] == 'A' && testMAS(grid, rowIx, colIx) {
+	for rowIx := 1; rowIx < grid.NumRows()-1; rowIx++ {
+		for colIx := 1; colIx < grid.NumCols()-1; colIx++ {
+			pos := gridPos{rowIx, colIx}
+			if grid.At(pos) == 'A' && testMAS(grid, pos) {
 				sum++
 			}
 		}
 	}
-	return strconv.Itoa(sum)
+	return strconv.Itoa(sum), nil
 }
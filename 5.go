@@ -1,14 +1,13 @@
 package main
 
 import (
+	"context"
 	"log/slog"
 	"strconv"
 	"strings"
-
-	runner "github.com/ThePants999/advent-of-code-go-runner"
 )
 
-var Day5 = runner.DayImplementation{
+var Day5 = newPuzzle(dayDefinition[p1Context]{
 	DayNumber:    5,
 	ExecutePart1: Day5Part1,
 	ExecutePart2: Day5Part2,
@@ -42,7 +41,7 @@ var Day5 = runner.DayImplementation{
 97,13,75,29,47`,
 	ExamplePart1Answer: "143",
 	ExamplePart2Answer: "123",
-}
+})
 
 const NUM_PAGES int = 100
 
@@ -72,7 +71,7 @@ func addPrereq(pages [][]int, page int, prereqOf int) {
 	}
 }
 
-func Day5Part1(logger *slog.Logger, input string) (string, any) {
+func Day5Part1(ctx context.Context, logger *slog.Logger, input string) (string, p1Context, error) {
 	// Parse the first half of the input. What we're going
 	// to construct here is a "map" of prerequisites,
 	// so if we see "47|53" , we add 53 to the set of pages
@@ -80,17 +79,24 @@ func Day5Part1(logger *slog.Logger, input string) (string, any) {
 	// are two digits, we use an array rather than a map for
 	// speed.)
 	prereqs := make([][]int, NUM_PAGES)
-	lines := strings.Fields(input)
+	lines := strings.Split(input, "\n")
 	ix := 0
-	var line string
-	for ix, line = range lines {
-		if line[2] != '|' {
-			break
+	for ; ix < len(lines) && len(lines[ix]) > 0; ix++ {
+		first, second, found := strings.Cut(lines[ix], "|")
+		if !found {
+			return "", p1Context{}, errorAt(ix+1, 0, "expected an ordering rule like 47|53, found %q", lines[ix])
+		}
+		firstPage, err := parsePageNumber(first, ix+1, 1)
+		if err != nil {
+			return "", p1Context{}, err
+		}
+		secondPage, err := parsePageNumber(second, ix+1, len(first)+2)
+		if err != nil {
+			return "", p1Context{}, err
 		}
-		firstPage, _ := strconv.Atoi(line[0:2])
-		secondPage, _ := strconv.Atoi(line[3:])
 		addPrereq(prereqs, firstPage, secondPage)
 	}
+	logger.Debug("Parsed ordering rules", slog.Int("rules", ix))
 
 	// We now go through the second part of the input, both
 	// parsing and solving simultaneously.
@@ -103,12 +109,21 @@ func Day5Part1(logger *slog.Logger, input string) (string, any) {
 	sum := 0
 	incorrectUpdates := make([][]int, 0, len(lines))
 	for ; ix < len(lines); ix++ {
+		if len(lines[ix]) == 0 {
+			continue
+		}
 		pagesInThisUpdateStr := strings.Split(lines[ix], ",")
 		numPages := len(pagesInThisUpdateStr)
 		pagesInThisUpdate := make([]int, numPages)
 		// Simultaneously convert to ints and reverse
+		column := 1
 		for i := range numPages {
-			pagesInThisUpdate[numPages-i-1], _ = strconv.Atoi(pagesInThisUpdateStr[i])
+			page, err := parsePageNumber(pagesInThisUpdateStr[i], ix+1, column)
+			if err != nil {
+				return "", p1Context{}, err
+			}
+			pagesInThisUpdate[numPages-i-1] = page
+			column += len(pagesInThisUpdateStr[i]) + 1
 		}
 
 		if checkUpdate(prereqs, pagesInThisUpdate) == -1 {
@@ -120,7 +135,21 @@ func Day5Part1(logger *slog.Logger, input string) (string, any) {
 			incorrectUpdates = append(incorrectUpdates, pagesInThisUpdate)
 		}
 	}
-	return strconv.Itoa(sum), p1Context{prereqs, incorrectUpdates}
+	logger.Debug("Checked updates", slog.Int("incorrectUpdates", len(incorrectUpdates)))
+	return strconv.Itoa(sum), p1Context{prereqs, incorrectUpdates}, nil
+}
+
+// Page numbers double as indexes into our prerequisite arrays, so
+// they need to be in range.
+func parsePageNumber(s string, line int, column int) (int, error) {
+	page, err := atoiAt(s, line, column)
+	if err != nil {
+		return 0, err
+	}
+	if page < 0 || page >= NUM_PAGES {
+		return 0, errorAt(line, column, "page numbers must be between 0 and %d, found %d", NUM_PAGES-1, page)
+	}
+	return page, nil
 }
 
 // Returns -1 for a valid update, else index of the last illegally-placed page.
@@ -141,14 +170,13 @@ func checkUpdate(prereqs [][]int, pagesInThisUpdate []int) int {
 	return invalidIndex
 }
 
-func Day5Part2(logger *slog.Logger, input string, part1Context any) string {
-	context := part1Context.(p1Context)
-	prereqs := context.prereqs
+func Day5Part2(ctx context.Context, logger *slog.Logger, input string, part1Context p1Context) (string, error) {
+	prereqs := part1Context.prereqs
 
 	// Go through each update that was identified as illegal in part 1.
 	sum := 0
-	for ix := 0; ix < len(context.incorrectUpdates); ix++ {
-		pagesInThisUpdate := context.incorrectUpdates[ix]
+	for ix := 0; ix < len(part1Context.incorrectUpdates); ix++ {
+		pagesInThisUpdate := part1Context.incorrectUpdates[ix]
 		// The same function that told us this was illegal can also tell
 		// us where the problematic element is. The problem statement
 		// allows us to assume that every update can be made legal, so
@@ -163,5 +191,5 @@ func Day5Part2(logger *slog.Logger, input string, part1Context any) string {
 			ix--
 		}
 	}
-	return strconv.Itoa(sum)
+	return strconv.Itoa(sum), nil
 }
@@ -1,17 +1,16 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log/slog"
 	"maps"
-	"runtime"
 	"slices"
 	"strconv"
 	"strings"
-
-	runner "github.com/ThePants999/advent-of-code-go-runner"
 )
 
-var Day6 = runner.DayImplementation{
+var Day6 = newPuzzle(dayDefinition[d6context]{
 	DayNumber:    6,
 	ExecutePart1: Day6Part1,
 	ExecutePart2: Day6Part2,
@@ -27,25 +26,11 @@ var Day6 = runner.DayImplementation{
 ......#...`,
 	ExamplePart1Answer: "41",
 	ExamplePart2Answer: "6",
-}
-
-type direction6 int
-
-const (
-	D6_UP direction6 = iota
-	D6_RIGHT
-	D6_DOWN
-	D6_LEFT
-)
-
-type gridPos struct {
-	row int
-	col int
-}
+})
 
 type obstacleHitState struct {
 	pos gridPos
-	dir direction6
+	dir Direction
 }
 
 type d6context struct {
@@ -56,77 +41,98 @@ type d6context struct {
 	startCol           int
 }
 
-func Day6Part1(logger *slog.Logger, input string) (string, any) {
-	lines := strings.Fields(input)
-	visited := make([][]bool, len(lines))
-	obstacles := make(map[gridPos]nothing)
-	obstaclesByRow := make([][]int, len(lines))
-	obstaclesByCol := make([][]int, len(lines[0]))
-	for ix := range lines[0] {
-		obstaclesByCol[ix] = make([]int, 0, 20)
+func Day6Part1(ctx context.Context, logger *slog.Logger, input string) (string, d6context, error) {
+	obstacles, markers, err := parseGridWithMarkers(strings.Fields(input), "^", func(gridItem rune) (bool, bool) {
+		return gridItem == '#', gridItem == '#' || gridItem == '.' || gridItem == '^'
+	})
+	if err != nil {
+		return "", d6context{}, err
 	}
+	start := markers[0]
+	numRows, numCols := obstacles.NumRows(), obstacles.NumCols()
+	logger.Debug("Parsed input", slog.Int("rows", numRows), slog.Int("cols", numCols), slog.Any("start", start))
 
-	// Parse the input. We're looking to build up the following.
-	// - startRow and startCol tell us where the guard starts.
-	// - grid will be used to track the guard's movements, and
-	//   records where the obstacles are in a way that's useful
-	//   for part 1.
+	// As well as the grid of obstacles, which we'll use to
+	// simulate the guard's movements for part 1, we build up:
 	// - obstaclesByRow records, for each row, the column indexes
 	//   that contain obstacles. Additionally recording obstacles
 	//   this way helps for part 2, where we don't need to
 	//   simulate the guard moving square by square but rather can
 	//   "teleport" him to the next obstacle in a given line.
 	// - obstaclesByCol is similar.
-	startRow, startCol := -1, -1
-	dir := D6_UP
-	for row, line := range lines {
-		obstaclesByRow[row] = make([]int, 0, 20)
-		visited[row] = make([]bool, len(line))
-		for col, gridItem := range line {
-			if gridItem == '#' {
-				obstacles[gridPos{row, col}] = nothing{}
-				obstaclesByRow[row] = append(obstaclesByRow[row], col)
-				obstaclesByCol[col] = append(obstaclesByCol[col], row)
-			} else if gridItem == '^' {
-				startRow, startCol = row, col
-				visited[row][col] = true
-			}
+	obstaclesByRow := make([][]int, numRows)
+	for ix := range numRows {
+		obstaclesByRow[ix] = make([]int, 0, 20)
+	}
+	obstaclesByCol := make([][]int, numCols)
+	for ix := range numCols {
+		obstaclesByCol[ix] = make([]int, 0, 20)
+	}
+	for pos, obstacle := range obstacles.All() {
+		if obstacle {
+			obstaclesByRow[pos.row] = append(obstaclesByRow[pos.row], pos.col)
+			obstaclesByCol[pos.col] = append(obstaclesByCol[pos.col], pos.row)
 		}
 	}
 
 	// Simulate the guard moving around the grid.
-	numRows, numCols := len(visited), len(visited[0])
-	curRow, curCol := startRow, startCol
+	vis := visualiserFrom(ctx)
+	visited := newGrid[bool](numRows, numCols)
+	visited.Set(start, true)
+	cur, dir := start, UP
 	obstacleCandidates := make(map[gridPos]nothing)
 	visitedCount := 1
-	for {
+	// The puzzle promises the guard leaves the grid, but if we're
+	// given a map where he doesn't, we'd be here forever. He can't
+	// make more than one move (or turn) per square per direction
+	// without repeating himself.
+	maxMoves := numRows * numCols * 4 * 2
+	for moves := 0; ; moves++ {
+		if moves == maxMoves {
+			return "", d6context{}, errorAt(start.row+1, start.col+1, "the guard never leaves the grid")
+		}
 		var inBounds bool
-		curRow, curCol, dir, inBounds = move(obstacles, curRow, curCol, numRows, numCols, dir)
+		cur, dir, inBounds = move(obstacles, cur, dir)
 		if !inBounds {
 			// The guard has left the grid - we're done.
 			break
 		}
-		if !visited[curRow][curCol] {
+		if !visited.At(cur) {
 			// This is the first time the guard has entered this space.
 			// Increase our count, which is our part 1 answer, and also
 			// record this space, as every space the guard visits is
 			// somewhere we'll need to consider generating a new obstacle
 			// in part 2.
-			obstacleCandidates[gridPos{curRow, curCol}] = nothing{}
+			obstacleCandidates[cur] = nothing{}
 			visitedCount++
-			visited[curRow][curCol] = true
+			visited.Set(cur, true)
+		}
+		if vis != nil {
+			guard, facing, visitedSoFar := cur, dir, visitedCount
+			vis.show(func() (string, string) {
+				return fmt.Sprintf("The guard has visited %d squares", visitedSoFar), renderPicture(numRows, numCols, func(pos gridPos) rune {
+					switch {
+					case pos == guard:
+						return facing.Arrow()
+					case obstacles.At(pos):
+						return '#'
+					case visited.At(pos):
+						return 'X'
+					}
+					return '.'
+				})
+			})
 		}
 	}
 
 	// Make sure we don't try to spawn an obstacle on top of the guard.
-	delete(obstacleCandidates, gridPos{startRow, startCol})
+	delete(obstacleCandidates, start)
+	logger.Debug("Found obstacle candidates for part 2", slog.Int("candidates", len(obstacleCandidates)))
 
-	return strconv.Itoa(visitedCount), d6context{obstaclesByRow, obstaclesByCol, slices.Collect(maps.Keys(obstacleCandidates)), startRow, startCol}
+	return strconv.Itoa(visitedCount), d6context{obstaclesByRow, obstaclesByCol, slices.Collect(maps.Keys(obstacleCandidates)), start.row, start.col}, nil
 }
 
-func Day6Part2(logger *slog.Logger, input string, part1Context any) string {
-	context := part1Context.(d6context)
-
+func Day6Part2(ctx context.Context, logger *slog.Logger, input string, part1Context d6context) (string, error) {
 	// The basic idea of how we tackle part 2 is that we're going to
 	// try spawning an obstacle at every location the guard visited
 	// in part 1.
@@ -137,58 +143,52 @@ func Day6Part2(logger *slog.Logger, input string, part1Context any) string {
 	// map (set, really) of obstacles that the guard has hit - but
 	// with ~2500 candidate obstacle locations in my input, we were
 	// spending half our runtime just on the memory allocations within
-	// those goroutines. So what we do now is to spawn only as many
-	// goroutines as we have CPU cores, and have each one process a
-	// number of obstacle candidates in series, reusing the same map
+	// those goroutines. So what we do now is to split the candidates
+	// into only as many chunks as the worker pool has goroutines, and
+	// have each one process its chunk in series, reusing the same map
 	// for each candidate, which vastly brings down the allocations.
-	threads := runtime.NumCPU()
-	obstaclesPerThread := len(context.obstacleCandidates) / threads
-	c := make(chan int)
-
-	for ix := range threads {
-		go tryFindLoop(context, ix*obstaclesPerThread, obstaclesPerThread, c)
-	}
-	remainder := len(context.obstacleCandidates) % threads
-	if remainder > 0 {
-		go tryFindLoop(context, threads*obstaclesPerThread, remainder, c)
-	}
-
-	// Every candidate tried will send either a 0 or a 1 on the channel
-	// depending on whether that candidate caused a loop.
-	loopCount := 0
-	for range len(context.obstacleCandidates) {
-		loopCount += <-c
+	loopCount, err := parallelSumChunks(ctx, len(part1Context.obstacleCandidates), func(first int, last int) int {
+		return tryFindLoops(ctx, part1Context, part1Context.obstacleCandidates[first:last])
+	})
+	if err != nil {
+		return "", err
 	}
 
-	return strconv.Itoa(loopCount)
+	return strconv.Itoa(loopCount), nil
 }
 
-func tryFindLoop(context d6context, firstObstacleIx int, numObstacles int, c chan int) {
+// Count how many of the given obstacle candidates cause a loop.
+// Gives up early if ctx is cancelled, in which case the caller
+// will be throwing the answer away.
+func tryFindLoops(ctx context.Context, part1Context d6context, obstacleCandidates []gridPos) int {
 	obstaclesHit := make(map[obstacleHitState]nothing, 150)
+	loops := 0
+	checker := newCancelChecker(ctx)
 
 	// As explained above, this function will process a number of obstacle
 	// candidates in series.
-	for ix := range numObstacles {
-		newObstacle := context.obstacleCandidates[firstObstacleIx+ix]
-		curRow, curCol, dir := context.startRow, context.startCol, D6_UP
+	for _, newObstacle := range obstacleCandidates {
+		curRow, curCol, dir := part1Context.startRow, part1Context.startCol, UP
 		for {
+			if checker.check() != nil {
+				return loops
+			}
 			var inBounds, loopDetected bool
 			// Unlike in part 1, we don't need to move square by square. The
 			// guard will walk forwards until he either hits an obstacle or
 			// exits the grid, so we just figure out what's next in his path
 			// and teleport him straight there.
-			curRow, curCol, dir, inBounds, loopDetected = moveToNextObstacle(context.obstaclesByRow, context.obstaclesByCol, newObstacle.row, newObstacle.col, obstaclesHit, curRow, curCol, dir)
+			curRow, curCol, dir, inBounds, loopDetected = moveToNextObstacle(part1Context.obstaclesByRow, part1Context.obstaclesByCol, newObstacle.row, newObstacle.col, obstaclesHit, curRow, curCol, dir)
 			if !inBounds {
 				// The guard has left the grid before we detected a loop,
 				// so this obstacle candidate didn't cause a loop.
-				c <- 0
 				break
 			}
 			if loopDetected {
 				// The guard hit an obstacle that he's hit before, in the
 				// same direction, which means he's now looping. Report
 				// that, and we're done.
-				c <- 1
+				loops++
 				break
 			}
 		}
@@ -196,9 +196,11 @@ func tryFindLoop(context d6context, firstObstacleIx int, numObstacles int, c cha
 		// Clear out the record of hit obstacles ready for the next candidate.
 		clear(obstaclesHit)
 	}
+
+	return loops
 }
 
-func moveToNextObstacle(obstaclesByRow [][]int, obstaclesByCol [][]int, newObstacleRow int, newObstacleCol int, obstaclesHit map[obstacleHitState]nothing, curRow int, curCol int, curDir direction6) (newRow int, newCol int, newDir direction6, inBounds bool, loopDetected bool) {
+func moveToNextObstacle(obstaclesByRow [][]int, obstaclesByCol [][]int, newObstacleRow int, newObstacleCol int, obstaclesHit map[obstacleHitState]nothing, curRow int, curCol int, curDir Direction) (newRow int, newCol int, newDir Direction, inBounds bool, loopDetected bool) {
 	// As noted above, we don't need to move the guard square by
 	// square. We just want to figure out what's next in his path
 	// and teleport him straight there.
@@ -219,11 +221,11 @@ func moveToNextObstacle(obstaclesByRow [][]int, obstaclesByCol [][]int, newObsta
 	var rightwards bool
 	newObstacle := -1
 
-	if curDir == D6_UP || curDir == D6_DOWN {
+	if curDir == UP || curDir == DOWN {
 		obstacles = obstaclesByCol[curCol]
 		position = &newRow
 		obstaclePosition = &(obstacleHit.pos.row)
-		rightwards = (curDir == D6_DOWN)
+		rightwards = (curDir == DOWN)
 		if newObstacleCol == curCol {
 			newObstacle = newObstacleRow
 		}
@@ -231,7 +233,7 @@ func moveToNextObstacle(obstaclesByRow [][]int, obstaclesByCol [][]int, newObsta
 		obstacles = obstaclesByRow[curRow]
 		position = &newCol
 		obstaclePosition = &(obstacleHit.pos.col)
-		rightwards = (curDir == D6_RIGHT)
+		rightwards = (curDir == RIGHT)
 		if newObstacleRow == curRow {
 			newObstacle = newObstacleCol
 		}
@@ -313,49 +315,18 @@ func moveToNextObstacle(obstaclesByRow [][]int, obstaclesByCol [][]int, newObsta
 		}
 	}
 
-	newDir = turnRight(curDir)
+	newDir = curDir.TurnRight()
 
 	return
 }
 
-func turnRight(curDir direction6) direction6 {
-	newDir := curDir + 1
-	if newDir > D6_LEFT {
-		newDir = D6_UP
+func move(obstacles Grid[bool], cur gridPos, curDir Direction) (newPos gridPos, newDir Direction, inBounds bool) {
+	newPos = cur.move(curDir)
+	if !obstacles.InBounds(newPos) {
+		return newPos, curDir, false
 	}
-	return newDir
-}
-
-func move(obstacles map[gridPos]nothing, curRow int, curCol int, numRows int, numCols int, curDir direction6) (newRow int, newCol int, newDir direction6, inBounds bool) {
-	newDir = curDir
-	newRow, newCol, inBounds = moveSimple(curRow, curCol, curDir, numRows, numCols)
-
-	pos := gridPos{newRow, newCol}
-	_, found := obstacles[pos]
-	if found {
-		newRow, newCol = curRow, curCol
-		newDir++
-		if newDir > D6_LEFT {
-			newDir = D6_UP
-		}
+	if obstacles.At(newPos) {
+		return cur, curDir.TurnRight(), true
 	}
-	return
-}
-
-func moveSimple(curRow int, curCol int, dir direction6, numRows int, numCols int) (newRow int, newCol int, inBounds bool) {
-	newRow, newCol, inBounds = curRow, curCol, true
-	switch dir {
-	case D6_UP:
-		newRow--
-	case D6_DOWN:
-		newRow++
-	case D6_LEFT:
-		newCol--
-	case D6_RIGHT:
-		newCol++
-	}
-	if newRow < 0 || newRow == numRows || newCol < 0 || newCol == numCols {
-		inBounds = false
-	}
-	return
+	return newPos, curDir, true
 }
@@ -1,15 +1,14 @@
 package main
 
 import (
+	"context"
 	"log/slog"
 	"math"
 	"strconv"
 	"strings"
-
-	runner "github.com/ThePants999/advent-of-code-go-runner"
 )
 
-var Day7 = runner.DayImplementation{
+var Day7 = newPuzzle(dayDefinition[[]*equation]{
 	DayNumber:    7,
 	ExecutePart1: Day7Part1,
 	ExecutePart2: Day7Part2,
@@ -24,75 +23,87 @@ var Day7 = runner.DayImplementation{
 292: 11 6 16 20`,
 	ExamplePart1Answer: "3749",
 	ExamplePart2Answer: "11387",
-}
+})
 
 type equation struct {
 	result   int
 	operands []int
 }
 
-func Day7Part1(logger *slog.Logger, input string) (string, any) {
+func Day7Part1(ctx context.Context, logger *slog.Logger, input string) (string, []*equation, error) {
 	// Parse the input into a slice of equation structs,
 	// each recording both the desired result and the
 	// operands we've been given.
-	numbers := strings.Fields(input)
-	equations := make([]*equation, 0, 1000)
-	var currEq *equation
-	for _, number := range numbers {
-		if number[len(number)-1] == ':' {
-			result, err := strconv.Atoi(number[:len(number)-1])
-			if err != nil {
-				panic("Invalid input")
-			}
-			currEq = &equation{result, make([]int, 0, 20)}
-			equations = append(equations, currEq)
-		} else {
-			num, err := strconv.Atoi(number)
-			if err != nil {
-				panic("Invalid input")
+	lines := strings.Split(input, "\n")
+	equations := make([]*equation, 0, len(lines))
+	for lineIx, line := range lines {
+		if len(line) == 0 {
+			continue
+		}
+		resultStr, operandsStr, found := strings.Cut(line, ":")
+		if !found {
+			return "", nil, errorAt(lineIx+1, 0, "expected a colon after the test value")
+		}
+		result, err := atoiAt(resultStr, lineIx+1, 1)
+		if err != nil {
+			return "", nil, err
+		}
+		currEq := &equation{result, make([]int, 0, 20)}
+		column := len(resultStr) + 2
+		for _, number := range strings.Split(operandsStr, " ") {
+			if len(number) > 0 {
+				num, err := atoiAt(number, lineIx+1, column)
+				if err != nil {
+					return "", nil, err
+				}
+				currEq.operands = append(currEq.operands, num)
 			}
-			currEq.operands = append(currEq.operands, num)
+			column += len(number) + 1
 		}
+		if len(currEq.operands) == 0 {
+			return "", nil, errorAt(lineIx+1, 0, "equation has no operands")
+		}
+		equations = append(equations, currEq)
 	}
+	logger.Debug("Parsed input", slog.Int("equations", len(equations)))
 
-	sum := runTest(equations, false)
-	return strconv.Itoa(sum), equations
+	sum, err := runTest(ctx, equations, false)
+	if err != nil {
+		return "", nil, err
+	}
+	return strconv.Itoa(sum), equations, nil
 }
 
-func Day7Part2(logger *slog.Logger, input string, part1Context any) string {
-	equations := part1Context.([]*equation)
-	sum := runTest(equations, true)
-	return strconv.Itoa(sum)
+func Day7Part2(ctx context.Context, logger *slog.Logger, input string, equations []*equation) (string, error) {
+	sum, err := runTest(ctx, equations, true)
+	if err != nil {
+		return "", err
+	}
+	return strconv.Itoa(sum), nil
 }
 
-func runTest(equations []*equation, allowConcatenation bool) int {
+func runTest(ctx context.Context, equations []*equation, allowConcatenation bool) (int, error) {
 	// Each equation is completely independent, so farm them
-	// out to a separate goroutine each for parallel processing.
-	c := make(chan int)
-	for _, eq := range equations {
-		go func() {
-			if testEquation(eq, eq.operands[0], 1, allowConcatenation) {
-				c <- eq.result
-			} else {
-				c <- 0
-			}
-		}()
-	}
-
-	// Collate the results.
-	sum := 0
-	for i := 0; i < len(equations); i++ {
-		sum += <-c
-	}
-
-	return sum
+	// out to the worker pool for parallel processing.
+	return parallelSum(ctx, len(equations), func(ix int) int {
+		eq := equations[ix]
+		if testEquation(eq, eq.operands[0], 1, allowConcatenation, newCancelChecker(ctx)) {
+			return eq.result
+		}
+		return 0
+	})
 }
 
 // Recursive function attempting to solve _equation_.
 // Takes the cumulative value calculated so far, and
 // tries to figure out the operator before the _index_th
 // operand.
-func testEquation(eq *equation, value int, index int, allowConcatenation bool) bool {
+//
+// The number of possibilities doubles (or triples) with every
+// operand, so a long enough equation could keep us here forever -
+// if checker says we've been cancelled, we just report failure and
+// let the caller sort it out.
+func testEquation(eq *equation, value int, index int, allowConcatenation bool, checker *cancelChecker) bool {
 	if index == len(eq.operands) {
 		// We're done, so whether we're successful
 		// depends on whether the cumulative result
@@ -100,7 +111,7 @@ func testEquation(eq *equation, value int, index int, allowConcatenation bool) b
 		return eq.result == value
 	}
 
-	if value > eq.result {
+	if value > eq.result || checker.check() != nil {
 		// If we go past the desired result, bug out
 		// early.
 		return false
@@ -108,13 +119,13 @@ func testEquation(eq *equation, value int, index int, allowConcatenation bool) b
 
 	// Fork to try +...
 	newValue := value + eq.operands[index]
-	if testEquation(eq, newValue, index+1, allowConcatenation) {
+	if testEquation(eq, newValue, index+1, allowConcatenation, checker) {
 		return true
 	}
 
 	// ...and *.
 	newValue = value * eq.operands[index]
-	if testEquation(eq, newValue, index+1, allowConcatenation) {
+	if testEquation(eq, newValue, index+1, allowConcatenation, checker) {
 		return true
 	}
 
@@ -124,7 +135,7 @@ func testEquation(eq *equation, value int, index int, allowConcatenation bool) b
 		numDigits := math.Log10(float64(eq.operands[index])) + 1
 		newValue = value * int(math.Pow10(int(numDigits)))
 		newValue += eq.operands[index]
-		if testEquation(eq, newValue, index+1, allowConcatenation) {
+		if testEquation(eq, newValue, index+1, allowConcatenation, checker) {
 			return true
 		}
 	}
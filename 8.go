@@ -1,16 +1,13 @@
 package main
 
 import (
+	"context"
 	"log/slog"
 	"strconv"
 	"strings"
-
-	"github.com/mowshon/iterium"
-
-	runner "github.com/ThePants999/advent-of-code-go-runner"
 )
 
-var Day8 = runner.DayImplementation{
+var Day8 = newPuzzle(dayDefinition[day8context]{
 	DayNumber:    8,
 	ExecutePart1: Day8Part1,
 	ExecutePart2: Day8Part2,
@@ -28,49 +25,59 @@ var Day8 = runner.DayImplementation{
 ............`,
 	ExamplePart1Answer: "14",
 	ExamplePart2Answer: "34",
-}
+})
 
 type day8context struct {
-	combinations [][]gridPos
-	numRows      int
-	numCols      int
+	combinations [][2]gridPos
+	grid         Grid[rune]
 }
 
-func Day8Part1(logger *slog.Logger, input string) (string, any) {
+func Day8Part1(ctx context.Context, logger *slog.Logger, input string) (string, day8context, error) {
 	// Parse the input. We don't care about modelling
 	// the grid - we just want a record of where each
 	// antenna is for each frequency, which we build
 	// up as a map from frequency to slice of
 	// coordinates.
-	rows := strings.Fields(input)
+	grid, err := parseGrid(strings.Fields(input), anyRune)
+	if err != nil {
+		return "", day8context{}, err
+	}
 	antennae := make(map[rune][]gridPos)
-	for rowIx, row := range rows {
-		for colIx, frequency := range row {
-			if frequency != '.' {
-				list, found := antennae[frequency]
-				if !found {
-					list = make([]gridPos, 0, 10)
-				}
-				antennae[frequency] = append(list, gridPos{rowIx, colIx})
+	for pos, frequency := range grid.All() {
+		if frequency != '.' {
+			list, found := antennae[frequency]
+			if !found {
+				list = make([]gridPos, 0, 10)
 			}
+			antennae[frequency] = append(list, pos)
 		}
 	}
-
-	// Use a combinatorics library to construct a list,
-	// of every pair of antennae.  We only want pairs at
-	// the same frequency, but we're going to build them
-	// into a single list as we don't care WHAT
-	// frequency each antinode is for, only that there is
-	// one.
-	allCombinations := make([][]gridPos, 0, 1000)
+	logger.Debug("Parsed input", slog.Int("rows", grid.NumRows()), slog.Int("cols", grid.NumCols()), slog.Int("frequencies", len(antennae)))
+
+	// Construct a list of every pair of antennae.  We
+	// only want pairs at the same frequency, but we're
+	// going to build them into a single list as we
+	// don't care WHAT frequency each antinode is for,
+	// only that there is one. I used to get these from a
+	// combinatorics library, but that works out every
+	// last pair before handing over the first, and a
+	// frequency with thousands of antennae has millions
+	// of them - so we'd never get the chance to give up.
+	checker := newCancelChecker(ctx)
+	allCombinations := make([][2]gridPos, 0, 1000)
 	for _, coords := range antennae {
-		combinations := iterium.Combinations(coords, 2)
-		slice, _ := combinations.Slice()
-		allCombinations = append(allCombinations, slice...)
+		for ix, first := range coords {
+			for _, second := range coords[ix+1:] {
+				if err := checker.check(); err != nil {
+					return "", day8context{}, err
+				}
+				allCombinations = append(allCombinations, [2]gridPos{first, second})
+			}
+		}
 	}
+	logger.Debug("Paired up antennae", slog.Int("pairs", len(allCombinations)))
 
-	numRows, numCols := len(rows), len(rows[0])
-	context := day8context{allCombinations, numRows, numCols}
+	part1Context := day8context{allCombinations, grid}
 
 	// We want to calculate the number of unique
 	// coordinates with antinodes, regardless of
@@ -79,6 +86,9 @@ func Day8Part1(logger *slog.Logger, input string) (string, any) {
 	// a set of coordinates.
 	set := make(map[gridPos]nothing)
 	for _, combination := range allCombinations {
+		if err := checker.check(); err != nil {
+			return "", day8context{}, err
+		}
 		// Record both the A->B direction and the
 		// B->A direction for each pair.
 		locationA := gridPos{
@@ -87,49 +97,42 @@ func Day8Part1(logger *slog.Logger, input string) (string, any) {
 		locationB := gridPos{
 			combination[1].row + combination[1].row - combination[0].row,
 			combination[1].col + combination[1].col - combination[0].col}
-		if locationInGrid(locationA, numRows, numCols) {
+		if grid.InBounds(locationA) {
 			set[locationA] = nothing{}
 		}
-		if locationInGrid(locationB, numRows, numCols) {
+		if grid.InBounds(locationB) {
 			set[locationB] = nothing{}
 		}
 	}
 
-	return strconv.Itoa(len(set)), context
+	return strconv.Itoa(len(set)), part1Context, nil
 }
 
-// Determine whether a location is in-bounds for the
-// given grid size.
-func locationInGrid(loc gridPos, numRows int, numCols int) bool {
-	return loc.row >= 0 && loc.row < numRows && loc.col >= 0 && loc.col < numCols
-}
-
-func (pos gridPos) Add(other gridPos) gridPos {
-	return gridPos{pos.row + other.row, pos.col + other.col}
-}
-
-func (pos gridPos) Subtract(other gridPos) gridPos {
-	return gridPos{pos.row - other.row, pos.col - other.col}
-}
-
-func Day8Part2(logger *slog.Logger, input string, part1Context any) string {
-	context := part1Context.(day8context)
-
+func Day8Part2(ctx context.Context, logger *slog.Logger, input string, part1Context day8context) (string, error) {
 	// Very similar to part 1, except instead of going
 	// A->B plus one delta, we keep adding a delta at a
 	// time until we leave the grid (and repeat in the
-	// other direction).
+	// other direction). A close pair on a big grid can
+	// have a long way to go, so we check whether to give
+	// up at every step, not just every pair.
+	checker := newCancelChecker(ctx)
 	set := make(map[gridPos]nothing)
-	for _, combination := range context.combinations {
+	for _, combination := range part1Context.combinations {
 		start := combination[0]
 		delta := combination[1].Subtract(start)
-		for next := start; locationInGrid(next, context.numRows, context.numCols); next = next.Add(delta) {
+		for next := start; part1Context.grid.InBounds(next); next = next.Add(delta) {
+			if err := checker.check(); err != nil {
+				return "", err
+			}
 			set[next] = nothing{}
 		}
-		for next := start; locationInGrid(next, context.numRows, context.numCols); next = next.Subtract(delta) {
+		for next := start; part1Context.grid.InBounds(next); next = next.Subtract(delta) {
+			if err := checker.check(); err != nil {
+				return "", err
+			}
 			set[next] = nothing{}
 		}
 	}
 
-	return strconv.Itoa(len(set))
+	return strconv.Itoa(len(set)), nil
 }
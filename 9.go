@@ -1,26 +1,48 @@
 package main
 
 import (
+	"context"
 	"log/slog"
 	"slices"
 	"strconv"
-
-	runner "github.com/ThePants999/advent-of-code-go-runner"
 )
 
-var Day9 = runner.DayImplementation{
+var Day9 = newPuzzle(dayDefinition[nothing]{
 	DayNumber:          9,
 	ExecutePart1:       Day9Part1,
 	ExecutePart2:       Day9Part2,
 	ExampleInput:       "2333133121414131402",
 	ExamplePart1Answer: "1928",
 	ExamplePart2Answer: "2858",
-}
+})
 
 // Terrifically efficient part 1 implementation in both memory and processing.
 // Pity it's 100% useless for part 2.
 
-func Day9Part1(logger *slog.Logger, input string) (string, any) {
+// The disk map is a single line of digits. Check that's what
+// we've got before either part starts relying on it. Gaps can be
+// empty, but a file with no blocks doesn't make any sense.
+func checkDiskMap(input string) (string, error) {
+	if len(input) == 0 {
+		return "", errorAt(0, 0, "empty disk map")
+	}
+	for ix, char := range input {
+		if char < '0' || char > '9' {
+			return "", errorAt(1, ix+1, "expected a digit, found %q", char)
+		}
+		if char == '0' && ix%2 == 0 {
+			return "", errorAt(1, ix+1, "file %d has no blocks", ix/2)
+		}
+	}
+	return input, nil
+}
+
+func Day9Part1(ctx context.Context, logger *slog.Logger, input string) (string, nothing, error) {
+	input, err := checkDiskMap(input)
+	if err != nil {
+		return "", nothing{}, err
+	}
+
 	// Parse input. We're not going to model the
 	// hard drive - we just want to know the size
 	// of each file and each gap.
@@ -34,6 +56,7 @@ func Day9Part1(logger *slog.Logger, input string) (string, any) {
 			gaps = append(gaps, length)
 		}
 	}
+	logger.Debug("Parsed input", slog.Int("files", len(files)), slog.Int("gaps", len(gaps)))
 
 	// The approach we're going to take here is to calculate
 	// the checksum on the fly. We're going to alternate
@@ -46,8 +69,14 @@ func Day9Part1(logger *slog.Logger, input string) (string, any) {
 	// combination of file on the left and gap on the
 	// left.
 	for {
-		// Fully append the next file on the left.
-		for i := 0; i < files[left_file_ix]; i++ {
+		// Fully append the next file on the left - or, if we've
+		// already started moving it into the gap before it, what's
+		// left of it.
+		remaining := files[left_file_ix]
+		if left_file_ix == right_file_ix {
+			remaining -= right_file_subix
+		}
+		for i := 0; i < remaining; i++ {
 			checksum += (left_file_ix * ix)
 			ix++
 		}
@@ -70,8 +99,9 @@ func Day9Part1(logger *slog.Logger, input string) (string, any) {
 			break
 		}
 
-		// Fill the next gap with right-hand files.
-		for i := 0; i < gaps[gap_ix]; i++ {
+		// Fill the next gap with right-hand files, unless we run
+		// out of them first, which a big enough gap can do.
+		for i := 0; i < gaps[gap_ix] && right_file_ix >= left_file_ix; i++ {
 			checksum += (right_file_ix * ix)
 			ix++
 			right_file_subix++
@@ -81,9 +111,12 @@ func Day9Part1(logger *slog.Logger, input string) (string, any) {
 			}
 		}
 		gap_ix++
+		if left_file_ix > right_file_ix {
+			break
+		}
 	}
 
-	return strconv.Itoa(checksum), nil
+	return strconv.Itoa(checksum), nothing{}, nil
 }
 
 // Part 2 uses a completely different data model. This time,
@@ -159,7 +192,12 @@ type disk struct {
 	last  *diskElement
 }
 
-func Day9Part2(logger *slog.Logger, input string, part1Context any) string {
+func Day9Part2(ctx context.Context, logger *slog.Logger, input string, _ nothing) (string, error) {
+	input, err := checkDiskMap(input)
+	if err != nil {
+		return "", err
+	}
+
 	// We're going to maintain a record of the locations of
 	// all gaps of at least X blocks. In other words, gaps[6]
 	// contains every gap that is 6 blocks or wider, in the
@@ -245,7 +283,7 @@ func Day9Part2(logger *slog.Logger, input string, part1Context any) string {
 		}
 	}
 
-	return strconv.Itoa(checksum)
+	return strconv.Itoa(checksum), nil
 }
 
 func shrinkGap(gap *diskElement, gaps [][]*diskElement, newSize int) {
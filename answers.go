@@ -0,0 +1,198 @@
+package main
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+	"sync"
+)
+
+// Once a day's been solved, the right answers for a given input
+// never change - so once we've seen a right answer, we can write it
+// down and check every later run against it. That way, if an
+// "optimisation" quietly breaks a day, we find out the next time
+// it's run rather than the next time someone submits an answer.
+//
+// The answers live in a JSON file, keyed by day, part and a hash of
+// the input (so that different people's inputs can live side by
+// side, and nobody's input ends up in the file).
+
+const ANSWERS_FILENAME string = "answers.json"
+
+// One confirmed answer, as stored in the file.
+type knownAnswer struct {
+	Day       int    `json:"day"`
+	Part      int    `json:"part"`
+	InputHash string `json:"inputHash"`
+	Answer    string `json:"answer"`
+}
+
+type answerKey struct {
+	day       int
+	part      int
+	inputHash string
+}
+
+// What one part came up with on this run.
+type answerResult struct {
+	day    int
+	part   int
+	input  string
+	answer string
+	failed bool
+}
+
+type answerVerdict string
+
+const (
+	ANSWER_PASS    answerVerdict = "PASS"
+	ANSWER_FAIL    answerVerdict = "FAIL"
+	ANSWER_UNKNOWN answerVerdict = "UNKNOWN"
+)
+
+// The known answers, plus the answers from this run, which may be
+// added to from several goroutines at once.
+type answerBook struct {
+	path string
+
+	// Whether to write this run's answers to the file as confirmed.
+	save bool
+
+	// Whether the file existed when we started. If it didn't, and
+	// we're not saving, there's nothing to check against and we
+	// keep quiet about it.
+	existed bool
+
+	known   map[answerKey]string
+	mu      sync.Mutex
+	results []answerResult
+}
+
+// Load the known answers from path. A missing file is fine - it
+// just means we don't know any answers yet.
+func loadAnswerBook(path string, save bool) (*answerBook, error) {
+	book := &answerBook{path: path, save: save, known: make(map[answerKey]string)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return book, nil
+	} else if err != nil {
+		return nil, err
+	}
+	book.existed = true
+	var answers []knownAnswer
+	if err := json.Unmarshal(data, &answers); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for _, answer := range answers {
+		book.known[answerKey{answer.Day, answer.Part, answer.InputHash}] = answer.Answer
+	}
+	return book, nil
+}
+
+// Inputs are hashed after normalisation, so that the same input
+// with different line endings is still the same input.
+func hashInput(input string) string {
+	hash := sha256.Sum256([]byte(normaliseInput(input)))
+	return hex.EncodeToString(hash[:])
+}
+
+// Note what a part came up with. This gets called while the runner
+// has the clock running, so it does as little as possible - all the
+// hashing and comparing waits until report(). If the same part runs
+// more than once (e.g. with -s), only the last run counts.
+func (b *answerBook) add(day int, part int, input string, answer string, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	result := answerResult{day, part, input, answer, failed}
+	ix := slices.IndexFunc(b.results, func(r answerResult) bool {
+		return r.day == day && r.part == part
+	})
+	if ix >= 0 {
+		b.results[ix] = result
+	} else {
+		b.results = append(b.results, result)
+	}
+}
+
+// Compare one of this run's answers against the known one. A part
+// that errored or timed out fails if we know what it should have
+// got, and is merely unknown if we don't.
+func (b *answerBook) verdict(result answerResult, inputHash string) (answerVerdict, string) {
+	expected, found := b.known[answerKey{result.day, result.part, inputHash}]
+	switch {
+	case !found:
+		return ANSWER_UNKNOWN, ""
+	case result.failed || result.answer != expected:
+		return ANSWER_FAIL, expected
+	default:
+		return ANSWER_PASS, expected
+	}
+}
+
+// Print a verdict for every part that ran, and save this run's
+// answers if we've been asked to. Returns false if anything failed
+// its check or the answers couldn't be saved.
+func (b *answerBook) report(w io.Writer) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.results) == 0 || !(b.existed || b.save) {
+		return true
+	}
+	slices.SortFunc(b.results, func(x, y answerResult) int {
+		return cmp.Or(cmp.Compare(x.day, y.day), cmp.Compare(x.part, y.part))
+	})
+
+	ok := true
+	saved := 0
+	fmt.Fprintf(w, "Checking answers against %s:\n", b.path)
+	for _, result := range b.results {
+		inputHash := hashInput(result.input)
+		verdict, expected := b.verdict(result, inputHash)
+		switch verdict {
+		case ANSWER_FAIL:
+			ok = false
+			fmt.Fprintf(w, "Day %d part %d: %s (expected %s, got %s)\n", result.day, result.part, verdict, expected, result.answer)
+		default:
+			fmt.Fprintf(w, "Day %d part %d: %s\n", result.day, result.part, verdict)
+		}
+		// Saving means the user's confirmed this run's answers are
+		// right, so they replace any we had before.
+		if b.save && !result.failed && verdict != ANSWER_PASS {
+			b.known[answerKey{result.day, result.part, inputHash}] = result.answer
+			saved++
+		}
+	}
+
+	if b.save {
+		if err := b.write(); err != nil {
+			fmt.Fprintf(w, "Couldn't save answers: %v\n", err)
+			return false
+		}
+		fmt.Fprintf(w, "Saved %d new or changed answers to %s\n", saved, b.path)
+	}
+	return ok
+}
+
+// Write out all the known answers, in a stable order so that the
+// file diffs nicely if it's kept under version control.
+func (b *answerBook) write() error {
+	answers := make([]knownAnswer, 0, len(b.known))
+	for key, answer := range b.known {
+		answers = append(answers, knownAnswer{key.day, key.part, key.inputHash, answer})
+	}
+	slices.SortFunc(answers, func(x, y knownAnswer) int {
+		return cmp.Or(cmp.Compare(x.Day, y.Day), cmp.Compare(x.Part, y.Part), cmp.Compare(x.InputHash, y.InputHash))
+	})
+	data, err := json.MarshalIndent(answers, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(b.path, append(data, '\n'), 0o644)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"io"
+	"path/filepath"
+	"testing"
+)
+
+func TestAnswerBook(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "answers.json")
+
+	// First time round there's nothing to check against, so
+	// everything's unknown - and gets saved.
+	book, err := loadAnswerBook(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	book.add(2, 1, "1 2 3\n", "1", false)
+	book.add(2, 2, "1 2 3\n", "", true)
+	if !book.report(io.Discard) {
+		t.Fatal("first run reported a failure")
+	}
+
+	book, err = loadAnswerBook(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name   string
+		result answerResult
+		want   answerVerdict
+	}{
+		{"same answer", answerResult{2, 1, "1 2 3\n", "1", false}, ANSWER_PASS},
+		{"CRLF input", answerResult{2, 1, "1 2 3\r\n", "1", false}, ANSWER_PASS},
+		{"wrong answer", answerResult{2, 1, "1 2 3\n", "0", false}, ANSWER_FAIL},
+		{"part failed", answerResult{2, 1, "1 2 3\n", "ERROR: oops", true}, ANSWER_FAIL},
+		{"other input", answerResult{2, 1, "3 2 1\n", "1", false}, ANSWER_UNKNOWN},
+		{"failure not saved", answerResult{2, 2, "1 2 3\n", "1", false}, ANSWER_UNKNOWN},
+	} {
+		if got, _ := book.verdict(tc.result, hashInput(tc.result.input)); got != tc.want {
+			t.Errorf("%s: got %s, want %s", tc.name, got, tc.want)
+		}
+	}
+
+	book.add(2, 1, "1 2 3\n", "0", false)
+	if book.report(io.Discard) {
+		t.Error("wrong answer wasn't reported as a failure")
+	}
+}
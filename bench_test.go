@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+// Benchmarks for both parts of every day. These are driven from
+// the real puzzle inputs where we have them - the runner caches
+// those in inputs/ (which is git-ignored, as AoC inputs mustn't be
+// redistributed) - and otherwise fall back to the example input,
+// which at least catches gross regressions.
+//
+// Run with something like:
+//   go test -run '^$' -bench . -count 10 | go run ./cmd/benchtable
+// to reproduce the execution times table in the README.
+
+func BenchmarkDays(b *testing.B) {
+	logger := quietLogger()
+	for _, day := range allDays {
+		input, source := benchmarkInput(day)
+		if input == "" {
+			b.Logf("day %d: no cached input and no example input, skipping", day.DayNumber)
+			continue
+		}
+		b.Logf("day %d: using %s input", day.DayNumber, source)
+
+		b.Run(fmt.Sprintf("Day%02d/Part1", day.DayNumber), func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				if _, _, err := day.runPart1(context.Background(), logger, input); err != nil {
+					b.Fatalf("%v", err)
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("Day%02d/Part2", day.DayNumber), func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				// Several days modify part 1's context while running
+				// part 2, so every iteration needs a fresh one - but
+				// we don't want to count that time against part 2.
+				b.StopTimer()
+				_, part1Context, err := day.runPart1(context.Background(), logger, input)
+				if err != nil {
+					b.Fatalf("%v", err)
+				}
+				b.StartTimer()
+				if _, err := day.runPart2(context.Background(), logger, input, part1Context); err != nil {
+					b.Fatalf("%v", err)
+				}
+			}
+		})
+	}
+}
+
+// Find the input to benchmark a day against, returning it along
+// with a description of where it came from.
+func benchmarkInput(day puzzle) (string, string) {
+	input, err := inputDir(INPUT_DIRNAME).read(day.DayNumber)
+	if err == nil {
+		return input, "real"
+	}
+	return day.ExampleInput, "example"
+}
@@ -0,0 +1,94 @@
+// Turns the output of the day benchmarks back into the execution
+// times table from the README. Run the benchmarks several times
+// (the README figures are over a thousand runs) and pipe the result
+// in:
+//
+//	go test -run '^$' -bench . -count 1000 | go run ./cmd/benchtable
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// e.g. "BenchmarkDays/Day11/Part2-12    	     128	   8201234 ns/op	 ..."
+var benchLine = regexp.MustCompile(`^BenchmarkDays/Day(\d+)/Part([12])(?:-\d+)?\s+\d+\s+([\d.]+) ns/op`)
+
+type partSamples [2][]time.Duration
+
+func main() {
+	samples := make(map[int]*partSamples)
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		match := benchLine.FindStringSubmatch(scanner.Text())
+		if match == nil {
+			continue
+		}
+		day, _ := strconv.Atoi(match[1])
+		part, _ := strconv.Atoi(match[2])
+		nsPerOp, err := strconv.ParseFloat(match[3], 64)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Couldn't parse timing %q: %v\n", match[3], err)
+			os.Exit(1)
+		}
+		if samples[day] == nil {
+			samples[day] = &partSamples{}
+		}
+		samples[day][part-1] = append(samples[day][part-1], time.Duration(nsPerOp))
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed reading benchmark output: %v\n", err)
+		os.Exit(1)
+	}
+	if len(samples) == 0 {
+		fmt.Fprintln(os.Stderr, "No day benchmarks found in input")
+		os.Exit(1)
+	}
+
+	// Same approach as the runner's statistics: a day's median
+	// and mean are the sums of its two parts' medians and means.
+	days := make([]int, 0, len(samples))
+	for day := range samples {
+		days = append(days, day)
+	}
+	slices.Sort(days)
+	fmt.Println("| Day | Median | Mean |")
+	fmt.Println("| ------- | ------- | ------- |")
+	for _, day := range days {
+		var median, mean time.Duration
+		for _, part := range samples[day] {
+			median += medianOf(part)
+			mean += meanOf(part)
+		}
+		fmt.Printf("| %d | %s | %s |\n", day, median.Round(time.Microsecond), mean.Round(time.Microsecond))
+	}
+}
+
+func medianOf(durations []time.Duration) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	sorted := slices.Clone(durations)
+	slices.Sort(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+func meanOf(durations []time.Duration) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	var total time.Duration
+	for _, d := range durations {
+		total += d
+	}
+	return total / time.Duration(len(durations))
+}
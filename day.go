@@ -0,0 +1,325 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"runtime/debug"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	runner "github.com/ThePants999/advent-of-code-go-runner"
+)
+
+// How each day defines its solution. C is whatever part 1 hands on
+// to part 2 - usually the parsed input, plus anything part 1 worked
+// out that part 2 can reuse. Days with nothing to hand on use
+// nothing{}. Having C in the type means that part 2 expecting
+// something other than what part 1 provides is a compile error.
+type dayDefinition[C any] struct {
+	DayNumber          int
+	ExecutePart1       func(context.Context, *slog.Logger, string) (string, C, error)
+	ExecutePart2       func(context.Context, *slog.Logger, string, C) (string, error)
+	ExampleInput       string
+	ExamplePart1Answer string
+	ExamplePart2Answer string
+
+	// Any numbers the day needs that don't come from the input.
+	// See dayParameter.
+	Parameters []*dayParameter
+}
+
+// Turn a day's definition into a puzzle, which has the same shape
+// for every day regardless of C, so they can all go in one list.
+func newPuzzle[C any](def dayDefinition[C]) puzzle {
+	return puzzle{
+		DayNumber: def.DayNumber,
+		ExecutePart1: func(ctx context.Context, logger *slog.Logger, input string) (string, any, error) {
+			return def.ExecutePart1(ctx, logger, input)
+		},
+		ExecutePart2: func(ctx context.Context, logger *slog.Logger, input string, part1Context any) (string, error) {
+			typedContext, ok := part1Context.(C)
+			if !ok {
+				// Part 1 hasn't been run, so we're on our own.
+				// All part 1's work is in service of producing
+				// the context, so we have no choice but to do
+				// it all again.
+				logger.Debug("Running part 1 to get context for part 2")
+				var err error
+				if _, typedContext, err = def.ExecutePart1(ctx, logger, input); err != nil {
+					return "", err
+				}
+			}
+			return def.ExecutePart2(ctx, logger, input, typedContext)
+		},
+		ExampleInput:       def.ExampleInput,
+		ExamplePart1Answer: def.ExamplePart1Answer,
+		ExamplePart2Answer: def.ExamplePart2Answer,
+		Parameters:         def.Parameters,
+	}
+}
+
+// A single day's solution, in the form we hand to the runner. This
+// mirrors runner.DayImplementation, except that both parts report
+// bad input by returning an error rather than panicking, and can be
+// cancelled via their context.Context. implementation() adapts it
+// for the runner.
+type puzzle struct {
+	DayNumber          int
+	ExecutePart1       func(context.Context, *slog.Logger, string) (string, any, error)
+	ExecutePart2       func(context.Context, *slog.Logger, string, any) (string, error)
+	ExampleInput       string
+	ExamplePart1Answer string
+	ExamplePart2Answer string
+	Parameters         []*dayParameter
+}
+
+// Run part 1, converting any error (or, as a last line of defence,
+// any panic) into a *DayError identifying this day and part. If ctx
+// is cancelled, the error wraps ctx's error.
+func (p puzzle) runPart1(ctx context.Context, logger *slog.Logger, input string) (result string, part1Context any, err error) {
+	defer p.recoverInto(1, logger, &err)
+	input = normaliseInput(input)
+	result, part1Context, err = p.ExecutePart1(p.withParameters(ctx, input), logger, input)
+	if err != nil {
+		return "", nil, wrapDayError(p.DayNumber, 1, err)
+	}
+	return result, part1Context, nil
+}
+
+// Run part 2 given the context from a successful part 1.
+func (p puzzle) runPart2(ctx context.Context, logger *slog.Logger, input string, part1Context any) (result string, err error) {
+	defer p.recoverInto(2, logger, &err)
+	input = normaliseInput(input)
+	result, err = p.ExecutePart2(p.withParameters(ctx, input), logger, input, part1Context)
+	if err != nil {
+		return "", wrapDayError(p.DayNumber, 2, err)
+	}
+	return result, nil
+}
+
+func (p puzzle) recoverInto(part int, logger *slog.Logger, err *error) {
+	if r := recover(); r != nil {
+		logger.Debug("Recovered from panic", slog.Int("part", part), slog.String("stack", string(debug.Stack())))
+		*err = &DayError{Day: p.DayNumber, Part: part, Err: fmt.Errorf("panic: %v", r)}
+	}
+}
+
+// The runner has no concept of a part failing, so a failure is
+// logged and reported in place of the answer. If part 1 fails, part
+// 2 has no context to work from, so it's skipped.
+//
+// The runner also has no concept of a time limit, so we impose one
+// ourselves: if timeLimit is non-zero, part 1 starts the clock and
+// part 2 gets whatever's left. Any days that run out of time are
+// noted in timeouts, so that they can be reported at the end.
+//
+// Answers for real inputs (i.e. anything other than the example) are
+// noted in answers, if it isn't nil, to be checked against known
+// answers at the end.
+//
+// The runner passes its own logger, so settings.logger isn't used.
+func (p puzzle) implementation(settings runSettings) runner.DayImplementation {
+	timeLimit, timeouts, answers := settings.timeLimit, settings.timeouts, settings.answers
+	// Known answers are for the puzzle as set, so if any of the
+	// day's parameters have been changed, they don't apply.
+	for _, param := range p.Parameters {
+		if _, found := settings.parameters[param]; found {
+			answers = nil
+		}
+	}
+	return runner.DayImplementation{
+		DayNumber: p.DayNumber,
+		ExecutePart1: func(logger *slog.Logger, input string) (string, any) {
+			var deadline time.Time
+			if timeLimit > 0 {
+				deadline = time.Now().Add(timeLimit)
+			}
+			ctx, cancel := contextWithDeadline(deadline)
+			defer cancel()
+			ctx = withParameterOverrides(ctx, settings.parameters)
+			result, part1Context, err := p.runPart1(ctx, logger, input)
+			if err != nil {
+				result = p.reportFailure(1, logger, err, timeLimit, timeouts)
+				p.noteAnswer(answers, 1, input, result, true)
+				return result, adaptedContext{err: err}
+			}
+			p.noteAnswer(answers, 1, input, result, false)
+			return result, adaptedContext{part1Context: part1Context, deadline: deadline}
+		},
+		ExecutePart2: func(logger *slog.Logger, input string, part1Context any) string {
+			adapted, _ := part1Context.(adaptedContext)
+			if adapted.err != nil {
+				logger.Warn("Skipping part 2 as part 1 failed", slog.Any("error", adapted.err))
+				p.noteAnswer(answers, 2, input, "SKIPPED", true)
+				return "SKIPPED: part 1 failed"
+			}
+			ctx, cancel := contextWithDeadline(adapted.deadline)
+			defer cancel()
+			ctx = withParameterOverrides(ctx, settings.parameters)
+			result, err := p.runPart2(ctx, logger, input, adapted.part1Context)
+			if err != nil {
+				result = p.reportFailure(2, logger, err, timeLimit, timeouts)
+				p.noteAnswer(answers, 2, input, result, true)
+				return result
+			}
+			p.noteAnswer(answers, 2, input, result, false)
+			return result
+		},
+		ExampleInput:       p.ExampleInput,
+		ExamplePart1Answer: p.ExamplePart1Answer,
+		ExamplePart2Answer: p.ExamplePart2Answer,
+	}
+}
+
+// What implementation() passes from part 1 to part 2 via the runner:
+// the day's own context if part 1 succeeded, or the error if not,
+// plus the time by which part 2 needs to finish.
+type adaptedContext struct {
+	part1Context any
+	err          error
+	deadline     time.Time
+}
+
+func contextWithDeadline(deadline time.Time) (context.Context, context.CancelFunc) {
+	if deadline.IsZero() {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithDeadline(context.Background(), deadline)
+}
+
+func (p puzzle) noteAnswer(answers *answerBook, part int, input string, result string, failed bool) {
+	// The example has its own answers to check against, which the
+	// runner takes care of.
+	if answers != nil && input != p.ExampleInput {
+		answers.add(p.DayNumber, part, input, result, failed)
+	}
+}
+
+func (p puzzle) reportFailure(part int, logger *slog.Logger, err error, timeLimit time.Duration, timeouts *timeoutRecord) string {
+	if errors.Is(err, context.DeadlineExceeded) {
+		logger.Warn("Timed out", slog.Int("part", part), slog.Duration("limit", timeLimit))
+		timeouts.add(p.DayNumber)
+		return fmt.Sprintf("TIMED OUT: over %s", timeLimit)
+	}
+	logger.Error(fmt.Sprintf("Part %d failed", part), slog.Any("error", err))
+	return "ERROR: " + err.Error()
+}
+
+// The days that have run out of time, which may be added to from
+// several goroutines at once.
+type timeoutRecord struct {
+	mu   sync.Mutex
+	days []int
+}
+
+func (r *timeoutRecord) add(day int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !slices.Contains(r.days, day) {
+		r.days = append(r.days, day)
+	}
+}
+
+// Print which days timed out, if any did.
+func (r *timeoutRecord) report(w io.Writer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.days) == 0 {
+		return
+	}
+	slices.Sort(r.days)
+	days := make([]string, len(r.days))
+	for ix, day := range r.days {
+		days[ix] = strconv.Itoa(day)
+	}
+	fmt.Fprintf(w, "Days that timed out: %s\n", strings.Join(days, ", "))
+}
+
+// The error returned when a day can't solve its input. Line and
+// Column are 1-based positions in the input, and are zero when the
+// problem can't be pinned to a particular place (e.g. "there's no
+// start position anywhere in this maze").
+type DayError struct {
+	Day    int
+	Part   int
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e *DayError) Error() string {
+	switch {
+	case e.Line > 0 && e.Column > 0:
+		return fmt.Sprintf("day %d part %d: line %d, column %d: %v", e.Day, e.Part, e.Line, e.Column, e.Err)
+	case e.Line > 0:
+		return fmt.Sprintf("day %d part %d: line %d: %v", e.Day, e.Part, e.Line, e.Err)
+	default:
+		return fmt.Sprintf("day %d part %d: %v", e.Day, e.Part, e.Err)
+	}
+}
+
+func (e *DayError) Unwrap() error {
+	return e.Err
+}
+
+// What the solvers themselves return on bad input - they know where
+// in the input the problem is, but not which day or part they are.
+type inputError struct {
+	line   int
+	column int
+	msg    string
+}
+
+func (e *inputError) Error() string {
+	return e.msg
+}
+
+// Report a problem at a given 1-based line and column of the input.
+// Either may be zero if unknown.
+func errorAt(line int, column int, format string, args ...any) error {
+	return &inputError{line, column, fmt.Sprintf(format, args...)}
+}
+
+func wrapDayError(day int, part int, err error) *DayError {
+	dayErr := &DayError{Day: day, Part: part, Err: err}
+	var inputErr *inputError
+	if errors.As(err, &inputErr) {
+		dayErr.Line, dayErr.Column = inputErr.line, inputErr.column
+	}
+	return dayErr
+}
+
+// Parse a number found at a given position of the input.
+func atoiAt(s string, line int, column int) (int, error) {
+	num, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, errorAt(line, column, "expected a number, found %q", s)
+	}
+	return num, nil
+}
+
+// The proportion of cache lookups that found something, for days
+// that log how well their memoisation is doing.
+func hitRate(hits int, misses int) float64 {
+	if hits+misses == 0 {
+		return 0
+	}
+	return float64(hits) / float64(hits+misses)
+}
+
+// For errors from parsing a section of the input that doesn't start
+// on the first line, e.g. one of several grids. Shift the reported
+// line down by the number of lines before the section.
+func offsetErrorLine(err error, linesBefore int) error {
+	var inputErr *inputError
+	if errors.As(err, &inputErr) && inputErr.line > 0 {
+		inputErr.line += linesBefore
+	}
+	return err
+}
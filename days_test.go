@@ -0,0 +1,296 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Every day carries its own example input and answers, so the
+// bulk of this suite is simply running those through the same
+// list of days that main() hands to the runner. On top of that,
+// any extra inputs with known answers can be dropped into
+// testdata/ (see fixturesForDay below) and they'll be picked up
+// automatically.
+
+// A single input along with the answers we expect for it. An
+// empty answer means we don't know it, and the corresponding
+// part gets reported as skipped rather than silently passing.
+type fixture struct {
+	name        string
+	input       string
+	part1Answer string
+	part2Answer string
+}
+
+func quietLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestDays(t *testing.T) {
+	for _, day := range allDays {
+		t.Run(fmt.Sprintf("Day%02d", day.DayNumber), func(t *testing.T) {
+			fixtures := fixturesForDay(t, day.DayNumber)
+			if day.ExampleInput != "" {
+				// The same again, but as it might arrive from a
+				// Windows machine, to check normaliseInput copes.
+				crlf := strings.ReplaceAll(day.ExampleInput, "\n", "\r\n") + "\r\n\r\n"
+				fixtures = append([]fixture{
+					{"example", day.ExampleInput, day.ExamplePart1Answer, day.ExamplePart2Answer},
+					{"exampleCRLF", crlf, day.ExamplePart1Answer, day.ExamplePart2Answer},
+				}, fixtures...)
+			}
+			if len(fixtures) == 0 {
+				t.Skipf("day %d has no example input and no fixtures in testdata", day.DayNumber)
+			}
+
+			for _, f := range fixtures {
+				t.Run(f.name, func(t *testing.T) {
+					runFixture(t, day, f)
+				})
+			}
+		})
+	}
+}
+
+func runFixture(t *testing.T, day puzzle, f fixture) {
+	logger := quietLogger()
+
+	// Part 2 needs part 1's context, so part 1 always runs even
+	// if we have no answer to compare it against.
+	part1Result, part1Context, err := day.runPart1(context.Background(), logger, f.input)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	t.Run("part1", func(t *testing.T) {
+		if f.part1Answer == "" {
+			t.Skipf("no known part 1 answer; got %q", part1Result)
+		}
+		if part1Result != f.part1Answer {
+			t.Errorf("day %d part 1 (%s): got %q, want %q", day.DayNumber, f.name, part1Result, f.part1Answer)
+		}
+	})
+
+	t.Run("part2", func(t *testing.T) {
+		if f.part2Answer == "" {
+			// This covers both days without a second part (Day25)
+			// and days that deliberately don't attempt part 2 on
+			// small inputs (Day24).
+			t.Skipf("no known part 2 answer, not running part 2")
+		}
+		part2Result, err := day.runPart2(context.Background(), logger, f.input, part1Context)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if part2Result != f.part2Answer {
+			t.Errorf("day %d part 2 (%s): got %q, want %q", day.DayNumber, f.name, part2Result, f.part2Answer)
+		}
+	})
+}
+
+// Part 2 should still get the right answer when part 1 hasn't been
+// run first, by working out part 1's context for itself.
+func TestPart2Alone(t *testing.T) {
+	for _, day := range allDays {
+		t.Run(fmt.Sprintf("Day%02d", day.DayNumber), func(t *testing.T) {
+			if day.ExampleInput == "" || day.ExamplePart2Answer == "" {
+				t.Skipf("day %d has no example part 2 answer", day.DayNumber)
+			}
+			result, err := day.runPart2(context.Background(), quietLogger(), day.ExampleInput, nil)
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+			if result != day.ExamplePart2Answer {
+				t.Errorf("day %d part 2 on its own: got %q, want %q", day.DayNumber, result, day.ExamplePart2Answer)
+			}
+		})
+	}
+}
+
+// Days mustn't keep state anywhere that outlives a run, so running
+// the same day several times at once should give the same answers
+// every time.
+func TestDaysConcurrently(t *testing.T) {
+	const RUNS = 3
+	for _, day := range allDays {
+		if day.ExampleInput == "" {
+			continue
+		}
+		for run := range RUNS {
+			t.Run(fmt.Sprintf("Day%02d/run%d", day.DayNumber, run), func(t *testing.T) {
+				t.Parallel()
+				runFixture(t, day, fixture{"example", day.ExampleInput, day.ExamplePart1Answer, day.ExamplePart2Answer})
+			})
+		}
+	}
+}
+
+// Extra fixtures for a day live in testdata/dayNN/. Each fixture
+// is a pair of files: NAME.in holds the puzzle input, and NAME.out
+// holds the part 1 answer on its first line and the part 2 answer
+// on its second. Either answer may be left blank if unknown.
+func fixturesForDay(t testing.TB, dayNumber int) []fixture {
+	dir := filepath.Join("testdata", fmt.Sprintf("day%02d", dayNumber))
+	inputFiles, err := filepath.Glob(filepath.Join(dir, "*.in"))
+	if err != nil {
+		t.Fatalf("listing fixtures in %s: %v", dir, err)
+	}
+
+	fixtures := make([]fixture, 0, len(inputFiles))
+	for _, inputFile := range inputFiles {
+		name := strings.TrimSuffix(filepath.Base(inputFile), ".in")
+		input, err := os.ReadFile(inputFile)
+		if err != nil {
+			t.Fatalf("reading fixture %s: %v", inputFile, err)
+		}
+		answers, err := os.ReadFile(filepath.Join(dir, name+".out"))
+		if err != nil {
+			t.Fatalf("reading answers for fixture %s: %v", inputFile, err)
+		}
+		answerLines := strings.Split(strings.TrimRight(string(answers), "\n"), "\n")
+		f := fixture{name: name, input: strings.TrimRight(string(input), "\n")}
+		f.part1Answer = strings.TrimSpace(answerLines[0])
+		if len(answerLines) > 1 {
+			f.part2Answer = strings.TrimSpace(answerLines[1])
+		}
+		fixtures = append(fixtures, f)
+	}
+	return fixtures
+}
+
+// Malformed inputs should produce a DayError pointing at the
+// problem, rather than a panic or a wrong answer.
+func TestBadInputs(t *testing.T) {
+	tests := []struct {
+		name   string
+		day    puzzle
+		part   int
+		input  string
+		line   int
+		column int
+	}{
+		{"day 1 missing number", Day1, 1, "3   4\n4\n2   5", 2, 0},
+		{"day 2 not a number", Day2, 1, "7 6 4 2 1\n1 2 x 8 9", 2, 5},
+		{"day 2 blank line", Day2, 1, "7 6 4 2 1\n\n1 3 6 7 9", 2, 1},
+		{"day 5 page out of range", Day5, 1, "47|53\n97|130\n\n47,53", 2, 4},
+		{"day 6 no guard", Day6, 1, "....\n.#..\n....", 0, 0},
+		{"day 7 missing colon", Day7, 1, "190: 10 19\n3267 81 40 27", 2, 0},
+		{"day 9 not a digit", Day9, 1, "23331x3121", 1, 6},
+		{"day 9 empty file", Day9, 1, "2333013121", 1, 5},
+		{"day 13 wrong prefix", Day13, 1, "Button A: X+94, Y+34\nButton C: X+22, Y+67\nPrize: X=8400, Y=5400", 2, 1},
+		{"day 15 bad movement", Day15, 1, "#####\n#@.O#\n#####\n\n<>^x", 5, 4},
+		{"day 15 no wall", Day15, 1, "#####\n#@.O.\n#####\n\n<>", 2, 5},
+		{"day 16 no end", Day16, 1, "#####\n#S..#\n#####", 0, 0},
+		{"day 17 negative register", Day17, 1, "Register A: 5\nRegister B: -1\nRegister C: 0\n\nProgram: 0,5", 2, 13},
+		{"day 17 bad combo operand", Day17, 1, "Register A: 1\nRegister B: 0\nRegister C: 0\n\nProgram: 5,7", 5, 0},
+		{"day 18 outside grid", Day18, 1, "1,1\n90,2", 2, 0},
+		{"day 20 dead end", Day20, 1, "#######\n#S.#.E#\n#######", 2, 3},
+		{"day 21 bad code", Day21, 1, "029A\n98xA", 2, 3},
+		{"day 23 bad connection", Day23, 1, "kh-tc\nqpkh", 2, 0},
+		{"day 24 loop", Day24, 1, "x00: 1\ny00: 1\n\nx00 AND abc -> z00\nz00 OR y00 -> abc\nx00 XOR y00 -> z01", 4, 0},
+		{"day 25 short row", Day25, 1, "#####\n.####\n.###\n.####\n.#.#.\n.#...\n.....", 3, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			logger := quietLogger()
+			_, _, err := test.day.runPart1(context.Background(), logger, test.input)
+			var dayErr *DayError
+			if !errors.As(err, &dayErr) {
+				t.Fatalf("expected a DayError, got %v", err)
+			}
+			if dayErr.Day != test.day.DayNumber || dayErr.Part != test.part || dayErr.Line != test.line || dayErr.Column != test.column {
+				t.Errorf("got error at day %d part %d line %d column %d (%v), want day %d part %d line %d column %d",
+					dayErr.Day, dayErr.Part, dayErr.Line, dayErr.Column, dayErr, test.day.DayNumber, test.part, test.line, test.column)
+			}
+		})
+	}
+}
+
+// Inputs that would keep a day busy forever should be given up on
+// once the context's deadline passes, rather than hanging.
+func TestTimeouts(t *testing.T) {
+	tests := []struct {
+		name  string
+		day   puzzle
+		part  int
+		input string
+	}{
+		// jnz 0 with A never changing, so the program never halts.
+		{"day 17 endless program", Day17, 1, "Register A: 1\nRegister B: 0\nRegister C: 0\n\nProgram: 3,0"},
+		// 2^40 combinations of operators, none of which get close.
+		{"day 7 long equation", Day7, 1, "1000000000: " + strings.Repeat("1 ", 40)},
+		// 200 million pairs of antennae.
+		{"day 8 crowded frequency", Day8, 1, strings.Repeat("a", 20000)},
+		// A million pairs, which part 1 gets through quickly enough,
+		// but tens of millions of steps along their lines for part 2.
+		{"day 8 long line", Day8, 2, strings.Repeat("a", 1500)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Part 2 only gets the time limit once part 1's finished.
+			var part1Context any
+			var err error
+			if test.part == 2 {
+				if _, part1Context, err = test.day.runPart1(context.Background(), quietLogger(), test.input); err != nil {
+					t.Fatalf("%v", err)
+				}
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			if test.part == 2 {
+				_, err = test.day.runPart2(ctx, quietLogger(), test.input, part1Context)
+			} else {
+				_, _, err = test.day.runPart1(ctx, quietLogger(), test.input)
+			}
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("expected a timeout, got %v", err)
+			}
+		})
+	}
+}
+
+// Every day should say something at debug level about what it's
+// doing, and it should all come out as valid JSON - in particular,
+// nothing logged should be a struct whose fields JSON can't see.
+func TestDaysLog(t *testing.T) {
+	for _, day := range allDays {
+		if day.ExampleInput == "" {
+			continue
+		}
+		t.Run(fmt.Sprintf("Day%02d", day.DayNumber), func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+			_, part1Context, err := day.runPart1(context.Background(), logger, day.ExampleInput)
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+			if _, err := day.runPart2(context.Background(), logger, day.ExampleInput, part1Context); err != nil {
+				t.Fatalf("%v", err)
+			}
+
+			if buf.Len() == 0 {
+				t.Fatalf("day %d logged nothing", day.DayNumber)
+			}
+			for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+				var record map[string]any
+				if err := json.Unmarshal([]byte(line), &record); err != nil {
+					t.Fatalf("invalid JSON log record %q: %v", line, err)
+				}
+				if strings.Contains(line, "{}") {
+					t.Errorf("log record with an empty object: %s", line)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+// A direction of travel on a grid. The eight directions go
+// clockwise from UP, so the four orthogonal directions are the
+// even values and turning is just arithmetic. Days that only move
+// orthogonally use UP, RIGHT, DOWN and LEFT and ignore the rest.
+type Direction int
+
+const (
+	UP Direction = iota
+	UP_RIGHT
+	RIGHT
+	DOWN_RIGHT
+	DOWN
+	DOWN_LEFT
+	LEFT
+	UP_LEFT
+)
+
+const NUM_DIRECTIONS int = 8
+
+// For ranging over, e.g. "for _, dir := range ORTHOGONAL_DIRECTIONS".
+var ORTHOGONAL_DIRECTIONS = [4]Direction{UP, RIGHT, DOWN, LEFT}
+var ALL_DIRECTIONS = [NUM_DIRECTIONS]Direction{UP, UP_RIGHT, RIGHT, DOWN_RIGHT, DOWN, DOWN_LEFT, LEFT, UP_LEFT}
+
+var directionDeltas = [NUM_DIRECTIONS]gridPos{
+	UP:         {-1, 0},
+	UP_RIGHT:   {-1, 1},
+	RIGHT:      {0, 1},
+	DOWN_RIGHT: {1, 1},
+	DOWN:       {1, 0},
+	DOWN_LEFT:  {1, -1},
+	LEFT:       {0, -1},
+	UP_LEFT:    {-1, -1},
+}
+
+// Puzzles draw directions as ^>v<. There's no standard for the
+// diagonals, so we use the Unicode arrows.
+var directionArrows = [NUM_DIRECTIONS]rune{'^', '↗', '>', '↘', 'v', '↙', '<', '↖'}
+
+// Turn by a number of eighths of a full turn, clockwise if
+// positive and anticlockwise if negative.
+func (dir Direction) Rotate(eighths int) Direction {
+	return Direction(((int(dir)+eighths)%NUM_DIRECTIONS + NUM_DIRECTIONS) % NUM_DIRECTIONS)
+}
+
+func (dir Direction) TurnRight() Direction {
+	return dir.Rotate(2)
+}
+
+func (dir Direction) TurnLeft() Direction {
+	return dir.Rotate(-2)
+}
+
+func (dir Direction) Reverse() Direction {
+	return dir.Rotate(4)
+}
+
+func (dir Direction) IsOrthogonal() bool {
+	return dir%2 == 0
+}
+
+// For orthogonal directions, a number from 0-3, for days that
+// keep something per direction in a four-element array.
+func (dir Direction) orthogonalIndex() int {
+	return int(dir) / 2
+}
+
+// How far one step in this direction moves you.
+func (dir Direction) Delta() gridPos {
+	return directionDeltas[dir]
+}
+
+func (dir Direction) Arrow() rune {
+	return directionArrows[dir]
+}
+
+// The direction drawn as a given arrow character, or false if
+// the character isn't one.
+func directionFromArrow(arrow rune) (Direction, bool) {
+	for dir, candidate := range directionArrows {
+		if candidate == arrow {
+			return Direction(dir), true
+		}
+	}
+	return UP, false
+}
+
+// The position one step away in the given direction. This doesn't
+// care about grid bounds - see Grid.InBounds for that.
+func (pos gridPos) move(dir Direction) gridPos {
+	return pos.Add(directionDeltas[dir])
+}
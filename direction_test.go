@@ -0,0 +1,57 @@
+package main
+
+import "testing"
+
+func TestDirectionTurning(t *testing.T) {
+	for _, dir := range ALL_DIRECTIONS {
+		if got := dir.TurnRight().TurnLeft(); got != dir {
+			t.Errorf("%c turned right then left is %c", dir.Arrow(), got.Arrow())
+		}
+		if got := dir.Reverse().Reverse(); got != dir {
+			t.Errorf("%c reversed twice is %c", dir.Arrow(), got.Arrow())
+		}
+		if got := dir.TurnRight().TurnRight(); got != dir.Reverse() {
+			t.Errorf("%c turned right twice is %c, want %c", dir.Arrow(), got.Arrow(), dir.Reverse().Arrow())
+		}
+		// Going one way then back again should cancel out.
+		if got := (gridPos{3, 3}).move(dir).move(dir.Reverse()); got != (gridPos{3, 3}) {
+			t.Errorf("%c then back again ends up at %v", dir.Arrow(), got)
+		}
+	}
+
+	if got := UP.Rotate(-1); got != UP_LEFT {
+		t.Errorf("UP rotated an eighth anticlockwise is %c", got.Arrow())
+	}
+	if got := LEFT.TurnRight(); got != UP {
+		t.Errorf("LEFT turned right is %c", got.Arrow())
+	}
+}
+
+func TestDirectionArrows(t *testing.T) {
+	tests := []struct {
+		arrow rune
+		dir   Direction
+		delta gridPos
+	}{
+		{'^', UP, gridPos{-1, 0}},
+		{'>', RIGHT, gridPos{0, 1}},
+		{'v', DOWN, gridPos{1, 0}},
+		{'<', LEFT, gridPos{0, -1}},
+	}
+	for _, test := range tests {
+		dir, ok := directionFromArrow(test.arrow)
+		if !ok || dir != test.dir {
+			t.Errorf("%q parsed as %v, %v", test.arrow, dir, ok)
+		}
+		if dir.Arrow() != test.arrow {
+			t.Errorf("%q rendered as %q", test.arrow, dir.Arrow())
+		}
+		if dir.Delta() != test.delta {
+			t.Errorf("%q moves by %v, want %v", test.arrow, dir.Delta(), test.delta)
+		}
+	}
+
+	if _, ok := directionFromArrow('x'); ok {
+		t.Errorf("'x' parsed as a direction")
+	}
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"runtime"
+	"time"
+
+	runner "github.com/ThePants999/advent-of-code-go-runner"
+)
+
+// The runner runs days strictly one after another, which is what
+// you want when timing them individually. But the days are all
+// independent of one another, so when what you want is all the
+// answers as quickly as possible, we can do better by running them
+// all at once. The runner also insists on fetching inputs itself,
+// which needs a session cookie, whereas sometimes we just want to
+// point a day at a file. It supports neither, so this is our own
+// much simpler equivalent of it.
+
+// What one part of a day came up with, and what it cost.
+type partRun struct {
+	result   string
+	duration time.Duration
+
+	// How many heap allocations the part made. We can only tell
+	// when days aren't running in parallel, as otherwise there's
+	// no telling whose allocations are whose.
+	allocs        uint64
+	allocsCounted bool
+
+	// Only filled in when profiling.
+	profiled       bool
+	bytesAllocated uint64
+	peakHeap       uint64
+}
+
+// Everything we found out from running one day. Each pair is
+// indexed by part number minus one.
+type dayRun struct {
+	day puzzle
+
+	// What each part got for the example input, and whether that
+	// was right. Only filled in if we ran the example.
+	ranExample     bool
+	example        [2]partRun
+	exampleCorrect [2]bool
+
+	// The answers for the real input, and how long they took.
+	ranReal  bool
+	real     [2]partRun
+	inputErr error
+}
+
+func (run *dayRun) totalTime() time.Duration {
+	return run.real[0].duration + run.real[1].duration
+}
+
+// How days are to be run, shared between all of them.
+type runSettings struct {
+	logger    *slog.Logger
+	timeLimit time.Duration
+	timeouts  *timeoutRecord
+	answers   *answerBook
+
+	// Whether to count each part's allocations, which is only
+	// meaningful if nothing else is running at the same time.
+	countAllocs bool
+
+	// If set, the real inputs are profiled, with the profiles going
+	// in this directory. See profilePart.
+	profileDir string
+
+	// Values for day parameters, overriding their defaults.
+	parameters map[*dayParameter]int
+
+	// Where the real inputs come from. Errors wrapping
+	// fs.ErrNotExist mean there's no input for that day.
+	readInput func(dayNumber int) (string, error)
+}
+
+// Run both parts of a day against an input, with the same handling
+// of failures and time limits as the runner sees via
+// implementation().
+func runBothParts(settings runSettings, day puzzle, input string) (parts [2]partRun) {
+	// The runner tags everything a day logs with the day number,
+	// which matters all the more when days are running at once.
+	logger := settings.logger.With(slog.Int("day", day.DayNumber))
+	impl := day.implementation(settings)
+	var part1Context any
+	parts[0] = measurePart(settings, day.DayNumber, 1, func() (result string) {
+		result, part1Context = impl.ExecutePart1(logger, input)
+		return
+	})
+	logger.Info("Part 1 results", slog.String("result", parts[0].result), slog.Duration("duration", parts[0].duration))
+	parts[1] = measurePart(settings, day.DayNumber, 2, func() string {
+		return impl.ExecutePart2(logger, input, part1Context)
+	})
+	logger.Info("Part 2 results", slog.String("result", parts[1].result), slog.Duration("duration", parts[1].duration))
+	return
+}
+
+// Time a part, and count its allocations if asked. Reading the
+// memory stats stops the world, so it's kept outside the timing.
+func measurePart(settings runSettings, dayNumber int, partNumber int, part func() string) partRun {
+	if settings.profileDir != "" {
+		return profilePart(settings, dayNumber, partNumber, part)
+	}
+	countAllocs := settings.countAllocs
+	var before, after runtime.MemStats
+	if countAllocs {
+		runtime.ReadMemStats(&before)
+	}
+	start := time.Now()
+	run := partRun{result: part()}
+	run.duration = time.Since(start)
+	if countAllocs {
+		runtime.ReadMemStats(&after)
+		run.allocs = after.Mallocs - before.Mallocs
+		run.allocsCounted = true
+	}
+	return run
+}
+
+func (run *dayRun) runExample(settings runSettings) {
+	if run.day.ExampleInput == "" {
+		return
+	}
+	run.ranExample = true
+	// Profiles are for the real input only.
+	settings.profileDir = ""
+	run.example = runBothParts(settings, run.day, run.day.ExampleInput)
+	run.exampleCorrect[0] = run.example[0].result == run.day.ExamplePart1Answer
+	run.exampleCorrect[1] = run.example[1].result == run.day.ExamplePart2Answer
+}
+
+func (run *dayRun) runReal(settings runSettings) {
+	input, err := settings.readInput(run.day.DayNumber)
+	if err != nil {
+		run.inputErr = err
+		return
+	}
+	run.ranReal = true
+	run.real = runBothParts(settings, run.day, input)
+}
+
+// Run the given days, returning what we found out from each, plus
+// the wall-clock time taken for the real inputs.
+//
+// If parallel is set, the days all run at once on the shared worker
+// pool. Days farm their own work out to the same pool, so running
+// them alongside each other doesn't oversubscribe the CPU - a day
+// that finds the pool busy just does its work itself.
+func runDays(settings runSettings, days []puzzle, parallel bool, runExample bool, runReal bool) ([]dayRun, time.Duration) {
+	settings.countAllocs = !parallel
+	runs := make([]dayRun, len(days))
+	for ix, day := range days {
+		runs[ix].day = day
+	}
+	forEachRun := func(f func(run *dayRun)) {
+		if parallel {
+			parallelFor(context.Background(), len(runs), func(ix int) {
+				f(&runs[ix])
+			})
+		} else {
+			for ix := range runs {
+				f(&runs[ix])
+			}
+		}
+	}
+
+	// The examples are quick, but we still don't want them muddying
+	// the timings, so they all get done up front.
+	if runExample {
+		forEachRun(func(run *dayRun) { run.runExample(settings) })
+	}
+
+	var wallClock time.Duration
+	if runReal {
+		start := time.Now()
+		forEachRun(func(run *dayRun) { run.runReal(settings) })
+		wallClock = time.Since(start)
+	}
+	return runs, wallClock
+}
+
+// Print the results of runDays in the same order and much the same
+// format as the runner would have.
+func printRuns(runs []dayRun, parallel bool, wallClock time.Duration, profileDir string) {
+	var sumOfDays time.Duration
+	missingInputs := false
+	ranReal := false
+	for _, run := range runs {
+		fmt.Println(runner.DAY_SEPARATOR)
+		fmt.Printf("Day %d\n", run.day.DayNumber)
+		if run.ranExample {
+			fmt.Println("--Example input--")
+			printExampleResult(1, run.exampleCorrect[0], run.day.ExamplePart1Answer, run.example[0].result)
+			printExampleResult(2, run.exampleCorrect[1], run.day.ExamplePart2Answer, run.example[1].result)
+		}
+		if run.inputErr != nil {
+			if errors.Is(run.inputErr, fs.ErrNotExist) {
+				missingInputs = true
+				fmt.Println("No input for this day")
+			} else {
+				fmt.Printf("Couldn't read input: %v\n", run.inputErr)
+			}
+		}
+		if run.ranReal {
+			fmt.Println("--Real input--")
+			fmt.Printf("Part 1: %s (%s)\nPart 2: %s (%s)\nTotal time: %s\n", run.real[0].result, run.real[0].duration, run.real[1].result, run.real[1].duration, run.totalTime())
+			sumOfDays += run.totalTime()
+			ranReal = true
+			if run.real[0].profiled {
+				printProfile(&run, profileDir)
+			}
+		}
+	}
+	fmt.Println(runner.DAY_SEPARATOR)
+	if missingInputs {
+		fmt.Println("Some inputs are missing. Either put them in the inputs directory as dayNN.txt, or run those days once with none of -j, -i, --inputDir or -f to download them.")
+	}
+	if ranReal && parallel {
+		fmt.Printf("Total time: %s summed across days, %s wall-clock\n", sumOfDays, wallClock)
+	} else if ranReal && len(runs) > 1 {
+		fmt.Printf("Total time: %s\n", sumOfDays)
+	}
+}
+
+func printExampleResult(part int, correct bool, expected string, received string) {
+	if correct {
+		fmt.Printf("Part %d: CORRECT\n", part)
+	} else {
+		fmt.Printf("Part %d: INCORRECT\nExpected:  %s\nReceived:  %s\n", part, expected, received)
+	}
+}
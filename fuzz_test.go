@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"runtime/debug"
+	"testing"
+	"time"
+)
+
+// Fuzz targets for parsing every day's input. The parsers are
+// written for speed, and lean on the input looking the way the
+// puzzle says it will - so these check that, whatever they're
+// given, each day either reports where the input's wrong or comes
+// up with an answer, and never panics or hangs.
+//
+// Parsing happens as the first thing part 1 does, so that's what
+// gets fuzzed. The seed corpus is each day's example, plus any
+// fixtures in testdata. Run one with something like:
+//   go test -run '^$' -fuzz FuzzDay13 -fuzztime 1m
+
+// How long part 1 gets before it should give up, and how much longer
+// than that it can take to notice before we call it a hang.
+const FUZZ_TIME_LIMIT time.Duration = time.Second
+const FUZZ_HANG_GRACE time.Duration = 5 * time.Second
+
+// Days without an example still need something to start from. These
+// are cut-down versions of the puzzles' own examples.
+var extraFuzzSeeds = map[int][]string{
+	14: {"p=0,4 v=3,-3\np=6,3 v=-1,-3\np=10,3 v=-1,2\np=2,0 v=2,-1"},
+	24: {"x00: 1\nx01: 0\ny00: 1\ny01: 1\n\nx00 AND y00 -> z00\nx01 XOR y01 -> z01\nx00 OR y01 -> z02"},
+}
+
+func fuzzDay(f *testing.F, day puzzle) {
+	if day.ExampleInput != "" {
+		f.Add(day.ExampleInput)
+	}
+	for _, seed := range extraFuzzSeeds[day.DayNumber] {
+		f.Add(seed)
+	}
+	for _, fixture := range fixturesForDay(f, day.DayNumber) {
+		f.Add(fixture.input)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		// Run part 1 directly rather than via runPart1, which would
+		// turn a panic into an error and hide it from the fuzzer.
+		ctx, cancel := context.WithTimeout(context.Background(), FUZZ_TIME_LIMIT)
+		defer cancel()
+		ctx = day.withParameters(ctx, normaliseInput(input))
+		type outcome struct {
+			err      error
+			panicked any
+			stack    []byte
+		}
+		done := make(chan outcome, 1)
+		go func() {
+			// A panic on another goroutine would take the whole
+			// fuzzer down with it, rather than being reported
+			// against the input that caused it.
+			defer func() {
+				if r := recover(); r != nil {
+					done <- outcome{panicked: r, stack: debug.Stack()}
+				}
+			}()
+			_, _, err := day.ExecutePart1(ctx, quietLogger(), normaliseInput(input))
+			done <- outcome{err: err}
+		}()
+
+		var got outcome
+		select {
+		case got = <-done:
+		case <-time.After(FUZZ_TIME_LIMIT + FUZZ_HANG_GRACE):
+			t.Fatalf("day %d still running %s after it should have given up, on input %q", day.DayNumber, FUZZ_HANG_GRACE, input)
+		}
+
+		var inputErr *inputError
+		switch {
+		case got.panicked != nil:
+			t.Fatalf("day %d panicked on input %q: %v\n%s", day.DayNumber, input, got.panicked, got.stack)
+		case got.err == nil:
+		case errors.Is(got.err, context.DeadlineExceeded):
+			// Taking too long on a pathological input is fine, as
+			// long as we gave up when asked.
+		case !errors.As(got.err, &inputErr):
+			t.Errorf("day %d returned an error that doesn't say where in the input the problem is: %v", day.DayNumber, got.err)
+		}
+	})
+}
+
+func FuzzDay01(f *testing.F) { fuzzDay(f, Day1) }
+func FuzzDay02(f *testing.F) { fuzzDay(f, Day2) }
+func FuzzDay03(f *testing.F) { fuzzDay(f, Day3) }
+func FuzzDay04(f *testing.F) { fuzzDay(f, Day4) }
+func FuzzDay05(f *testing.F) { fuzzDay(f, Day5) }
+func FuzzDay06(f *testing.F) { fuzzDay(f, Day6) }
+func FuzzDay07(f *testing.F) { fuzzDay(f, Day7) }
+func FuzzDay08(f *testing.F) { fuzzDay(f, Day8) }
+func FuzzDay09(f *testing.F) { fuzzDay(f, Day9) }
+func FuzzDay10(f *testing.F) { fuzzDay(f, Day10) }
+func FuzzDay11(f *testing.F) { fuzzDay(f, Day11) }
+func FuzzDay12(f *testing.F) { fuzzDay(f, Day12) }
+func FuzzDay13(f *testing.F) { fuzzDay(f, Day13) }
+func FuzzDay14(f *testing.F) { fuzzDay(f, Day14) }
+func FuzzDay15(f *testing.F) { fuzzDay(f, Day15) }
+func FuzzDay16(f *testing.F) { fuzzDay(f, Day16) }
+func FuzzDay17(f *testing.F) { fuzzDay(f, Day17) }
+func FuzzDay18(f *testing.F) { fuzzDay(f, Day18) }
+func FuzzDay19(f *testing.F) { fuzzDay(f, Day19) }
+func FuzzDay20(f *testing.F) { fuzzDay(f, Day20) }
+func FuzzDay21(f *testing.F) { fuzzDay(f, Day21) }
+func FuzzDay22(f *testing.F) { fuzzDay(f, Day22) }
+func FuzzDay23(f *testing.F) { fuzzDay(f, Day23) }
+func FuzzDay24(f *testing.F) { fuzzDay(f, Day24) }
+func FuzzDay25(f *testing.F) { fuzzDay(f, Day25) }
@@ -0,0 +1,937 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Random input generators, one per day. Each produces a valid input
+// of roughly the given size, which satisfies whatever the puzzle
+// promises about its inputs - the guard leaves the map, the maze can
+// be solved, the program can output itself and so on - so that the
+// days can run on them. They're for stress-testing and benchmarking
+// with more than the one input each of us gets, via the generate
+// command, and for testing days against their reference
+// implementations (see reference_test.go).
+type inputGenerator struct {
+	// What size is a number of - reports, robots and so on - and
+	// roughly how many there are in a real input.
+	sizeMeans   string
+	defaultSize int
+	generate    func(rng *rand.Rand, size int) string
+}
+
+var inputGenerators = map[int]inputGenerator{
+	1:  {"pairs of location IDs", 1000, generateDay1},
+	2:  {"reports", 1000, generateDay2},
+	3:  {"characters of memory", 18000, generateDay3},
+	4:  {"rows and columns", 140, generateDay4},
+	5:  {"updates", 200, generateDay5},
+	6:  {"rows and columns", 130, generateDay6},
+	7:  {"equations", 850, generateDay7},
+	8:  {"rows and columns", 50, generateDay8},
+	9:  {"digits in the disk map", 19999, generateDay9},
+	10: {"rows and columns", 50, generateDay10},
+	11: {"stones", 8, generateDay11},
+	12: {"rows and columns", 140, generateDay12},
+	13: {"claw machines", 320, generateDay13},
+	14: {"robots", 500, generateDay14},
+	15: {"rows and columns", 50, generateDay15},
+	16: {"rows and columns", 141, generateDay16},
+	17: {"instructions working out each output (at most 6)", 4, generateDay17},
+	18: {"falling bytes (at least)", 3450, generateDay18},
+	19: {"designs", 400, generateDay19},
+	20: {"rows and columns", 141, generateDay20},
+	21: {"codes", 5, generateDay21},
+	22: {"buyers", 2000, generateDay22},
+	23: {"computers", 520, generateDay23},
+	24: {"bits in the numbers being added", 45, func(rng *rand.Rand, size int) string { return generateDay24(rng, size, d24SwappedPairs.Default) }},
+	25: {"locks and keys", 500, generateDay25},
+}
+
+// The generate command: write a random input for one day to stdout,
+// or for any number of days to dayNN.txt files in a directory, ready
+// for --inputDir.
+func runGenerate(args []string) error {
+	var day, size int
+	var seed uint64
+	var outDir string
+	flags := flag.NewFlagSet(os.Args[0]+" generate", flag.ContinueOnError)
+	flags.IntVar(&day, "d", 0, "")
+	flags.IntVar(&day, "day", 0, "")
+	flags.IntVar(&size, "size", 0, "")
+	flags.Uint64Var(&seed, "seed", 0, "")
+	flags.StringVar(&outDir, "outDir", "", "")
+	flags.Usage = printGenerateUsage
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	switch {
+	case flags.NArg() > 0:
+		return fmt.Errorf("unexpected %q", flags.Arg(0))
+	case day < 0 || day > len(allDays):
+		return fmt.Errorf("there's no day %d", day)
+	case day == 0 && outDir == "":
+		return errors.New("say which day to generate an input for with -d, or use --outDir to generate them all")
+	case size < 0:
+		return errors.New("--size can't be negative")
+	}
+	if seed == 0 {
+		seed = uint64(time.Now().UnixNano())
+	}
+	// Say what the seed was, so that an interesting input can be
+	// generated again - but not on stdout, where the input goes.
+	fmt.Fprintf(os.Stderr, "Generating with --seed %d\n", seed)
+
+	days := []int{day}
+	if day == 0 {
+		days = days[:0]
+		for dayNumber := 1; dayNumber <= len(allDays); dayNumber++ {
+			days = append(days, dayNumber)
+		}
+	}
+	if outDir != "" {
+		if err := os.MkdirAll(outDir, 0755); err != nil {
+			return err
+		}
+	}
+	for _, dayNumber := range days {
+		// Each day gets its own generator, so that one day's input
+		// doesn't depend on which other days were generated.
+		rng := rand.New(rand.NewPCG(seed, uint64(dayNumber)))
+		generator := inputGenerators[dayNumber]
+		daySize := size
+		if daySize == 0 {
+			daySize = generator.defaultSize
+		}
+		input := generator.generate(rng, daySize)
+		if outDir == "" {
+			fmt.Println(input)
+			continue
+		}
+		path := filepath.Join(outDir, fmt.Sprintf("day%02d.txt", dayNumber))
+		if err := os.WriteFile(path, []byte(input+"\n"), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const GENERATE_USAGE_TEXT = `Usage: %s generate [-d day] [--size N] [--seed N] [--outDir dir]
+Generate random inputs, which follow the rules the puzzles promise their inputs do.
+
+  -d, --day     The day to generate an input for, which is written to stdout
+      --outDir  Write inputs to this directory as dayNN.txt, ready for --inputDir. If
+                there's no -d, generate an input for every day
+      --size    How big an input to generate - see below for what it means for each
+                day. The default is about the size of a real input
+      --seed    Generate the same inputs as a previous run that used this seed
+
+Sizes:
+`
+
+func printGenerateUsage() {
+	fmt.Printf(GENERATE_USAGE_TEXT, os.Args[0])
+	for dayNumber := 1; dayNumber <= len(allDays); dayNumber++ {
+		generator := inputGenerators[dayNumber]
+		fmt.Printf("  Day %2d: %s (default %d)\n", dayNumber, generator.sizeMeans, generator.defaultSize)
+	}
+}
+
+// Pairs of five-digit numbers, where plenty of the numbers on the
+// right also appear on the left, so that part 2 has something to
+// find.
+func generateDay1(rng *rand.Rand, size int) string {
+	lines := make([]string, size)
+	lefts := make([]int, 0, size)
+	for ix := range lines {
+		left, right := 10000+rng.IntN(90000), 10000+rng.IntN(90000)
+		lefts = append(lefts, left)
+		if rng.IntN(3) == 0 {
+			right = lefts[rng.IntN(len(lefts))]
+		}
+		lines[ix] = fmt.Sprintf("%d   %d", left, right)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Reports of five to eight levels, most of which either are safe or
+// are one bad level away from it, so that both parts have plenty to
+// do.
+func generateDay2(rng *rand.Rand, size int) string {
+	reports := make([]string, size)
+	for ix := range reports {
+		levels := make([]int, 5+rng.IntN(4))
+		direction := 1
+		if rng.IntN(2) == 0 {
+			direction = -1
+		}
+		levels[0] = 20 + rng.IntN(60)
+		for levelIx := 1; levelIx < len(levels); levelIx++ {
+			levels[levelIx] = levels[levelIx-1] + direction*(1+rng.IntN(3))
+		}
+		// Spoil up to two levels.
+		for range rng.IntN(3) {
+			levels[rng.IntN(len(levels))] += rng.IntN(9) - 4
+		}
+		reports[ix] = joinInts(levels, " ")
+	}
+	return strings.Join(reports, "\n")
+}
+
+// Corrupted memory: real instructions, near misses that mustn't
+// count, and junk, in lines of about 3000 characters.
+func generateDay3(rng *rand.Rand, size int) string {
+	const junk = "!@#$%^&*()[]{}<>?/'+-_:;,~ whatwhowherewhenfromselecthowwhy"
+	operand := func() int {
+		return rng.IntN([]int{10, 100, 1000}[rng.IntN(3)])
+	}
+	var memory strings.Builder
+	lineStart := 0
+	for memory.Len() < size {
+		switch rng.IntN(12) {
+		case 0, 1, 2:
+			fmt.Fprintf(&memory, "mul(%d,%d)", operand(), operand())
+		case 3:
+			// Almost a mul.
+			fmt.Fprintf(&memory, []string{"mul(%d,%d]", "mul[%d,%d)", "mul(%d, %d)", "mul ( %d,%d)", "mul(%d,%d"}[rng.IntN(5)], operand(), operand())
+		case 4:
+			fmt.Fprintf(&memory, "mul(%d,%d)", 1000+rng.IntN(9000), operand())
+		case 5:
+			memory.WriteString([]string{"do()", "don't()", "do(", "don't", "do_not()"}[rng.IntN(5)])
+		default:
+			for range 1 + rng.IntN(8) {
+				memory.WriteByte(junk[rng.IntN(len(junk))])
+			}
+		}
+		if memory.Len()-lineStart >= 3000 {
+			memory.WriteByte('\n')
+			lineStart = memory.Len()
+		}
+	}
+	return strings.TrimRight(memory.String(), "\n")
+}
+
+// A word search that's nothing but Xs, Ms, As and Ss.
+func generateDay4(rng *rand.Rand, size int) string {
+	grid := newGrid[byte](size, size)
+	for pos := range grid.All() {
+		grid.Set(pos, "XMAS"[rng.IntN(4)])
+	}
+	return gridText(grid)
+}
+
+// Rules saying which way round every pair of some two-digit pages
+// go, and updates using those pages, half of which are already in
+// order. As in the real inputs, the rules between the pages in any
+// one update always put them in a definite order.
+func generateDay5(rng *rand.Rand, size int) string {
+	order := rng.Perm(90)[:49]
+	for ix := range order {
+		order[ix] += 10
+	}
+	rules := make([]string, 0, len(order)*(len(order)-1)/2)
+	for ix, before := range order {
+		for _, after := range order[ix+1:] {
+			rules = append(rules, fmt.Sprintf("%d|%d", before, after))
+		}
+	}
+	rng.Shuffle(len(rules), func(i int, j int) { rules[i], rules[j] = rules[j], rules[i] })
+
+	updates := make([]string, size)
+	for ix := range updates {
+		pages := rng.Perm(len(order))[:5+2*rng.IntN(10)]
+		if rng.IntN(2) == 0 {
+			slices.Sort(pages)
+		}
+		for pageIx, orderIx := range pages {
+			pages[pageIx] = order[orderIx]
+		}
+		updates[ix] = joinInts(pages, ",")
+	}
+	return strings.Join(rules, "\n") + "\n\n" + strings.Join(updates, "\n")
+}
+
+// A lab with scattered obstacles and a guard who, as promised,
+// eventually walks out of it. Left to chance, he usually finds his
+// way out in no time, so this tries a few labs and keeps the one
+// where he takes longest about it.
+func generateDay6(rng *rand.Rand, size int) string {
+	size = max(size, 2)
+	best, bestMoves := "", -1
+	for attempts := 0; attempts < 20 || best == ""; attempts++ {
+		grid := newGrid[byte](size, size)
+		for pos := range grid.All() {
+			grid.Set(pos, '.')
+			if rng.IntN(25) == 0 {
+				grid.Set(pos, '#')
+			}
+		}
+		start := gridPos{rng.IntN(size), rng.IntN(size)}
+		grid.Set(start, '^')
+
+		// Follow the guard to see whether he gets out.
+		pos, dir := start, UP
+		seen := make(map[obstacleHitState]bool)
+		for grid.InBounds(pos) && !seen[obstacleHitState{pos, dir}] {
+			seen[obstacleHitState{pos, dir}] = true
+			if next := pos.move(dir); grid.InBounds(next) && grid.At(next) == '#' {
+				dir = dir.TurnRight()
+			} else {
+				pos = next
+			}
+		}
+		if !grid.InBounds(pos) && len(seen) > bestMoves {
+			best, bestMoves = gridText(grid), len(seen)
+		}
+	}
+	return best
+}
+
+// Equations with two to twelve operands, about half of which can be
+// made true, with test values that fit comfortably in an int.
+func generateDay7(rng *rand.Rand, size int) string {
+	const maxValue = 1_000_000_000_000_000
+	lines := make([]string, size)
+	for ix := range lines {
+		operands := make([]int, 2+rng.IntN(11))
+		for operandIx := range operands {
+			operands[operandIx] = 1 + rng.IntN([]int{9, 99, 999}[rng.IntN(3)])
+		}
+		value := operands[0]
+		for _, operand := range operands[1:] {
+			switch rng.IntN(3) {
+			case 0:
+				value += operand
+			case 1:
+				value *= operand
+			default:
+				for shift := operand; shift > 0; shift /= 10 {
+					value *= 10
+				}
+				value += operand
+			}
+			if value > maxValue {
+				break
+			}
+		}
+		if value > maxValue || rng.IntN(2) == 0 {
+			value = 1 + rng.IntN(maxValue/1000)
+		}
+		lines[ix] = fmt.Sprintf("%d: %s", value, joinInts(operands, " "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// A map with a few antennas on each of lots of frequencies.
+func generateDay8(rng *rand.Rand, size int) string {
+	const frequencies = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	grid := newGrid[byte](size, size)
+	for pos := range grid.All() {
+		grid.Set(pos, '.')
+	}
+	numFrequencies := min(len(frequencies), max(1, size*size/60))
+	for ix := range numFrequencies {
+		for range 3 + rng.IntN(2) {
+			if pos := (gridPos{rng.IntN(size), rng.IntN(size)}); grid.At(pos) == '.' {
+				grid.Set(pos, frequencies[ix])
+			}
+		}
+	}
+	return gridText(grid)
+}
+
+// A disk map, which always ends with a file. Files are never empty,
+// though the gaps between them can be.
+func generateDay9(rng *rand.Rand, size int) string {
+	diskMap := make([]byte, size|1)
+	for ix := range diskMap {
+		if ix%2 == 0 {
+			diskMap[ix] = byte('1' + rng.IntN(9))
+		} else {
+			diskMap[ix] = byte('0' + rng.IntN(10))
+		}
+	}
+	return string(diskMap)
+}
+
+// A random landscape with hiking trails laid over it - climbing
+// from 0 to 9 a step at a time.
+func generateDay10(rng *rand.Rand, size int) string {
+	grid := newGrid[byte](size, size)
+	for pos := range grid.All() {
+		grid.Set(pos, byte('0'+rng.IntN(10)))
+	}
+	for range size * size / 40 {
+		pos := gridPos{rng.IntN(size), rng.IntN(size)}
+		grid.Set(pos, '0')
+		for height := byte('1'); height <= '9'; height++ {
+			// Don't double back, which would undo the trail so far.
+			var options []gridPos
+			for next := range grid.Neighbours(pos) {
+				if grid.At(next) != height-1 && grid.At(next) != height-2 {
+					options = append(options, next)
+				}
+			}
+			if len(options) == 0 {
+				break
+			}
+			pos = options[rng.IntN(len(options))]
+			grid.Set(pos, height)
+		}
+	}
+	return gridText(grid)
+}
+
+// Stones with a mix of single digits and bigger numbers.
+func generateDay11(rng *rand.Rand, size int) string {
+	stones := make([]int, size)
+	for ix := range stones {
+		if rng.IntN(3) == 0 {
+			stones[ix] = rng.IntN(10)
+		} else {
+			stones[ix] = rng.IntN(1000000)
+		}
+	}
+	return joinInts(stones, " ")
+}
+
+// A garden of irregular regions, grown outwards from random spots.
+// Different regions can have the same letter, and sometimes touch.
+func generateDay12(rng *rand.Rand, size int) string {
+	grid := newGrid[byte](size, size)
+	var growing []gridPos
+	for range max(1, size*size/40) {
+		pos := gridPos{rng.IntN(size), rng.IntN(size)}
+		grid.Set(pos, byte('A'+rng.IntN(26)))
+		growing = append(growing, pos)
+	}
+	for len(growing) > 0 {
+		ix := rng.IntN(len(growing))
+		pos := growing[ix]
+		growing[ix] = growing[len(growing)-1]
+		growing = growing[:len(growing)-1]
+		for adj := range grid.Neighbours(pos) {
+			if grid.At(adj) == 0 {
+				grid.Set(adj, grid.At(pos))
+				growing = append(growing, adj)
+			}
+		}
+	}
+	return gridText(grid)
+}
+
+// Claw machines whose buttons never move the claw in the same
+// direction. About a third can win their prize in part 1, and about
+// a third in part 2.
+func generateDay13(rng *rand.Rand, size int) string {
+	const part2Offset = 10000000000000
+	machines := make([]string, size)
+	for ix := range machines {
+		var a, b gridPos
+		for a.row*b.col == a.col*b.row {
+			a = gridPos{10 + rng.IntN(90), 10 + rng.IntN(90)}
+			b = gridPos{10 + rng.IntN(90), 10 + rng.IntN(90)}
+		}
+		prize := gridPos{1000 + rng.IntN(19000), 1000 + rng.IntN(19000)}
+		switch rng.IntN(3) {
+		case 0:
+			aPresses, bPresses := 1+rng.IntN(100), 1+rng.IntN(100)
+			prize = gridPos{aPresses*a.row + bPresses*b.row, aPresses*a.col + bPresses*b.col}
+		case 1:
+			// Solve for how many presses it takes to get near the
+			// part 2 prize, round them off, and put the prize
+			// wherever that lands. That only works out if one
+			// button moves further in X than Y and the other the
+			// other way round; otherwise, leave it unwinnable.
+			det := float64(a.row*b.col - a.col*b.row)
+			target := gridPos{part2Offset + prize.row, part2Offset + prize.col}
+			aPresses := int((float64(target.row)*float64(b.col) - float64(target.col)*float64(b.row)) / det)
+			bPresses := int((float64(target.col)*float64(a.row) - float64(target.row)*float64(a.col)) / det)
+			landed := gridPos{aPresses*a.row + bPresses*b.row - part2Offset, aPresses*a.col + bPresses*b.col - part2Offset}
+			if aPresses >= 0 && bPresses >= 0 && landed.row > 0 && landed.col > 0 {
+				prize = landed
+			}
+		}
+		machines[ix] = fmt.Sprintf("Button A: X+%d, Y+%d\nButton B: X+%d, Y+%d\nPrize: X=%d, Y=%d", a.row, a.col, b.row, b.col, prize.row, prize.col)
+	}
+	return strings.Join(machines, "\n\n")
+}
+
+// Robots in the default 101x103 area, most of which will gather into
+// a picture (well, a blob) at some random time, with the rest just
+// milling around.
+func generateDay14(rng *rand.Rand, size int) string {
+	width, height := d14Width.Default, d14Height.Default
+	pictureTime := 1 + rng.IntN(width*height-1)
+	pictureRow, pictureCol := rng.IntN(height-10), rng.IntN(width-10)
+	lines := make([]string, max(size, 1))
+	for ix := range lines {
+		var pos gridPos
+		vector := gridPos{rng.IntN(199) - 99, rng.IntN(199) - 99}
+		if ix%4 == 3 {
+			pos = gridPos{rng.IntN(height), rng.IntN(width)}
+		} else {
+			// Work backwards from where it'll be in the picture.
+			pos = gridPos{pictureRow + rng.IntN(10), pictureCol + rng.IntN(10)}
+			pos.row = ((pos.row-vector.row*pictureTime)%height + height) % height
+			pos.col = ((pos.col-vector.col*pictureTime)%width + width) % width
+		}
+		lines[ix] = fmt.Sprintf("p=%d,%d v=%d,%d", pos.col, pos.row, vector.col, vector.row)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// A walled warehouse full of boxes and odd bits of wall, and a long
+// list of moves for the robot, in lines of up to 1000.
+func generateDay15(rng *rand.Rand, size int) string {
+	size = max(size, 3)
+	grid := newGrid[byte](size, size)
+	for pos := range grid.All() {
+		switch {
+		case pos.row == 0 || pos.col == 0 || pos.row == size-1 || pos.col == size-1 || rng.IntN(12) == 0:
+			grid.Set(pos, '#')
+		case rng.IntN(3) == 0:
+			grid.Set(pos, 'O')
+		default:
+			grid.Set(pos, '.')
+		}
+	}
+	grid.Set(gridPos{1 + rng.IntN(size-2), 1 + rng.IntN(size-2)}, '@')
+
+	var moves strings.Builder
+	for ix := range 8 * size * size {
+		if ix > 0 && ix%1000 == 0 {
+			moves.WriteByte('\n')
+		}
+		moves.WriteByte("^>v<"[rng.IntN(4)])
+	}
+	return gridText(grid) + "\n\n" + moves.String()
+}
+
+// A maze, with the start in the bottom left and the end in the top
+// right as in the real ones, and a few walls knocked through so that
+// there's more than one way round.
+func generateDay16(rng *rand.Rand, size int) string {
+	grid := carveMaze(rng, size)
+	size = grid.NumRows()
+	for range size * size / 50 {
+		pos := gridPos{1 + rng.IntN(size-2), 1 + rng.IntN(size-2)}
+		if pos.row%2 != pos.col%2 {
+			// Between two squares of the maze, so this joins
+			// them.
+			grid.Set(pos, '.')
+		}
+	}
+	grid.Set(gridPos{size - 2, 1}, 'S')
+	grid.Set(gridPos{1, size - 2}, 'E')
+	return gridText(grid)
+}
+
+// Programs of the same shape as the real ones: each time round the
+// loop, work something out from the bottom few bits of A, output it
+// and shift A right by three bits, until A runs out. size is how many
+// steps go into working out what to output - at least one, as with
+// none, there's never an A that makes the program output itself.
+// That has to be possible for part 2, so we keep trying until it is.
+// It also can't be more than six, as beyond that the program's too
+// long for the A that outputs it to fit in an int.
+func generateDay17(rng *rand.Rand, size int) string {
+	size = min(size, 6)
+	for {
+		// B gets set from A first; after that, anything goes, except
+		// that C has to be set before it's used.
+		body := [][2]int{{INS_BST, 4}}
+		cSet := false
+		for range max(size, 1) {
+			switch rng.IntN(3) {
+			case 0:
+				body = append(body, [2]int{INS_BXL, rng.IntN(8)})
+			case 1:
+				body = append(body, [2]int{INS_CDV, 5})
+				cSet = true
+			default:
+				if cSet {
+					body = append(body, [2]int{INS_BXC, rng.IntN(8)})
+				} else {
+					body = append(body, [2]int{INS_BXL, rng.IntN(8)})
+				}
+			}
+		}
+		body = append(body, [2]int{INS_OUT, 5})
+		body = slices.Insert(body, 1+rng.IntN(len(body)), [2]int{INS_ADV, 3})
+		body = append(body, [2]int{INS_JNZ, 0})
+
+		prog := d17Program{data: make([]int, 0, 2*len(body))}
+		for _, instruction := range body {
+			prog.data = append(prog.data, instruction[0], instruction[1])
+		}
+		if _, found, _, _ := prog.findSelfReplicatingA(newCancelChecker(context.Background())); !found {
+			continue
+		}
+		a := 1 + rng.IntN(1<<min(3*len(prog.data), 62)-1)
+		return fmt.Sprintf("Register A: %d\nRegister B: 0\nRegister C: 0\n\nProgram: %s", a, joinInts(prog.data, ","))
+	}
+}
+
+// Bytes falling into the default-sized memory space, all in
+// different places. There's still a way out once the first lot have
+// fallen, for part 1, and the way out does get blocked eventually,
+// for part 2 - if that takes more than size bytes, there are more.
+func generateDay18(rng *rand.Rand, size int) string {
+	gridSize, startAfter := d18GridSize.Default, d18StartAfter.Default
+	exit := gridPos{gridSize - 1, gridSize - 1}
+	for {
+		bytes := make([]gridPos, 0, gridSize*gridSize)
+		for _, ix := range rng.Perm(gridSize * gridSize) {
+			if pos := (gridPos{ix / gridSize, ix % gridSize}); pos != (gridPos{}) && pos != exit {
+				bytes = append(bytes, pos)
+			}
+		}
+
+		// Find the first byte that blocks the way out.
+		blocked := sort.Search(len(bytes), func(n int) bool {
+			grid := newGrid[bool](gridSize, gridSize)
+			for _, pos := range bytes[:n+1] {
+				grid.Set(pos, true)
+			}
+			return !gridPathExists(grid, gridPos{}, exit)
+		})
+		if blocked < startAfter {
+			continue
+		}
+
+		lines := make([]string, max(size, startAfter, blocked+1))
+		for ix := range lines {
+			lines[ix] = fmt.Sprintf("%d,%d", bytes[ix].col, bytes[ix].row)
+		}
+		return strings.Join(lines, "\n")
+	}
+}
+
+// Towels, and designs made from them - plus some that can't be. Only
+// some of the colours come as towels by themselves, and most of the
+// towels are longer, so that the number of ways to make each design
+// stays about as big as in the real inputs rather than running into
+// the billions of billions.
+func generateDay19(rng *rand.Rand, size int) string {
+	const colours = "wubrg"
+	randomStripes := func(length int) string {
+		stripes := make([]byte, length)
+		for ix := range stripes {
+			stripes[ix] = colours[rng.IntN(len(colours))]
+		}
+		return string(stripes)
+	}
+	towelSet := make(map[string]bool)
+	for _, ix := range rng.Perm(len(colours))[:2] {
+		towelSet[colours[ix:ix+1]] = true
+	}
+	for range 5 {
+		towelSet[randomStripes(2)] = true
+	}
+	for len(towelSet) < max(size, 20) {
+		towelSet[randomStripes(3+rng.IntN(6))] = true
+	}
+	towels := slices.Sorted(maps.Keys(towelSet))
+	rng.Shuffle(len(towels), func(i int, j int) { towels[i], towels[j] = towels[j], towels[i] })
+
+	designs := make([]string, size)
+	for ix := range designs {
+		length := 20 + rng.IntN(41)
+		if rng.IntN(2) == 0 {
+			designs[ix] = randomStripes(length)
+			continue
+		}
+		var design strings.Builder
+		for design.Len() < length {
+			design.WriteString(towels[rng.IntN(len(towels))])
+		}
+		designs[ix] = design.String()
+	}
+	return strings.Join(towels, ", ") + "\n\n" + strings.Join(designs, "\n")
+}
+
+// A racetrack: the one path through a maze from start to end, with
+// everything else walled off. Shortcuts come from where the track
+// doubles back on itself on the other side of a wall.
+func generateDay20(rng *rand.Rand, size int) string {
+	maze := carveMaze(rng, size)
+	size = maze.NumRows()
+	start, end := gridPos{size - 2, 1}, gridPos{1, size - 2}
+
+	// Find the path through the maze, and keep only that.
+	cameFrom := map[gridPos]gridPos{start: start}
+	queue := []gridPos{start}
+	for len(queue) > 0 && queue[0] != end {
+		pos := queue[0]
+		queue = queue[1:]
+		for adj := range maze.Neighbours(pos) {
+			if _, seen := cameFrom[adj]; !seen && maze.At(adj) == '.' {
+				cameFrom[adj] = pos
+				queue = append(queue, adj)
+			}
+		}
+	}
+	track := newGrid[byte](size, size)
+	for pos := range track.All() {
+		track.Set(pos, '#')
+	}
+	for pos := end; pos != start; pos = cameFrom[pos] {
+		track.Set(pos, '.')
+	}
+	track.Set(start, 'S')
+	track.Set(end, 'E')
+	return gridText(track)
+}
+
+// Codes of three digits and an A.
+func generateDay21(rng *rand.Rand, size int) string {
+	codes := make([]string, size)
+	for ix := range codes {
+		codes[ix] = fmt.Sprintf("%03dA", rng.IntN(1000))
+	}
+	return strings.Join(codes, "\n")
+}
+
+// Buyers' initial secret numbers, which are 24 bits at most.
+func generateDay22(rng *rand.Rand, size int) string {
+	secrets := make([]int, size)
+	for ix := range secrets {
+		secrets[ix] = 1 + rng.IntN(1<<24-1)
+	}
+	return joinInts(secrets, "\n")
+}
+
+// A network where each computer is connected to about thirteen
+// others at random, apart from thirteen that are all connected to
+// each other - the LAN party, as the real inputs have exactly one
+// biggest set. At random, it's very unlikely that any other set of
+// computers all connected to each other gets much past four.
+func generateDay23(rng *rand.Rand, size int) string {
+	size = min(max(size, 2), 26*26)
+	names := make([]string, 0, size)
+	for _, ix := range rng.Perm(26 * 26)[:size] {
+		names = append(names, string([]byte{byte('a' + ix/26), byte('a' + ix%26)}))
+	}
+	connected := make(map[intPair]bool)
+	connect := func(one int, two int) {
+		connected[intPair{min(one, two), max(one, two)}] = true
+	}
+	party := min(size, 13)
+	for one := range party {
+		for two := one + 1; two < party; two++ {
+			connect(one, two)
+		}
+	}
+	for range size * 13 / 2 {
+		if one, two := rng.IntN(size), rng.IntN(size); one != two {
+			connect(one, two)
+		}
+	}
+
+	lines := make([]string, 0, len(connected))
+	for c := range connected {
+		if rng.IntN(2) == 0 {
+			c.one, c.two = c.two, c.one
+		}
+		lines = append(lines, names[c.one]+"-"+names[c.two])
+	}
+	// Map order isn't random enough to rely on, or repeatable
+	// enough for --seed.
+	slices.Sort(lines)
+	rng.Shuffle(len(lines), func(i int, j int) { lines[i], lines[j] = lines[j], lines[i] })
+	return strings.Join(lines, "\n")
+}
+
+// A ripple-carry adder for numbers of the given number of bits, with
+// the outputs of some pairs of gates swapped. As in the real inputs,
+// each pair is within one bit's part of the adder, and no bit has
+// more than one pair. Nothing in the first bit's half-adder gets
+// swapped, as Day 24 assumes the problem isn't there, and the swaps
+// never create a loop.
+func generateDay24(rng *rand.Rand, bits int, swappedPairs int) string {
+	bits = max(bits, 2, swappedPairs+1)
+	names := make(map[string]bool)
+	newName := func() string {
+		for {
+			name := string([]byte{byte('a' + rng.IntN(23)), byte('a' + rng.IntN(26)), byte('a' + rng.IntN(26))})
+			if !names[name] {
+				names[name] = true
+				return name
+			}
+		}
+	}
+	type gate struct {
+		in1, op, in2, out string
+	}
+	var gates []gate
+	add := func(in1 string, op string, in2 string, out string) {
+		if rng.IntN(2) == 0 {
+			in1, in2 = in2, in1
+		}
+		gates = append(gates, gate{in1, op, in2, out})
+	}
+
+	carry := newName()
+	add("x00", "XOR", "y00", "z00")
+	add("x00", "AND", "y00", carry)
+	for bit := 1; bit < bits; bit++ {
+		x, y, z := fmt.Sprintf("x%02d", bit), fmt.Sprintf("y%02d", bit), fmt.Sprintf("z%02d", bit)
+		sum, both, carried := newName(), newName(), newName()
+		nextCarry := newName()
+		if bit == bits-1 {
+			nextCarry = fmt.Sprintf("z%02d", bits)
+		}
+		add(x, "XOR", y, sum)
+		add(x, "AND", y, both)
+		add(sum, "XOR", carry, z)
+		add(sum, "AND", carry, carried)
+		add(both, "OR", carried, nextCarry)
+		carry = nextCarry
+	}
+
+	// Swap outputs, trying again if that makes a loop. Each bit
+	// after the first has five gates.
+	for {
+		swapped := slices.Clone(gates)
+		for _, bit := range rng.Perm(bits - 1)[:swappedPairs] {
+			order := rng.Perm(5)
+			if min(order[0], order[1]) == 1 && max(order[0], order[1]) == 3 {
+				// Both ANDs feed the same OR, so swapping them
+				// wouldn't change anything.
+				order[1] = 0
+			}
+			first, second := &swapped[2+5*bit+order[0]], &swapped[2+5*bit+order[1]]
+			first.out, second.out = second.out, first.out
+		}
+		lines := make([]string, 0, 2*bits+1+len(gates))
+		for _, wire := range "xy" {
+			for bit := range bits {
+				lines = append(lines, fmt.Sprintf("%c%02d: %d", wire, bit, rng.IntN(2)))
+			}
+		}
+		lines = append(lines, "")
+		for _, ix := range rng.Perm(len(swapped)) {
+			g := swapped[ix]
+			lines = append(lines, fmt.Sprintf("%s %s %s -> %s", g.in1, g.op, g.in2, g.out))
+		}
+		input := strings.Join(lines, "\n")
+		if circuit, err := parseD24Input(input); err == nil && circuit.checkForLoops() == nil {
+			return input
+		}
+	}
+}
+
+// Locks and keys, in roughly equal numbers, with pins of any height.
+func generateDay25(rng *rand.Rand, size int) string {
+	schematics := make([]string, size)
+	for ix := range schematics {
+		isALock := rng.IntN(2) == 0
+		grid := newGrid[byte](7, 5)
+		for col := range 5 {
+			height := rng.IntN(6)
+			for row := range 7 {
+				filled := row <= height
+				if !isALock {
+					filled = row >= 6-height
+				}
+				if filled {
+					grid.Set(gridPos{row, col}, '#')
+				} else {
+					grid.Set(gridPos{row, col}, '.')
+				}
+			}
+		}
+		schematics[ix] = gridText(grid)
+	}
+	return strings.Join(schematics, "\n\n")
+}
+
+// A maze of corridors one square wide, with walls all round, carved
+// out by wandering at random and backing up at dead ends - so there's
+// exactly one way between any two squares. The squares are those with
+// odd row and column, so the size is rounded up to be odd.
+func carveMaze(rng *rand.Rand, size int) Grid[byte] {
+	size = max(size|1, 5)
+	grid := newGrid[byte](size, size)
+	for pos := range grid.All() {
+		grid.Set(pos, '#')
+	}
+	start := gridPos{size - 2, 1}
+	grid.Set(start, '.')
+	trail := []gridPos{start}
+	for len(trail) > 0 {
+		pos := trail[len(trail)-1]
+		var options []Direction
+		for _, dir := range ORTHOGONAL_DIRECTIONS {
+			next := pos.move(dir).move(dir)
+			if next.row > 0 && next.col > 0 && next.row < size-1 && next.col < size-1 && grid.At(next) == '#' {
+				options = append(options, dir)
+			}
+		}
+		if len(options) == 0 {
+			trail = trail[:len(trail)-1]
+			continue
+		}
+		dir := options[rng.IntN(len(options))]
+		grid.Set(pos.move(dir), '.')
+		grid.Set(pos.move(dir).move(dir), '.')
+		trail = append(trail, pos.move(dir).move(dir))
+	}
+	return grid
+}
+
+// Whether you can get from one square to another, going round the
+// squares that are set.
+func gridPathExists(grid Grid[bool], from gridPos, to gridPos) bool {
+	seen := newGrid[bool](grid.NumRows(), grid.NumCols())
+	seen.Set(from, true)
+	queue := []gridPos{from}
+	for len(queue) > 0 {
+		pos := queue[0]
+		queue = queue[1:]
+		if pos == to {
+			return true
+		}
+		for adj := range grid.Neighbours(pos) {
+			if !grid.At(adj) && !seen.At(adj) {
+				seen.Set(adj, true)
+				queue = append(queue, adj)
+			}
+		}
+	}
+	return false
+}
+
+func gridText(grid Grid[byte]) string {
+	var text strings.Builder
+	for row := range grid.NumRows() {
+		if row > 0 {
+			text.WriteByte('\n')
+		}
+		for col := range grid.NumCols() {
+			text.WriteByte(grid.At(gridPos{row, col}))
+		}
+	}
+	return text.String()
+}
+
+func joinInts(nums []int, separator string) string {
+	strs := make([]string, len(nums))
+	for ix, num := range nums {
+		strs[ix] = fmt.Sprint(num)
+	}
+	return strings.Join(strs, separator)
+}
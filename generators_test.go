@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"testing"
+	"time"
+)
+
+// Every generated input should be one the days can solve, at any
+// size. The reference tests check the answers for the days that
+// have references; this just checks that nothing goes wrong.
+func TestGenerators(t *testing.T) {
+	for _, day := range allDays {
+		t.Run(fmt.Sprintf("Day%02d", day.DayNumber), func(t *testing.T) {
+			generator, ok := inputGenerators[day.DayNumber]
+			if !ok {
+				t.Fatalf("day %d has no input generator", day.DayNumber)
+			}
+			// Something smaller than a real input, to keep this
+			// quick, but not so small as to go below what the
+			// puzzles need (day 24 has to have room for its
+			// swapped wires, for instance).
+			for _, size := range []int{max(generator.defaultSize/10, 8), generator.defaultSize} {
+				rng := rand.New(rand.NewPCG(1, uint64(day.DayNumber)))
+				input := generator.generate(rng, size)
+
+				ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+				_, part1Context, err := day.runPart1(ctx, quietLogger(), input)
+				if err == nil {
+					_, err = day.runPart2(ctx, quietLogger(), input, part1Context)
+				}
+				cancel()
+				if err != nil {
+					t.Errorf("size %d: %v", size, err)
+				}
+			}
+		})
+	}
+}
+
+// The day 17 generator throws away programs that can't output
+// themselves, which it finds out from the same search as part 2.
+func TestD17FindSelfReplicatingA(t *testing.T) {
+	for _, test := range []struct {
+		program []int
+		answer  int
+		found   bool
+	}{
+		{[]int{0, 3, 5, 4, 3, 0}, 117440, true},
+		// Only ever outputs B, which is always 0.
+		{[]int{0, 3, 5, 5, 3, 0}, 0, false},
+	} {
+		prog := d17Program{data: test.program}
+		answer, found, _, err := prog.findSelfReplicatingA(newCancelChecker(context.Background()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found != test.found || (found && answer != test.answer) {
+			t.Errorf("%v: got %d (found %v), want %d (found %v)", test.program, answer, found, test.answer, test.found)
+		}
+	}
+}
@@ -5,11 +5,10 @@ go 1.23.3
 require (
 	github.com/ThePants999/advent-of-code-go-runner v1.0.0
 	github.com/golang-collections/collections v0.0.0-20130729185459-604e922904d3
-	github.com/mowshon/iterium v1.0.0
 	github.com/orcaman/concurrent-map/v2 v2.0.1
 )
 
 require (
 	github.com/logrusorgru/aurora/v4 v4.0.0 // indirect
-	golang.org/x/exp v0.0.0-20230307190834-24139beb5833 // indirect
+	github.com/stretchr/testify v1.8.2 // indirect
 )
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"iter"
+	"log/slog"
+	"strings"
+)
+
+// A position in a grid. Row 0 is the top, column 0 is the left.
+type gridPos struct {
+	row int
+	col int
+}
+
+func (pos gridPos) Add(other gridPos) gridPos {
+	return gridPos{pos.row + other.row, pos.col + other.col}
+}
+
+func (pos gridPos) Subtract(other gridPos) gridPos {
+	return gridPos{pos.row - other.row, pos.col - other.col}
+}
+
+// So that positions show up properly in logs - the JSON handler
+// can't see unexported fields.
+func (pos gridPos) LogValue() slog.Value {
+	return slog.GroupValue(slog.Int("row", pos.row), slog.Int("col", pos.col))
+}
+
+// A rectangular 2D grid, which most years account for a good
+// third of the puzzles. The squares are stored in one flat slice,
+// row by row, which is friendlier to the cache than a slice of
+// slices and means a grid is cheap to copy around - copies share
+// the same squares.
+type Grid[T any] struct {
+	numRows int
+	numCols int
+	cells   []T
+}
+
+// Create a grid where every square has T's zero value.
+func newGrid[T any](numRows int, numCols int) Grid[T] {
+	return Grid[T]{numRows, numCols, make([]T, numRows*numCols)}
+}
+
+// Parse a grid from its text form, one string per row, converting
+// each square with _parse_. If _parse_ doesn't recognise a
+// character, that's reported as an error at that character.
+func parseGrid[T any](rows []string, parse func(rune) (T, bool)) (Grid[T], error) {
+	grid, _, err := parseGridWithMarkers(rows, "", parse)
+	return grid, err
+}
+
+// As parseGrid, but additionally find each of the characters in
+// _markers_ (e.g. "SE" for a maze's start and end), returning
+// their positions in the same order. Each marker must appear
+// exactly once. _parse_ still gets to convert the marker squares
+// themselves, as they're usually also a space of some kind.
+func parseGridWithMarkers[T any](rows []string, markers string, parse func(rune) (T, bool)) (Grid[T], []gridPos, error) {
+	if err := checkRectangular(rows); err != nil {
+		return Grid[T]{}, nil, err
+	}
+
+	grid := newGrid[T](len(rows), len(rows[0]))
+	found := make([]gridPos, len(markers))
+	for ix := range found {
+		found[ix] = gridPos{-1, -1}
+	}
+	for rowIx, row := range rows {
+		// Puzzle inputs are ASCII, so we can go byte by byte and
+		// know that each one is a column.
+		for colIx := range len(row) {
+			char := rune(row[colIx])
+			if markerIx := strings.IndexRune(markers, char); markerIx != -1 {
+				if first := found[markerIx]; first.row != -1 {
+					return Grid[T]{}, nil, errorAt(rowIx+1, colIx+1, "found a second %q, the first was at line %d, column %d", char, first.row+1, first.col+1)
+				}
+				found[markerIx] = gridPos{rowIx, colIx}
+			}
+			val, ok := parse(char)
+			if !ok {
+				return Grid[T]{}, nil, errorAt(rowIx+1, colIx+1, "unexpected %q in grid", char)
+			}
+			grid.cells[rowIx*grid.numCols+colIx] = val
+		}
+	}
+	for ix, pos := range found {
+		if pos.row == -1 {
+			return Grid[T]{}, nil, errorAt(0, 0, "no %q found in grid", markers[ix])
+		}
+	}
+
+	return grid, found, nil
+}
+
+// For grids of plain characters, where any character goes.
+func anyRune(char rune) (rune, bool) {
+	return char, true
+}
+
+// Check that a grid given as one string per row is non-empty and
+// rectangular, as the grid-based days all assume.
+func checkRectangular(rows []string) error {
+	if len(rows) == 0 || len(rows[0]) == 0 {
+		return errorAt(0, 0, "empty grid")
+	}
+	for rowIx, row := range rows {
+		if len(row) != len(rows[0]) {
+			return errorAt(rowIx+1, 0, "expected %d columns, found %d", len(rows[0]), len(row))
+		}
+	}
+	return nil
+}
+
+// Check that every square around the edge of a (rectangular) grid
+// is _border_. Days that rely on walls to keep them inside the
+// grid use this rather than bounds-checking every move.
+func checkBorder(rows []string, border byte) error {
+	lastRow, lastCol := len(rows)-1, len(rows[0])-1
+	for rowIx, row := range rows {
+		for colIx := 0; colIx <= lastCol; colIx++ {
+			onEdge := rowIx == 0 || rowIx == lastRow || colIx == 0 || colIx == lastCol
+			if onEdge && row[colIx] != border {
+				return errorAt(rowIx+1, colIx+1, "expected %q around the edge of the grid, found %q", border, row[colIx])
+			}
+		}
+	}
+	return nil
+}
+
+func (g Grid[T]) NumRows() int {
+	return g.numRows
+}
+
+func (g Grid[T]) NumCols() int {
+	return g.numCols
+}
+
+func (g Grid[T]) InBounds(pos gridPos) bool {
+	return pos.row >= 0 && pos.row < g.numRows && pos.col >= 0 && pos.col < g.numCols
+}
+
+// Get the contents of a square, which must be in bounds.
+func (g Grid[T]) At(pos gridPos) T {
+	return g.cells[pos.row*g.numCols+pos.col]
+}
+
+// Get the contents of a square, or T's zero value and false if
+// the position is outside the grid.
+func (g Grid[T]) Lookup(pos gridPos) (T, bool) {
+	if !g.InBounds(pos) {
+		var zero T
+		return zero, false
+	}
+	return g.At(pos), true
+}
+
+func (g Grid[T]) Set(pos gridPos, val T) {
+	g.cells[pos.row*g.numCols+pos.col] = val
+}
+
+// A pointer to a square, for grids of structs that get updated
+// in place. The pointer stays valid for the life of the grid.
+func (g Grid[T]) Ptr(pos gridPos) *T {
+	return &g.cells[pos.row*g.numCols+pos.col]
+}
+
+// One row of the grid. This shares storage with the grid, so it's
+// the fastest way of scanning along a row.
+func (g Grid[T]) Row(row int) []T {
+	return g.cells[row*g.numCols : (row+1)*g.numCols]
+}
+
+// Every square in the grid, row by row.
+func (g Grid[T]) All() iter.Seq2[gridPos, T] {
+	return func(yield func(gridPos, T) bool) {
+		for ix, val := range g.cells {
+			if !yield(gridPos{ix / g.numCols, ix % g.numCols}, val) {
+				return
+			}
+		}
+	}
+}
+
+// The in-bounds squares directly above, below, left and right of
+// a position.
+func (g Grid[T]) Neighbours(pos gridPos) iter.Seq[gridPos] {
+	return func(yield func(gridPos) bool) {
+		if pos.row > 0 && !yield(gridPos{pos.row - 1, pos.col}) {
+			return
+		}
+		if pos.row < g.numRows-1 && !yield(gridPos{pos.row + 1, pos.col}) {
+			return
+		}
+		if pos.col > 0 && !yield(gridPos{pos.row, pos.col - 1}) {
+			return
+		}
+		if pos.col < g.numCols-1 {
+			yield(gridPos{pos.row, pos.col + 1})
+		}
+	}
+}
+
+// As Neighbours, but also including the diagonals.
+func (g Grid[T]) AllNeighbours(pos gridPos) iter.Seq[gridPos] {
+	return func(yield func(gridPos) bool) {
+		for rowDelta := -1; rowDelta <= 1; rowDelta++ {
+			for colDelta := -1; colDelta <= 1; colDelta++ {
+				adj := gridPos{pos.row + rowDelta, pos.col + colDelta}
+				if (rowDelta != 0 || colDelta != 0) && g.InBounds(adj) && !yield(adj) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// A new grid that's this one flipped along its top-left to
+// bottom-right diagonal, so rows become columns.
+func (g Grid[T]) Transpose() Grid[T] {
+	transposed := newGrid[T](g.numCols, g.numRows)
+	for pos, val := range g.All() {
+		transposed.Set(gridPos{pos.col, pos.row}, val)
+	}
+	return transposed
+}
+
+// A new grid that's this one turned a quarter turn clockwise, so
+// the left-hand column becomes the top row.
+func (g Grid[T]) RotateClockwise() Grid[T] {
+	rotated := newGrid[T](g.numCols, g.numRows)
+	for pos, val := range g.All() {
+		rotated.Set(gridPos{pos.col, g.numRows - 1 - pos.row}, val)
+	}
+	return rotated
+}
+
+// Turn the grid back into text, one line per row, in the same
+// form as puzzle inputs. Mostly useful when debugging.
+func (g Grid[T]) Render(toRune func(T) rune) string {
+	var sb strings.Builder
+	sb.Grow(g.numRows * (g.numCols + 1))
+	for row := range g.numRows {
+		if row > 0 {
+			sb.WriteByte('\n')
+		}
+		for _, val := range g.Row(row) {
+			sb.WriteRune(toRune(val))
+		}
+	}
+	return sb.String()
+}
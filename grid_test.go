@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var testGridRows = []string{
+	"#S.",
+	"..E",
+}
+
+func TestParseGrid(t *testing.T) {
+	grid, markers, err := parseGridWithMarkers(testGridRows, "ES", anyRune)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if grid.NumRows() != 2 || grid.NumCols() != 3 {
+		t.Errorf("got %dx%d grid, want 2x3", grid.NumRows(), grid.NumCols())
+	}
+	if got := grid.At(gridPos{1, 2}); got != 'E' {
+		t.Errorf("got %q at row 1, column 2, want 'E'", got)
+	}
+	if want := []gridPos{{1, 2}, {0, 1}}; !slices.Equal(markers, want) {
+		t.Errorf("got markers at %v, want %v", markers, want)
+	}
+	if got := grid.Render(func(char rune) rune { return char }); got != strings.Join(testGridRows, "\n") {
+		t.Errorf("rendered as %q", got)
+	}
+}
+
+func TestParseGridErrors(t *testing.T) {
+	onlyWalls := func(char rune) (bool, bool) { return true, char == '#' }
+	tests := []struct {
+		name    string
+		rows    []string
+		markers string
+		parse   func(rune) (bool, bool)
+		line    int
+		column  int
+	}{
+		{"empty", nil, "", onlyWalls, 0, 0},
+		{"ragged", []string{"###", "##"}, "", onlyWalls, 2, 0},
+		{"unexpected character", []string{"###", "#.#"}, "", onlyWalls, 2, 2},
+		{"missing marker", []string{"#"}, "S", onlyWalls, 0, 0},
+		{"duplicate marker", []string{"S.", ".S"}, "S", func(rune) (bool, bool) { return false, true }, 2, 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, _, err := parseGridWithMarkers(test.rows, test.markers, test.parse)
+			var inputErr *inputError
+			if !errors.As(err, &inputErr) {
+				t.Fatalf("expected an inputError, got %v", err)
+			}
+			if inputErr.line != test.line || inputErr.column != test.column {
+				t.Errorf("got error at line %d, column %d (%v), want line %d, column %d", inputErr.line, inputErr.column, err, test.line, test.column)
+			}
+		})
+	}
+}
+
+func TestGridNeighbours(t *testing.T) {
+	grid := newGrid[int](3, 3)
+	tests := []struct {
+		pos        gridPos
+		neighbours int
+		all        int
+	}{
+		{gridPos{0, 0}, 2, 3},
+		{gridPos{0, 1}, 3, 5},
+		{gridPos{1, 1}, 4, 8},
+		{gridPos{2, 2}, 2, 3},
+	}
+	for _, test := range tests {
+		neighbours := slices.Collect(grid.Neighbours(test.pos))
+		if len(neighbours) != test.neighbours {
+			t.Errorf("%v has neighbours %v, want %d of them", test.pos, neighbours, test.neighbours)
+		}
+		all := slices.Collect(grid.AllNeighbours(test.pos))
+		if len(all) != test.all {
+			t.Errorf("%v has neighbours %v including diagonals, want %d of them", test.pos, all, test.all)
+		}
+		for _, adj := range all {
+			if !grid.InBounds(adj) || adj == test.pos {
+				t.Errorf("%v has neighbour %v", test.pos, adj)
+			}
+		}
+	}
+
+	if _, found := grid.Lookup(gridPos{-1, 0}); found {
+		t.Errorf("found a square outside the grid")
+	}
+}
+
+func TestGridTransformations(t *testing.T) {
+	grid, err := parseGrid([]string{"abc", "def"}, anyRune)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	render := func(g Grid[rune]) string {
+		return g.Render(func(char rune) rune { return char })
+	}
+
+	if got, want := render(grid.Transpose()), "ad\nbe\ncf"; got != want {
+		t.Errorf("transposed to %q, want %q", got, want)
+	}
+	if got, want := render(grid.RotateClockwise()), "da\neb\nfc"; got != want {
+		t.Errorf("rotated to %q, want %q", got, want)
+	}
+	fullCircle := grid.RotateClockwise().RotateClockwise().RotateClockwise().RotateClockwise()
+	if got, want := render(fullCircle), render(grid); got != want {
+		t.Errorf("four rotations gave %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Some days can draw a picture of what they've worked out - the
+// regions and their fences, the best paths through the maze and so
+// on - for write-ups. As with the visualiser, they find somewhere
+// to put the pictures in their context, and if there isn't one,
+// which is all the time except under the draw command, they don't
+// draw anything.
+//
+// Pictures are drawn on a grid the same size as the puzzle's:
+// every square gets filled with a colour, and then lines can be
+// drawn on top, either along the edges of squares (e.g. fences) or
+// from the middle of one square to the middle of another (e.g.
+// cheats). That's all any of the days need, and it means one
+// picture can be written out as either a PNG or an SVG.
+type gridImage struct {
+	squares Grid[color.RGBA]
+	lines   []imageLine
+}
+
+// A line between two points, measured in squares from the top left
+// corner of the grid.
+type imageLine struct {
+	fromX, fromY float64
+	toX, toY     float64
+	colour       color.RGBA
+}
+
+var (
+	IMAGE_BLACK = color.RGBA{0x00, 0x00, 0x00, 0xff}
+	IMAGE_WHITE = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	IMAGE_WALL  = color.RGBA{0x40, 0x40, 0x48, 0xff}
+	IMAGE_START = color.RGBA{0x20, 0xa0, 0x40, 0xff}
+	IMAGE_END   = color.RGBA{0xd0, 0x20, 0x20, 0xff}
+)
+
+func newGridImage(numRows int, numCols int, background color.RGBA) *gridImage {
+	img := &gridImage{squares: newGrid[color.RGBA](numRows, numCols)}
+	for pos := range img.squares.All() {
+		img.squares.Set(pos, background)
+	}
+	return img
+}
+
+func (img *gridImage) fill(pos gridPos, colour color.RGBA) {
+	img.squares.Set(pos, colour)
+}
+
+// Draw a line along the side of a square.
+func (img *gridImage) edge(pos gridPos, dir Direction, colour color.RGBA) {
+	top, left := float64(pos.row), float64(pos.col)
+	switch dir {
+	case UP:
+		img.lines = append(img.lines, imageLine{left, top, left + 1, top, colour})
+	case RIGHT:
+		img.lines = append(img.lines, imageLine{left + 1, top, left + 1, top + 1, colour})
+	case DOWN:
+		img.lines = append(img.lines, imageLine{left, top + 1, left + 1, top + 1, colour})
+	case LEFT:
+		img.lines = append(img.lines, imageLine{left, top, left, top + 1, colour})
+	}
+}
+
+// Draw a line from the middle of one square to the middle of
+// another.
+func (img *gridImage) link(from gridPos, to gridPos, colour color.RGBA) {
+	img.lines = append(img.lines, imageLine{float64(from.col) + 0.5, float64(from.row) + 0.5, float64(to.col) + 0.5, float64(to.row) + 0.5, colour})
+}
+
+// Write the picture as a PNG, with each square scale pixels across.
+func (img *gridImage) writePNG(w io.Writer, scale int) error {
+	numRows, numCols := img.squares.NumRows(), img.squares.NumCols()
+	out := image.NewRGBA(image.Rect(0, 0, numCols*scale, numRows*scale))
+	for pos, colour := range img.squares.All() {
+		for y := pos.row * scale; y < (pos.row+1)*scale; y++ {
+			for x := pos.col * scale; x < (pos.col+1)*scale; x++ {
+				out.SetRGBA(x, y, colour)
+			}
+		}
+	}
+
+	// There's nothing in the standard library for drawing lines, so
+	// we stamp a little square of pixels every half pixel or so
+	// along each one. Crude, but they're all either straight or
+	// short.
+	thickness := max(scale/5, 1)
+	for _, line := range img.lines {
+		dx, dy := line.toX-line.fromX, line.toY-line.fromY
+		steps := int(math.Ceil(math.Hypot(dx, dy)*float64(scale)*2)) + 1
+		for step := range steps + 1 {
+			fraction := float64(step) / float64(steps)
+			centreX := int(math.Round((line.fromX + dx*fraction) * float64(scale)))
+			centreY := int(math.Round((line.fromY + dy*fraction) * float64(scale)))
+			for y := centreY - thickness/2; y < centreY-thickness/2+thickness; y++ {
+				for x := centreX - thickness/2; x < centreX-thickness/2+thickness; x++ {
+					// Anything off the edge of the image is
+					// ignored by SetRGBA.
+					out.SetRGBA(x, y, line.colour)
+				}
+			}
+		}
+	}
+	return png.Encode(w, out)
+}
+
+// Write the picture as an SVG, with each square scale units across.
+func (img *gridImage) writeSVG(w io.Writer, scale int) error {
+	numRows, numCols := img.squares.NumRows(), img.squares.NumCols()
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n", numCols*scale, numRows*scale, numCols, numRows)
+	// One rectangle per run of squares of the same colour, rather
+	// than one per square, keeps the file down to something a
+	// browser can cope with.
+	fmt.Fprintln(out, `<g shape-rendering="crispEdges">`)
+	for row := range numRows {
+		squares := img.squares.Row(row)
+		for start := 0; start < numCols; {
+			end := start + 1
+			for end < numCols && squares[end] == squares[start] {
+				end++
+			}
+			fmt.Fprintf(out, `<rect x="%d" y="%d" width="%d" height="1" fill="%s"/>`+"\n", start, row, end-start, svgColour(squares[start]))
+			start = end
+		}
+	}
+	fmt.Fprintln(out, `</g>`)
+	fmt.Fprintln(out, `<g stroke-width="0.2" stroke-linecap="round">`)
+	for _, line := range img.lines {
+		fmt.Fprintf(out, `<line x1="%g" y1="%g" x2="%g" y2="%g" stroke="%s"/>`+"\n", line.fromX, line.fromY, line.toX, line.toY, svgColour(line.colour))
+	}
+	fmt.Fprintln(out, `</g>`)
+	fmt.Fprintln(out, `</svg>`)
+	return out.Flush()
+}
+
+func svgColour(colour color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", colour.R, colour.G, colour.B)
+}
+
+// The ix'th of an endless list of colours, each as different from
+// the ones just before it as we can manage, for telling things
+// apart. The hue goes round by the golden ratio each time, which
+// never quite comes back to where it started.
+func paletteColour(ix int) color.RGBA {
+	hue := math.Mod(float64(ix)*0.618033988749895, 1) * 6
+	// Pastel colours, so that lines drawn on top stand out.
+	const saturation, value = 0.45, 0.95
+	chroma := saturation * value
+	x := chroma * (1 - math.Abs(math.Mod(hue, 2)-1))
+	var r, g, b float64
+	switch int(hue) {
+	case 0:
+		r, g, b = chroma, x, 0
+	case 1:
+		r, g, b = x, chroma, 0
+	case 2:
+		r, g, b = 0, chroma, x
+	case 3:
+		r, g, b = 0, x, chroma
+	case 4:
+		r, g, b = x, 0, chroma
+	default:
+		r, g, b = chroma, 0, x
+	}
+	base := value - chroma
+	return color.RGBA{uint8((r + base) * 255), uint8((g + base) * 255), uint8((b + base) * 255), 0xff}
+}
+
+// Somewhere for days to put their pictures, named so that a day can
+// draw more than one.
+type imageRecorder struct {
+	names  []string
+	images map[string]*gridImage
+}
+
+var drawnDays = []int{12, 14, 16, 20}
+
+type imageRecorderKey struct{}
+
+func newImageRecorder() *imageRecorder {
+	return &imageRecorder{images: make(map[string]*gridImage)}
+}
+
+func withImageRecorder(ctx context.Context, images *imageRecorder) context.Context {
+	return context.WithValue(ctx, imageRecorderKey{}, images)
+}
+
+// Where to put pictures, or nil if nobody wants them. Days should
+// check for nil before drawing anything.
+func imageRecorderFrom(ctx context.Context) *imageRecorder {
+	images, _ := ctx.Value(imageRecorderKey{}).(*imageRecorder)
+	return images
+}
+
+func (images *imageRecorder) add(name string, img *gridImage) {
+	if _, found := images.images[name]; !found {
+		images.names = append(images.names, name)
+	}
+	images.images[name] = img
+}
+
+const (
+	IMAGE_FORMAT_PNG = "png"
+	IMAGE_FORMAT_SVG = "svg"
+)
+
+// The draw command: run one day, and write out whatever pictures it
+// draws.
+func runDraw(args []string) error {
+	var dayNumber, scale int
+	var inputPath, format, outDir string
+	var example bool
+	overrides := make(map[*dayParameter]int)
+	flags := flag.NewFlagSet(os.Args[0]+" draw", flag.ContinueOnError)
+	flags.IntVar(&dayNumber, "d", 0, "")
+	flags.IntVar(&dayNumber, "day", 0, "")
+	flags.StringVar(&inputPath, "i", "", "")
+	flags.StringVar(&inputPath, "input", "", "")
+	flags.BoolVar(&example, "example", false, "")
+	flags.StringVar(&format, "f", IMAGE_FORMAT_PNG, "")
+	flags.StringVar(&format, "format", IMAGE_FORMAT_PNG, "")
+	flags.IntVar(&scale, "scale", 10, "")
+	flags.StringVar(&outDir, "outDir", ".", "")
+	flags.Func("param", "", func(arg string) error {
+		return parseParameterOverride(overrides, arg)
+	})
+	flags.Usage = printDrawUsage
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var writeImage func(*gridImage, io.Writer, int) error
+	switch format {
+	case IMAGE_FORMAT_PNG:
+		writeImage = (*gridImage).writePNG
+	case IMAGE_FORMAT_SVG:
+		writeImage = (*gridImage).writeSVG
+	default:
+		return fmt.Errorf("there's no %q format - use png or svg", format)
+	}
+	switch {
+	case flags.NArg() > 0:
+		return fmt.Errorf("unexpected %q", flags.Arg(0))
+	case !slices.Contains(drawnDays, dayNumber):
+		return fmt.Errorf("day %d doesn't draw any pictures", dayNumber)
+	case scale < 1:
+		return errors.New("--scale must be at least 1")
+	}
+	day := allDays[dayNumber-1]
+	input, err := chooseInput(day, inputPath, example)
+	if err != nil {
+		return err
+	}
+
+	images := newImageRecorder()
+	ctx := withImageRecorder(withParameterOverrides(context.Background(), overrides), images)
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	part1Result, part1Context, err := day.runPart1(ctx, logger, input)
+	if err != nil {
+		return err
+	}
+	part2Result, err := day.runPart2(ctx, logger, input, part1Context)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Part 1: %s\nPart 2: %s\n", part1Result, part2Result)
+
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return err
+	}
+	for _, name := range images.names {
+		path := filepath.Join(outDir, fmt.Sprintf("day%02d-%s.%s", dayNumber, name, format))
+		file, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		err = writeImage(images.images[name], file, scale)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("couldn't write %s: %w", path, err)
+		}
+		fmt.Printf("Wrote %s\n", path)
+	}
+	return nil
+}
+
+const DRAW_USAGE_TEXT = `Usage: %s draw -d <day> [options]
+
+Run a day and write out pictures of what it worked out, as PNGs or
+SVGs. Days %s draw pictures.
+
+Options:
+  -d, --day      Which day to draw
+  -i, --input    Use the input in this file, rather than the one in %s
+      --example  Use the day's example input
+  -f, --format   png (the default) or svg
+      --scale    How many pixels across each square of the grid is (default 10)
+      --outDir   Where to write the pictures, named dayNN-name.png (default .)
+      --param    Set one of the day's parameters, as when running days
+`
+
+func printDrawUsage() {
+	days := make([]string, len(drawnDays))
+	for ix, day := range drawnDays {
+		days[ix] = fmt.Sprint(day)
+	}
+	fmt.Printf(DRAW_USAGE_TEXT, os.Args[0], strings.Join(days, ", "), INPUT_DIRNAME)
+}
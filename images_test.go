@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image/color"
+	"image/png"
+	"math/rand/v2"
+	"strings"
+	"testing"
+)
+
+func testImage() *gridImage {
+	img := newGridImage(2, 3, IMAGE_WHITE)
+	img.fill(gridPos{0, 0}, IMAGE_START)
+	img.fill(gridPos{0, 1}, IMAGE_START)
+	img.edge(gridPos{1, 2}, DOWN, IMAGE_BLACK)
+	img.link(gridPos{0, 0}, gridPos{1, 2}, IMAGE_END)
+	return img
+}
+
+func TestGridImagePNG(t *testing.T) {
+	var out bytes.Buffer
+	if err := testImage().writePNG(&out, 10); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := png.Decode(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := decoded.Bounds().Size(); size.X != 30 || size.Y != 20 {
+		t.Fatalf("got a %dx%d image, want 30x20", size.X, size.Y)
+	}
+	for _, check := range []struct {
+		x, y int
+		want color.RGBA
+	}{
+		{12, 2, IMAGE_START},
+		{2, 18, IMAGE_WHITE},
+		{25, 19, IMAGE_BLACK},
+		{5, 5, IMAGE_END},
+		{25, 15, IMAGE_END},
+	} {
+		if got := color.RGBAModel.Convert(decoded.At(check.x, check.y)); got != check.want {
+			t.Errorf("pixel %d,%d is %v, want %v", check.x, check.y, got, check.want)
+		}
+	}
+}
+
+func TestGridImageSVG(t *testing.T) {
+	var out bytes.Buffer
+	if err := testImage().writeSVG(&out, 10); err != nil {
+		t.Fatal(err)
+	}
+	svg := out.String()
+	// The top row is two green squares and a white one, and the
+	// bottom row is all white.
+	if rects := strings.Count(svg, "<rect"); rects != 3 {
+		t.Errorf("got %d rectangles, want 3:\n%s", rects, svg)
+	}
+	for _, want := range []string{
+		`width="30" height="20" viewBox="0 0 3 2"`,
+		`<rect x="0" y="0" width="2" height="1" fill="#20a040"/>`,
+		`<line x1="2" y1="2" x2="3" y2="2" stroke="#000000"/>`,
+		`<line x1="0.5" y1="0.5" x2="2.5" y2="1.5" stroke="#d02020"/>`,
+	} {
+		if !strings.Contains(svg, want) {
+			t.Errorf("expected %s in:\n%s", want, svg)
+		}
+	}
+}
+
+// Drawing pictures shouldn't change a day's answers.
+func TestDrawnDays(t *testing.T) {
+	for _, dayNumber := range drawnDays {
+		t.Run(fmt.Sprintf("Day%02d", dayNumber), func(t *testing.T) {
+			day := allDays[dayNumber-1]
+			input := day.ExampleInput
+			if input == "" {
+				input = inputGenerators[dayNumber].generate(rand.New(rand.NewPCG(1, uint64(dayNumber))), 50)
+			}
+			want := solveBothParts(t, context.Background(), day, input)
+
+			images := newImageRecorder()
+			got := solveBothParts(t, withImageRecorder(context.Background(), images), day, input)
+			if got != want {
+				t.Errorf("got %v while drawing, want %v", got, want)
+			}
+			if len(images.names) == 0 {
+				t.Fatalf("didn't draw anything")
+			}
+			for _, name := range images.names {
+				if err := images.images[name].writePNG(&bytes.Buffer{}, 2); err != nil {
+					t.Errorf("%s: %v", name, err)
+				}
+			}
+		})
+	}
+}
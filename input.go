@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Inputs can come from the runner (which downloads them), from a
+// directory of files, from a single file or from stdin, and they
+// don't all agree on line endings or whether there's a newline at
+// the end. So before any day sees its input, we tidy it up: CRLF
+// becomes LF, and trailing newlines are stripped. That means the
+// days don't need to worry about any of it themselves.
+func normaliseInput(input string) string {
+	input = strings.ReplaceAll(input, "\r\n", "\n")
+	return strings.TrimRight(input, "\n")
+}
+
+// Where the runner caches inputs, and where we look for them by
+// default. Like the runner, we take this to be relative to the
+// current directory, not to wherever the executable is.
+const INPUT_DIRNAME string = "inputs"
+
+// A directory of puzzle inputs. A day's input can be named either
+// dayNN.txt (e.g. day06.txt), for inputs put there by hand, or just
+// the bare day number, which is how the runner caches what it
+// downloads.
+type inputDir string
+
+func (dir inputDir) inputFileNames(dayNumber int) []string {
+	return []string{
+		filepath.Join(string(dir), fmt.Sprintf("day%02d.txt", dayNumber)),
+		filepath.Join(string(dir), strconv.Itoa(dayNumber)),
+	}
+}
+
+// Read a day's input. If there isn't one, the error wraps
+// fs.ErrNotExist.
+func (dir inputDir) read(dayNumber int) (string, error) {
+	fileNames := dir.inputFileNames(dayNumber)
+	for _, fileName := range fileNames {
+		data, err := os.ReadFile(fileName)
+		if err == nil {
+			return string(data), nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+	}
+	return "", fmt.Errorf("no input for day %d (expected %s): %w", dayNumber, fileNames[0], fs.ErrNotExist)
+}
+
+// Read a single input from a file, or from stdin if the path is "-".
+func readInputFile(path string) (string, error) {
+	if path == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		return string(data), err
+	}
+	data, err := os.ReadFile(path)
+	return string(data), err
+}
+
+// The input for a command that runs a single day: the one in the
+// given file (or stdin, if it's "-"), or the day's example, or
+// failing either, the one in the inputs directory.
+func chooseInput(day puzzle, path string, example bool) (string, error) {
+	switch {
+	case path != "" && example:
+		return "", errors.New("the -i and --example arguments are mutually exclusive")
+	case example && day.ExampleInput == "":
+		return "", fmt.Errorf("day %d has no example", day.DayNumber)
+	case example:
+		return day.ExampleInput, nil
+	case path != "":
+		return readInputFile(path)
+	}
+	return inputDir(INPUT_DIRNAME).read(day.DayNumber)
+}
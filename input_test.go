@@ -0,0 +1,42 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInputDir(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, contents string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Day 6 has both kinds of name, in which case the hand-placed
+	// one wins; day 7 only has what the runner would have cached.
+	write("day06.txt", "by hand")
+	write("6", "downloaded")
+	write("7", "downloaded")
+
+	for _, tc := range []struct {
+		day  int
+		want string
+	}{
+		{6, "by hand"},
+		{7, "downloaded"},
+	} {
+		got, err := inputDir(dir).read(tc.day)
+		if err != nil {
+			t.Errorf("day %d: %v", tc.day, err)
+		} else if got != tc.want {
+			t.Errorf("day %d: got %q, want %q", tc.day, got, tc.want)
+		}
+	}
+
+	if _, err := inputDir(dir).read(8); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("day 8: got %v, want an error wrapping fs.ErrNotExist", err)
+	}
+}
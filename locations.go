@@ -0,0 +1,330 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Day 1 only asks for two numbers about the pair of location ID
+// lists, but there's a lot more you might want to know when
+// reconciling lists like these for real: how far apart the pairs
+// typically are, which IDs only turn up on one side, and which IDs
+// the similarity score is mostly made of. This is all that, built
+// on the same parsing as the puzzle, along with a way of keeping
+// both scores up to date as pairs arrive one at a time.
+
+type d1Stats struct {
+	pairs int
+
+	// The puzzle's two answers.
+	totalDistance   int
+	similarityScore int
+
+	// How far apart each pair is, once both lists are sorted - the
+	// distances that add up to the total distance.
+	distances d1Distribution
+
+	// The distinct IDs found in one list but not the other, in
+	// order.
+	onlyLeft  []int
+	onlyRight []int
+
+	// The IDs contributing most to the similarity score, biggest
+	// first.
+	mostSimilar []d1Similarity
+}
+
+type d1Distribution struct {
+	min, max  int
+	median    float64
+	histogram []d1Bucket
+}
+
+// How many distances are between from and to, inclusive.
+type d1Bucket struct {
+	from, to int
+	count    int
+}
+
+// An ID that's in both lists, how many times it's in each, and how
+// much it adds to the similarity score as a result.
+type d1Similarity struct {
+	id                    int
+	leftCount, rightCount int
+	score                 int
+}
+
+// Work out everything we know about a pair of lists, which are
+// sorted first if they aren't already. topN is how many IDs to
+// report in mostSimilar, and buckets how many buckets the histogram
+// of distances has (at most).
+func analyseD1Lists(list1 []int, list2 []int, topN int, buckets int) d1Stats {
+	if !slices.IsSorted(list1) {
+		list1 = slices.Sorted(slices.Values(list1))
+	}
+	if !slices.IsSorted(list2) {
+		list2 = slices.Sorted(slices.Values(list2))
+	}
+	stats := d1Stats{pairs: len(list1)}
+	if len(list1) == 0 {
+		return stats
+	}
+
+	distances := make([]int, len(list1))
+	for ix := range list1 {
+		distances[ix] = max(list1[ix]-list2[ix], list2[ix]-list1[ix])
+		stats.totalDistance += distances[ix]
+	}
+	stats.distances = d1Distribute(distances, buckets)
+
+	// Both lists being sorted, we can walk through them side by
+	// side, a run of equal IDs at a time, and see for each ID
+	// whether it's in one, the other or both.
+	var similar []d1Similarity
+	for ix1, ix2 := 0, 0; ix1 < len(list1) || ix2 < len(list2); {
+		id := 0
+		switch {
+		case ix2 == len(list2) || (ix1 < len(list1) && list1[ix1] < list2[ix2]):
+			id = list1[ix1]
+		default:
+			id = list2[ix2]
+		}
+		leftCount, rightCount := 0, 0
+		for ; ix1 < len(list1) && list1[ix1] == id; ix1++ {
+			leftCount++
+		}
+		for ; ix2 < len(list2) && list2[ix2] == id; ix2++ {
+			rightCount++
+		}
+		switch {
+		case rightCount == 0:
+			stats.onlyLeft = append(stats.onlyLeft, id)
+		case leftCount == 0:
+			stats.onlyRight = append(stats.onlyRight, id)
+		default:
+			score := id * leftCount * rightCount
+			stats.similarityScore += score
+			similar = append(similar, d1Similarity{id, leftCount, rightCount, score})
+		}
+	}
+	slices.SortStableFunc(similar, func(a d1Similarity, b d1Similarity) int {
+		return b.score - a.score
+	})
+	stats.mostSimilar = similar[:min(topN, len(similar))]
+	return stats
+}
+
+// The spread of a set of distances, with a histogram of up to the
+// given number of equal-width buckets. Sorts the distances.
+func d1Distribute(distances []int, buckets int) d1Distribution {
+	slices.Sort(distances)
+	dist := d1Distribution{min: distances[0], max: distances[len(distances)-1]}
+	middle := len(distances) / 2
+	if len(distances)%2 == 1 {
+		dist.median = float64(distances[middle])
+	} else {
+		dist.median = float64(distances[middle-1]+distances[middle]) / 2
+	}
+
+	if buckets < 1 {
+		return dist
+	}
+	width := (dist.max - dist.min + buckets) / buckets
+	for from := dist.min; from <= dist.max; from += width {
+		dist.histogram = append(dist.histogram, d1Bucket{from: from, to: from + width - 1})
+	}
+	for _, distance := range distances {
+		dist.histogram[(distance-dist.min)/width].count++
+	}
+	return dist
+}
+
+// Both scores for a pair of lists that grow a pair at a time.
+//
+// The similarity score's easy: a new ID on the left adds itself for
+// every time it's already on the right, and vice versa. The total
+// distance is harder, as a new ID can change which pairs get
+// compared with which - but only for the pairs between where the
+// two new IDs go in their sorted lists. Everything before that is
+// where it was, and everything after has just moved up one on both
+// sides. So we keep both lists sorted, and only redo the distances
+// between the two new IDs.
+type d1Stream struct {
+	left, right             []int
+	leftCounts, rightCounts map[int]int
+
+	totalDistance   int
+	similarityScore int
+}
+
+func newD1Stream() *d1Stream {
+	return &d1Stream{leftCounts: make(map[int]int), rightCounts: make(map[int]int)}
+}
+
+func (s *d1Stream) add(left int, right int) {
+	s.similarityScore += left * s.rightCounts[left]
+	s.leftCounts[left]++
+	s.similarityScore += right * s.leftCounts[right]
+	s.rightCounts[right]++
+
+	leftIx, _ := slices.BinarySearch(s.left, left)
+	rightIx, _ := slices.BinarySearch(s.right, right)
+	first, last := min(leftIx, rightIx), max(leftIx, rightIx)
+	for ix := first; ix < last; ix++ {
+		s.totalDistance -= max(s.left[ix]-s.right[ix], s.right[ix]-s.left[ix])
+	}
+	s.left = slices.Insert(s.left, leftIx, left)
+	s.right = slices.Insert(s.right, rightIx, right)
+	for ix := first; ix <= last; ix++ {
+		s.totalDistance += max(s.left[ix]-s.right[ix], s.right[ix]-s.left[ix])
+	}
+}
+
+// The lists command: print everything analyseD1Lists knows about
+// a day 1 input or, with --stream, both scores after every pair.
+func runLists(args []string) error {
+	var inputPath string
+	var example, stream bool
+	var topN, buckets int
+	flags := flag.NewFlagSet(os.Args[0]+" lists", flag.ContinueOnError)
+	flags.StringVar(&inputPath, "i", "", "")
+	flags.StringVar(&inputPath, "input", "", "")
+	flags.BoolVar(&example, "example", false, "")
+	flags.BoolVar(&stream, "stream", false, "")
+	flags.IntVar(&topN, "top", 10, "")
+	flags.IntVar(&buckets, "buckets", 10, "")
+	flags.Usage = printListsUsage
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	switch {
+	case flags.NArg() > 0:
+		return fmt.Errorf("unexpected %q", flags.Arg(0))
+	case topN < 0:
+		return errors.New("--top can't be negative")
+	case buckets < 1:
+		return errors.New("--buckets must be at least 1")
+	}
+
+	if stream {
+		if example {
+			return streamD1Lists(strings.NewReader(Day1.ExampleInput), os.Stdout)
+		}
+		if inputPath == "" || inputPath == "-" {
+			return streamD1Lists(os.Stdin, os.Stdout)
+		}
+		file, err := os.Open(inputPath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		return streamD1Lists(file, os.Stdout)
+	}
+
+	input, err := chooseInput(Day1, inputPath, example)
+	if err != nil {
+		return err
+	}
+	list1, list2, err := parseD1Lists(normaliseInput(input))
+	if err != nil {
+		return locateInputError(err)
+	}
+	printD1Stats(os.Stdout, analyseD1Lists(list1, list2, topN, buckets))
+	return nil
+}
+
+// Read pairs a line at a time, printing both scores after each.
+func streamD1Lists(r io.Reader, w io.Writer) error {
+	s := newD1Stream()
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		left, right, err := parseD1Line(line, lineNum)
+		if err != nil {
+			return locateInputError(err)
+		}
+		s.add(left, right)
+		fmt.Fprintf(w, "%d pairs: total distance %d, similarity score %d\n", len(s.left), s.totalDistance, s.similarityScore)
+	}
+	return scanner.Err()
+}
+
+// Say whereabouts in the input a parsing error is, as there's no day
+// and part for a DayError to do it for us.
+func locateInputError(err error) error {
+	var inputErr *inputError
+	if errors.As(err, &inputErr) && inputErr.line > 0 {
+		return fmt.Errorf("line %d: %w", inputErr.line, err)
+	}
+	return err
+}
+
+func printD1Stats(w io.Writer, stats d1Stats) {
+	fmt.Fprintf(w, "Pairs: %d\n", stats.pairs)
+	fmt.Fprintf(w, "Total distance: %d\n", stats.totalDistance)
+	fmt.Fprintf(w, "Similarity score: %d\n", stats.similarityScore)
+	if stats.pairs == 0 {
+		return
+	}
+
+	dist := stats.distances
+	fmt.Fprintf(w, "\nDistances: min %d, median %g, max %d\n", dist.min, dist.median, dist.max)
+	mostInBucket := 0
+	for _, bucket := range dist.histogram {
+		mostInBucket = max(mostInBucket, bucket.count)
+	}
+	for _, bucket := range dist.histogram {
+		fmt.Fprintf(w, "  %8d - %-8d %6d %s\n", bucket.from, bucket.to, bucket.count, strings.Repeat("#", bucket.count*40/mostInBucket))
+	}
+
+	fmt.Fprintf(w, "\nOnly in the left list: %s\n", summariseIDs(stats.onlyLeft))
+	fmt.Fprintf(w, "Only in the right list: %s\n", summariseIDs(stats.onlyRight))
+
+	fmt.Fprintf(w, "\nMost similar:\n  %8s %6s %6s %10s\n", "ID", "Left", "Right", "Score")
+	for _, similar := range stats.mostSimilar {
+		fmt.Fprintf(w, "  %8d %6d %6d %10d\n", similar.id, similar.leftCount, similar.rightCount, similar.score)
+	}
+}
+
+// A list of IDs, or the start of it if it's long.
+func summariseIDs(ids []int) string {
+	const MAX_IDS = 10
+	summary := fmt.Sprintf("%d IDs", len(ids))
+	if len(ids) > 0 {
+		summary += " (" + joinInts(ids[:min(len(ids), MAX_IDS)], ", ")
+		if len(ids) > MAX_IDS {
+			summary += ", ..."
+		}
+		summary += ")"
+	}
+	return summary
+}
+
+const LISTS_USAGE_TEXT = `Usage: %s lists [options]
+
+Analyse a pair of location ID lists, in the same form as day 1's
+input, beyond the puzzle's two answers.
+
+Options:
+  -i, --input    Use the lists in this file (or stdin, if "-"), rather than day 1's
+                 input in %s
+      --example  Use day 1's example input
+      --top      How many of the IDs adding most to the similarity score to list
+                 (default 10)
+      --buckets  How many buckets the histogram of distances has, at most (default 10)
+      --stream   Read pairs a line at a time (from stdin, unless -i says otherwise),
+                 printing both scores after each
+`
+
+func printListsUsage() {
+	fmt.Printf(LISTS_USAGE_TEXT, os.Args[0], INPUT_DIRNAME)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"math/rand/v2"
+	"reflect"
+	"testing"
+)
+
+func TestAnalyseD1Lists(t *testing.T) {
+	list1, list2, err := parseD1Lists(Day1.ExampleInput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := analyseD1Lists(list1, list2, 1, 3)
+	want := d1Stats{
+		pairs:           6,
+		totalDistance:   11,
+		similarityScore: 31,
+		distances: d1Distribution{
+			min:       0,
+			max:       5,
+			median:    1.5,
+			histogram: []d1Bucket{{0, 1, 3}, {2, 3, 2}, {4, 5, 1}},
+		},
+		onlyLeft:    []int{1, 2},
+		onlyRight:   []int{5, 9},
+		mostSimilar: []d1Similarity{{3, 3, 3, 27}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+}
+
+// However the pairs arrive, the stream's scores should always be
+// the same as analysing all the pairs so far from scratch.
+func TestD1Stream(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 1))
+	s := newD1Stream()
+	var list1, list2 []int
+	for range 500 {
+		left, right := rng.IntN(100), rng.IntN(100)
+		s.add(left, right)
+		list1, list2 = append(list1, left), append(list2, right)
+
+		want := analyseD1Lists(list1, list2, 0, 1)
+		if s.totalDistance != want.totalDistance || s.similarityScore != want.similarityScore {
+			t.Fatalf("after %d pairs, got %d and %d, want %d and %d", len(list1), s.totalDistance, s.similarityScore, want.totalDistance, want.similarityScore)
+		}
+	}
+}
@@ -1,42 +1,306 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"slices"
+	"time"
 
 	runner "github.com/ThePants999/advent-of-code-go-runner"
 )
 
+// Every day's implementation, in order. This is the list
+// handed to the runner, and also what the tests walk.
+var allDays = []puzzle{
+	Day1,
+	Day2,
+	Day3,
+	Day4,
+	Day5,
+	Day6,
+	Day7,
+	Day8,
+	Day9,
+	Day10,
+	Day11,
+	Day12,
+	Day13,
+	Day14,
+	Day15,
+	Day16,
+	Day17,
+	Day18,
+	Day19,
+	Day20,
+	Day21,
+	Day22,
+	Day23,
+	Day24,
+	Day25}
+
+// The options we understand. The runner parses its own options
+// straight off the command line, so we can't add to its set -
+// instead we parse the command line ourselves first, with the
+// runner's options alongside our own, and then hand the runner
+// only the ones it knows about.
+type options struct {
+	allDays, skipTests, testsOnly, profiling bool
+	day, stats                               int
+
+	// Ours.
+	parallel  bool
+	timeLimit time.Duration
+	logLevel  slog.Level
+	logJSON   bool
+	input     string
+	inputDir  string
+
+	answersPath string
+	saveAnswers bool
+	format      string
+	profileDir  string
+	parameters  map[*dayParameter]int
+	listParams  bool
+
+	// The command line to give the runner.
+	runnerArgs []string
+}
+
+var ourFlags = []string{"j", "parallel", "l", "timeLimit", "v", "logLevel", "logJSON", "i", "input", "inputDir", "answers", "saveAnswers", "f", "format", "profileDir", "param", "listParams"}
+
+func parseOptions(args []string) (options, error) {
+	opts := options{parameters: make(map[*dayParameter]int)}
+	flags := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	flags.BoolVar(&opts.allDays, "a", false, "")
+	flags.BoolVar(&opts.allDays, "allDays", false, "")
+	flags.BoolVar(&opts.skipTests, "k", false, "")
+	flags.BoolVar(&opts.skipTests, "skipTests", false, "")
+	flags.BoolVar(&opts.testsOnly, "t", false, "")
+	flags.BoolVar(&opts.testsOnly, "testsOnly", false, "")
+	flags.BoolVar(&opts.profiling, "p", false, "")
+	flags.BoolVar(&opts.profiling, "profiling", false, "")
+	flags.IntVar(&opts.day, "d", 0, "")
+	flags.IntVar(&opts.day, "day", 0, "")
+	flags.IntVar(&opts.stats, "s", 0, "")
+	flags.IntVar(&opts.stats, "stats", 0, "")
+	flags.BoolVar(&opts.parallel, "j", false, "")
+	flags.BoolVar(&opts.parallel, "parallel", false, "")
+	flags.DurationVar(&opts.timeLimit, "l", 0, "")
+	flags.DurationVar(&opts.timeLimit, "timeLimit", 0, "")
+	flags.TextVar(&opts.logLevel, "v", slog.LevelWarn, "")
+	flags.TextVar(&opts.logLevel, "logLevel", slog.LevelWarn, "")
+	flags.BoolVar(&opts.logJSON, "logJSON", false, "")
+	flags.StringVar(&opts.input, "i", "", "")
+	flags.StringVar(&opts.input, "input", "", "")
+	flags.StringVar(&opts.inputDir, "inputDir", "", "")
+	flags.StringVar(&opts.answersPath, "answers", ANSWERS_FILENAME, "")
+	flags.BoolVar(&opts.saveAnswers, "saveAnswers", false, "")
+	flags.StringVar(&opts.format, "f", FORMAT_TEXT, "")
+	flags.StringVar(&opts.format, "format", FORMAT_TEXT, "")
+	flags.StringVar(&opts.profileDir, "profileDir", "", "")
+	flags.Func("param", "", func(arg string) error {
+		return parseParameterOverride(opts.parameters, arg)
+	})
+	flags.BoolVar(&opts.listParams, "listParams", false, "")
+	flags.Usage = printUsage
+	if err := flags.Parse(args); err != nil {
+		return opts, err
+	}
+
+	flags.Visit(func(f *flag.Flag) {
+		if !slices.Contains(ourFlags, f.Name) {
+			opts.runnerArgs = append(opts.runnerArgs, "-"+f.Name+"="+f.Value.String())
+		}
+	})
+	opts.runnerArgs = append(opts.runnerArgs, flags.Args()...)
+	return opts, nil
+}
+
+const EXTRA_USAGE_TEXT = `Additional options:
+  -j, --parallel   Run all days (or just the -d day) at once, using inputs already on disk
+  -i, --input      Run the -d day against this file (or stdin, if "-") rather than
+                   downloading its input
+      --inputDir   Run all days (or just the -d day) against inputs in this directory,
+                   named dayNN.txt, rather than downloading them
+  -l, --timeLimit  Give up on any day that takes longer than this (e.g. 10s)
+  -v, --logLevel   Log at this level and above: debug, info, warn (the default) or error
+      --logJSON    Log as JSON rather than text
+  -f, --format     Print results as text (the default), or as json or csv with one record
+                   per day and part. Like -j, this uses inputs already on disk
+      --profileDir Write CPU and heap profiles for each part of each day to this
+                   directory, and print allocs/op and peak heap per day. Like -j, this
+                   uses inputs already on disk
+      --param      Set one of a day's parameters, e.g. --param 18.gridSize=50. Can be
+                   given more than once. The examples always use their own values
+      --listParams List the parameters each day has, with their defaults
+      --answers    Check answers against the known answers in this file (default answers.json)
+      --saveAnswers
+                   Record this run's answers in that file as the right ones
+
+To generate random inputs instead, see: %[1]s generate -h
+To watch a day's simulation in the terminal, see: %[1]s visualise -h
+To draw pictures of what a day worked out, see: %[1]s draw -h
+To analyse day 1's location ID lists in more depth, see: %[1]s lists -h
+To find out why day 2's unsafe reports are unsafe, see: %[1]s reports -h
+To scan day 3's corrupted memory a chunk at a time, see: %[1]s memory -h
+`
+
+func printUsage() {
+	fmt.Printf(runner.USAGE_TEXT, os.Args[0])
+	fmt.Printf(EXTRA_USAGE_TEXT, os.Args[0])
+}
+
+// The commands that do something other than run days, by the name
+// given as the first argument, with what to say each couldn't do if
+// it fails.
+var subcommands = map[string]struct {
+	run     func(args []string) error
+	failure string
+}{
+	"generate":  {runGenerate, "generate inputs"},
+	"visualise": {runVisualise, "visualise"},
+	"draw":      {runDraw, "draw pictures"},
+	"lists":     {runLists, "analyse lists"},
+	"reports":   {runReports, "explain reports"},
+	"memory":    {runMemory, "scan memory"},
+}
+
 func main() {
-	lvl := new(slog.LevelVar)
-	lvl.Set(slog.LevelWarn)
-	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: lvl}))
-
-	r := runner.NewRunner(logger, "2024", []runner.DayImplementation{
-		Day1,
-		Day2,
-		Day3,
-		Day4,
-		Day5,
-		Day6,
-		Day7,
-		Day8,
-		Day9,
-		Day10,
-		Day11,
-		Day12,
-		Day13,
-		Day14,
-		Day15,
-		Day16,
-		Day17,
-		Day18,
-		Day19,
-		Day20,
-		Day21,
-		Day22,
-		Day23,
-		Day24,
-		Day25})
-	r.Run()
+	if len(os.Args) > 1 {
+		if command, ok := subcommands[os.Args[1]]; ok {
+			if err := command.run(os.Args[2:]); errors.Is(err, flag.ErrHelp) {
+				return
+			} else if err != nil {
+				fmt.Fprintf(os.Stderr, "Couldn't %s: %v\n", command.failure, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	opts, err := parseOptions(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	} else if err != nil {
+		os.Exit(1)
+	}
+	if opts.listParams {
+		printParameters()
+		return
+	}
+
+	// Everything the days log goes to stderr, so that it doesn't get
+	// mixed up with the results. Debug shows what each day found in
+	// its input and how it went about solving it, which is usually
+	// enough to work out why an answer's wrong.
+	handlerOptions := &slog.HandlerOptions{Level: opts.logLevel}
+	var handler slog.Handler = slog.NewTextHandler(os.Stderr, handlerOptions)
+	if opts.logJSON {
+		handler = slog.NewJSONHandler(os.Stderr, handlerOptions)
+	}
+	logger := slog.New(handler)
+	if opts.timeLimit < 0 {
+		fmt.Println("The -l argument can't be negative.")
+		printUsage()
+		os.Exit(1)
+	}
+
+	answers, err := loadAnswerBook(opts.answersPath, opts.saveAnswers)
+	if err != nil {
+		fmt.Printf("Couldn't load known answers: %v\n", err)
+		os.Exit(1)
+	}
+
+	// When writing machine-readable results, anything else we have
+	// to say goes to stderr, so as not to get in the way.
+	var reportTo io.Writer = os.Stdout
+	if opts.format != FORMAT_TEXT {
+		reportTo = os.Stderr
+	}
+
+	settings := runSettings{
+		logger:     logger,
+		timeLimit:  opts.timeLimit,
+		timeouts:   &timeoutRecord{},
+		answers:    answers,
+		parameters: opts.parameters,
+	}
+	if opts.parallel || opts.input != "" || opts.inputDir != "" || opts.format != FORMAT_TEXT || opts.profileDir != "" {
+		runOfflineMode(settings, opts)
+	} else {
+		days := make([]runner.DayImplementation, len(allDays))
+		for ix, day := range allDays {
+			days[ix] = day.implementation(settings)
+		}
+		r := runner.NewRunner(logger, "2024", days)
+		os.Args = append([]string{os.Args[0]}, opts.runnerArgs...)
+		r.Run()
+	}
+
+	settings.timeouts.report(reportTo)
+	if !settings.answers.report(reportTo) {
+		os.Exit(1)
+	}
+}
+
+// Run days ourselves rather than via the runner, which is what we
+// do whenever the inputs are to come from disk rather than the
+// website - either because we've been asked for something the
+// runner can't do (running days in parallel, machine-readable
+// results or per-day profiles), or because we've been told where the inputs are.
+func runOfflineMode(settings runSettings, opts options) {
+	switch {
+	case opts.skipTests && opts.testsOnly:
+		fmt.Println("The -k and -t arguments are mutually exclusive. Specify one or the other.")
+	case opts.allDays && opts.day > 0:
+		fmt.Println("The -a and -d arguments are mutually exclusive. Specify one or the other.")
+	case opts.input != "" && opts.inputDir != "":
+		fmt.Println("The -i and --inputDir arguments are mutually exclusive. Specify one or the other.")
+	case opts.input != "" && opts.day == 0:
+		fmt.Println("The -i argument needs -d to say which day the input is for.")
+	case opts.format != FORMAT_TEXT && opts.format != FORMAT_JSON && opts.format != FORMAT_CSV:
+		fmt.Printf("There's no %q format. Use text, json or csv.\n", opts.format)
+	case opts.profiling || opts.stats > 0:
+		fmt.Println("The -p and -s arguments can only be used when the runner is fetching inputs, not with -j, -i, --inputDir, -f or --profileDir.")
+	case opts.parallel && opts.profileDir != "":
+		fmt.Println("The -j and --profileDir arguments are mutually exclusive, as days running alongside each other would end up in each other's profiles.")
+	case opts.day < 0 || opts.day > len(allDays):
+		fmt.Printf("There's no day %d.\n", opts.day)
+	default:
+		days := allDays
+		if opts.day > 0 {
+			days = allDays[opts.day-1 : opts.day]
+		}
+		if opts.input != "" {
+			settings.readInput = func(int) (string, error) { return readInputFile(opts.input) }
+		} else {
+			dir := inputDir(INPUT_DIRNAME)
+			if opts.inputDir != "" {
+				dir = inputDir(opts.inputDir)
+			}
+			settings.readInput = dir.read
+		}
+		if opts.profileDir != "" {
+			if err := os.MkdirAll(opts.profileDir, 0o755); err != nil {
+				fmt.Printf("Couldn't create profile directory: %v\n", err)
+				os.Exit(1)
+			}
+			settings.profileDir = opts.profileDir
+		}
+		settings.logger = settings.logger.With(slog.String("year", "2024"))
+		runs, wallClock := runDays(settings, days, opts.parallel, !opts.skipTests, !opts.testsOnly)
+		if opts.format == FORMAT_TEXT {
+			printRuns(runs, opts.parallel, wallClock, opts.profileDir)
+		} else if err := writeRecords(os.Stdout, opts.format, runs); err != nil {
+			fmt.Fprintf(os.Stderr, "Couldn't write results: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	printUsage()
+	os.Exit(1)
 }
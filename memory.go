@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// How much of the memory to read at once. This is all the memory
+// the memory command needs, however big the dump.
+const MEMORY_CHUNK_SIZE int = 64 << 10
+
+// The memory command: scan corrupted memory a chunk at a time, as
+// day 3 does, printing the running totals as it goes.
+func runMemory(args []string) error {
+	var inputPath string
+	var example bool
+	var every int64
+	flags := flag.NewFlagSet(os.Args[0]+" memory", flag.ContinueOnError)
+	flags.StringVar(&inputPath, "i", "", "")
+	flags.StringVar(&inputPath, "input", "", "")
+	flags.BoolVar(&example, "example", false, "")
+	flags.Int64Var(&every, "every", 0, "")
+	flags.Usage = printMemoryUsage
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	switch {
+	case flags.NArg() > 0:
+		return fmt.Errorf("unexpected %q", flags.Arg(0))
+	case inputPath != "" && example:
+		return errors.New("the -i and --example arguments are mutually exclusive")
+	case every < 0:
+		return errors.New("--every can't be negative")
+	}
+
+	switch {
+	case example:
+		return streamD3Memory(strings.NewReader(Day3.ExampleInput), os.Stdout, every)
+	case inputPath == "" || inputPath == "-":
+		return streamD3Memory(os.Stdin, os.Stdout, every)
+	}
+	file, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return streamD3Memory(file, os.Stdout, every)
+}
+
+// Read all of r through a d3Stream, printing the totals every so
+// many bytes (or after every read, if every is 1, which is what you
+// want for tail -f) and at the end.
+func streamD3Memory(r io.Reader, w io.Writer, every int64) error {
+	memory := newD3Stream()
+	chunk := make([]byte, MEMORY_CHUNK_SIZE)
+	var reported int64 = -1
+	nextReport := every
+	report := func() {
+		fmt.Fprintf(w, "%d bytes: %d muls adding up to %d, %d of them enabled adding up to %d\n", memory.bytes, memory.muls, memory.total, memory.enabledMuls, memory.enabledTotal)
+		reported = memory.bytes
+	}
+	for {
+		n, err := r.Read(chunk)
+		memory.Write(chunk[:n])
+		if every > 0 && memory.bytes >= nextReport {
+			report()
+			nextReport = (memory.bytes/every + 1) * every
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return err
+		}
+	}
+	if reported != memory.bytes {
+		report()
+	}
+	return nil
+}
+
+const MEMORY_USAGE_TEXT = `Usage: %s memory [options]
+
+Scan corrupted memory for day 3's instructions a chunk at a time, in
+the same small amount of memory however big it is, printing the
+totals for both parts as it goes.
+
+Options:
+  -i, --input    Scan this file (or stdin, if "-", which is the default)
+      --example  Scan day 3's example input
+      --every    Print the totals each time this many more bytes have been scanned
+                 (1 for after every read, e.g. when following a log with tail -f),
+                 as well as at the end. By default, they're only printed at the end
+`
+
+func printMemoryUsage() {
+	fmt.Printf(MEMORY_USAGE_TEXT, os.Args[0])
+}
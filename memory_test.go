@@ -0,0 +1,23 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestStreamD3Memory(t *testing.T) {
+	var out bytes.Buffer
+	err := streamD3Memory(iotest.OneByteReader(strings.NewReader(Day3.ExampleInput)), &out, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `30 bytes: 1 muls adding up to 8, 1 of them enabled adding up to 8
+60 bytes: 3 muls adding up to 121, 1 of them enabled adding up to 8
+73 bytes: 4 muls adding up to 161, 2 of them enabled adding up to 48
+`
+	if got := out.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
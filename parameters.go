@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Some days depend on numbers that aren't in the input - the size
+// of the grid, how many times to blink, and so on - and which are
+// different for the example than for the real thing. Rather than
+// each day guessing which it's been given from the size of the
+// input, which goes wrong as soon as someone gives it an input of
+// their own, days declare those numbers as parameters. Each has a
+// value for the example and a default for everything else, and the
+// default can be overridden from the command line.
+type dayParameter struct {
+	Name        string
+	Description string
+	Default     int
+	Example     int
+	// The range an override has to be in. A Max of 0 means there's
+	// no limit, as a parameter that can only be 0 isn't much of a
+	// parameter - a flag has a Max of 1.
+	Min, Max int
+}
+
+// The values parameters take for one run of a part, which travel in
+// the context.
+type parameterValues struct {
+	overrides map[*dayParameter]int
+	example   bool
+}
+
+type parameterValuesKey struct{}
+
+// Override the defaults for some parameters. The example always
+// uses its own values regardless, so that it still checks what it
+// was meant to check.
+func withParameterOverrides(ctx context.Context, overrides map[*dayParameter]int) context.Context {
+	values, _ := ctx.Value(parameterValuesKey{}).(parameterValues)
+	values.overrides = overrides
+	return context.WithValue(ctx, parameterValuesKey{}, values)
+}
+
+// Note in ctx whether this is the example input, which is what
+// decides whether the parameters take their example values.
+func (p puzzle) withParameters(ctx context.Context, input string) context.Context {
+	values, _ := ctx.Value(parameterValuesKey{}).(parameterValues)
+	values.example = p.ExampleInput != "" && input == p.ExampleInput
+	return context.WithValue(ctx, parameterValuesKey{}, values)
+}
+
+func (param *dayParameter) get(ctx context.Context) int {
+	values, _ := ctx.Value(parameterValuesKey{}).(parameterValues)
+	if values.example {
+		return param.Example
+	}
+	if value, found := values.overrides[param]; found {
+		return value
+	}
+	return param.Default
+}
+
+// Parse an override from the command line, which looks like
+// 18.gridSize=50, and add it to overrides.
+func parseParameterOverride(overrides map[*dayParameter]int, arg string) error {
+	name, valueStr, found := strings.Cut(arg, "=")
+	dayStr, paramName, foundDot := strings.Cut(name, ".")
+	if !found || !foundDot {
+		return fmt.Errorf("expected day.name=value, found %q", arg)
+	}
+	dayNumber, err := strconv.Atoi(dayStr)
+	if err != nil || dayNumber < 1 || dayNumber > len(allDays) {
+		return fmt.Errorf("there's no day %s", dayStr)
+	}
+	var param *dayParameter
+	for _, candidate := range allDays[dayNumber-1].Parameters {
+		if candidate.Name == paramName {
+			param = candidate
+		}
+	}
+	if param == nil {
+		return fmt.Errorf("day %d has no parameter %q", dayNumber, paramName)
+	}
+	value, err := strconv.Atoi(valueStr)
+	if err != nil {
+		return fmt.Errorf("%s: expected a number, found %q", name, valueStr)
+	}
+	if value < param.Min {
+		return fmt.Errorf("%s can't be less than %d", name, param.Min)
+	}
+	if param.Max != 0 && value > param.Max {
+		return fmt.Errorf("%s can't be more than %d", name, param.Max)
+	}
+	overrides[param] = value
+	return nil
+}
+
+// Print every day's parameters, for --listParams.
+func printParameters() {
+	for _, day := range allDays {
+		for _, param := range day.Parameters {
+			// Without an example, the example value never applies.
+			if day.ExampleInput == "" {
+				fmt.Printf("%d.%s (default %d)\n    %s\n", day.DayNumber, param.Name, param.Default, param.Description)
+			} else {
+				fmt.Printf("%d.%s (default %d, example %d)\n    %s\n", day.DayNumber, param.Name, param.Default, param.Example, param.Description)
+			}
+		}
+	}
+}
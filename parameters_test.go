@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+// An input the same size as the example, but which isn't the
+// example, should get the defaults unless they're overridden - it
+// shouldn't be mistaken for the example.
+func TestParameterOverrides(t *testing.T) {
+	// Day 20 splits its input with strings.Fields, so a trailing
+	// space makes no difference to it.
+	input := Day20.ExampleInput + " "
+	tests := []struct {
+		name         string
+		overrides    []string
+		part1, part2 string
+	}{
+		{"defaults", nil, "0", "0"},
+		{"overridden", []string{"20.part1Threshold=0", "20.part2Threshold=50"}, Day20.ExamplePart1Answer, Day20.ExamplePart2Answer},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			overrides := make(map[*dayParameter]int)
+			for _, arg := range test.overrides {
+				if err := parseParameterOverride(overrides, arg); err != nil {
+					t.Fatal(err)
+				}
+			}
+			ctx := withParameterOverrides(context.Background(), overrides)
+			part1, part1Context, err := Day20.runPart1(ctx, quietLogger(), input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			part2, err := Day20.runPart2(ctx, quietLogger(), input, part1Context)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if part1 != test.part1 || part2 != test.part2 {
+				t.Errorf("got %s and %s, want %s and %s", part1, part2, test.part1, test.part2)
+			}
+
+			// Whatever's been overridden, the example keeps its own
+			// values.
+			part1, _, err = Day20.runPart1(ctx, quietLogger(), Day20.ExampleInput)
+			if err != nil || part1 != Day20.ExamplePart1Answer {
+				t.Errorf("example: got %s (%v), want %s", part1, err, Day20.ExamplePart1Answer)
+			}
+		})
+	}
+}
+
+func TestParseParameterOverride(t *testing.T) {
+	for _, arg := range []string{
+		"18.gridSize",
+		"gridSize=5",
+		"26.gridSize=5",
+		"18.gridSiz=5",
+		"18.gridSize=five",
+		"18.gridSize=0",
+		"2.monotonic=2",
+		"2.monotonic=-1",
+		"21.part2Robots=36",
+	} {
+		if err := parseParameterOverride(make(map[*dayParameter]int), arg); err == nil {
+			t.Errorf("%s: expected an error", arg)
+		}
+	}
+}
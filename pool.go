@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// Lots of days split their work into independent pieces that can
+// run in parallel. Rather than each of them spawning a goroutine
+// per piece - which is fine for one day on its own, but runs badly
+// when several days are going at once - they all hand their pieces
+// to one shared pool with a goroutine per CPU core.
+//
+// Work is only ever handed to a pool goroutine that's sitting idle;
+// if they're all busy, whoever's submitting the work just does it
+// themselves. So nothing ever queues up waiting for a goroutine,
+// which means that pool work can itself use the pool without any
+// risk of deadlock.
+type workerPool struct {
+	workers int
+	tasks   chan func()
+}
+
+func newWorkerPool(workers int) *workerPool {
+	pool := &workerPool{workers, make(chan func())}
+	for range workers {
+		go func() {
+			for task := range pool.tasks {
+				task()
+			}
+		}()
+	}
+	return pool
+}
+
+var sharedPool = sync.OnceValue(func() *workerPool {
+	return newWorkerPool(runtime.NumCPU())
+})
+
+// How many pieces of work can run at once. Days that need some
+// expensive state per thread (e.g. a scratch map) should split
+// their work into this many pieces rather than more.
+func poolSize() int {
+	return sharedPool().workers
+}
+
+// Run f(0) through f(n-1) on the shared pool, returning once
+// they've all finished. If any of them panics, the panic is passed
+// on to our caller, where the day's usual panic handling will pick
+// it up.
+//
+// Once ctx is cancelled, any work that hasn't started yet is
+// skipped, and we return ctx's error - the results of whatever did
+// run are then incomplete, so callers should give up rather than
+// use them. Work that's already running is expected to keep an eye
+// on ctx itself if it might take a while.
+func parallelFor(ctx context.Context, n int, f func(ix int)) error {
+	pool := sharedPool()
+	var wg sync.WaitGroup
+	var panicOnce sync.Once
+	var panicked any
+	wg.Add(n)
+	for ix := range n {
+		task := func() {
+			defer wg.Done()
+			if ctx.Err() != nil {
+				return
+			}
+			defer func() {
+				if r := recover(); r != nil {
+					panicOnce.Do(func() { panicked = r })
+				}
+			}()
+			f(ix)
+		}
+		select {
+		case pool.tasks <- task:
+		default:
+			task()
+		}
+	}
+	wg.Wait()
+	if panicked != nil {
+		panic(panicked)
+	}
+	return ctx.Err()
+}
+
+// As parallelFor, for the common case where each piece of work
+// produces a number and we want the total.
+func parallelSum(ctx context.Context, n int, f func(ix int) int) (int, error) {
+	results := make([]int, n)
+	err := parallelFor(ctx, n, func(ix int) {
+		results[ix] = f(ix)
+	})
+	if err != nil {
+		return 0, err
+	}
+	sum := 0
+	for _, result := range results {
+		sum += result
+	}
+	return sum, nil
+}
+
+// Split n items into chunks, one per pool goroutine, and run
+// f(first, last) over each range [first, last) on the pool,
+// totalling the results.
+func parallelSumChunks(ctx context.Context, n int, f func(first int, last int) int) (int, error) {
+	chunks := min(poolSize(), n)
+	if chunks == 0 {
+		return 0, ctx.Err()
+	}
+	chunkSize := (n + chunks - 1) / chunks
+	chunks = (n + chunkSize - 1) / chunkSize
+	return parallelSum(ctx, chunks, func(chunk int) int {
+		first := chunk * chunkSize
+		return f(first, min(first+chunkSize, n))
+	})
+}
+
+// Long-running loops call check() on one of these every time round,
+// and give up if it returns an error. It only actually looks at the
+// context every so often, so that the overhead is negligible even
+// in the tightest of loops - but once it's seen a cancellation, it
+// keeps reporting it, so that recursive searches unwind all the way.
+type cancelChecker struct {
+	ctx   context.Context
+	count uint
+	err   error
+}
+
+const CANCEL_CHECK_INTERVAL uint = 1 << 10
+
+func newCancelChecker(ctx context.Context) *cancelChecker {
+	return &cancelChecker{ctx: ctx}
+}
+
+func (c *cancelChecker) check() error {
+	c.count++
+	if c.err == nil && c.count%CANCEL_CHECK_INTERVAL == 0 {
+		c.err = c.ctx.Err()
+	}
+	return c.err
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+)
+
+func TestParallelSum(t *testing.T) {
+	for _, n := range []int{0, 1, 7, 1000} {
+		want := n * (n - 1) / 2
+		if got, err := parallelSum(context.Background(), n, func(ix int) int { return ix }); got != want || err != nil {
+			t.Errorf("parallelSum(%d): got %d (%v), want %d", n, got, err, want)
+		}
+
+		// Every item should land in exactly one chunk.
+		var seen atomic.Int64
+		got, err := parallelSumChunks(context.Background(), n, func(first int, last int) int {
+			seen.Add(int64(last - first))
+			sum := 0
+			for ix := first; ix < last; ix++ {
+				sum += ix
+			}
+			return sum
+		})
+		if got != want || err != nil || seen.Load() != int64(n) {
+			t.Errorf("parallelSumChunks(%d): got %d over %d items, want %d over %d", n, got, seen.Load(), want, n)
+		}
+	}
+}
+
+// Work submitted from inside pool work mustn't deadlock, even when
+// there's far more of it than there are workers.
+func TestParallelForNested(t *testing.T) {
+	ctx := context.Background()
+	n := poolSize() * 4
+	got, _ := parallelSum(ctx, n, func(int) int {
+		inner, _ := parallelSum(ctx, n, func(int) int { return 1 })
+		return inner
+	})
+	if got != n*n {
+		t.Errorf("got %d, want %d", got, n*n)
+	}
+}
+
+func TestParallelForPanic(t *testing.T) {
+	defer func() {
+		if r := recover(); r != "boom" {
+			t.Errorf("expected the task's panic to reach the caller, got %v", r)
+		}
+	}()
+	parallelFor(context.Background(), 10, func(ix int) {
+		if ix == 5 {
+			panic("boom")
+		}
+	})
+}
+
+func TestParallelForCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var ran atomic.Int64
+	err := parallelFor(ctx, 100, func(int) { ran.Add(1) })
+	if !errors.Is(err, context.Canceled) || ran.Load() != 0 {
+		t.Errorf("got %v after running %d tasks, want context.Canceled after none", err, ran.Load())
+	}
+}
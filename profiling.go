@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/metrics"
+	"runtime/pprof"
+	"time"
+)
+
+// Plenty of the days have comments about how they avoid allocating
+// - reusing maps, swapping buffers and so on. Rather than taking
+// those on trust, profiling mode measures them: each part of each
+// day gets its own CPU and heap profiles, and we note how much it
+// allocated and how big the heap got while it ran.
+//
+// (The runner has a -p option too, but that profiles the whole run
+// into one file, which makes it hard to pick out any one day.)
+
+// How often to look at the size of the heap while a part is
+// running. Anything that only briefly pushes the heap up can slip
+// through the gaps, so the peak we report is a lower bound.
+const HEAP_SAMPLE_INTERVAL time.Duration = time.Millisecond
+
+const HEAP_METRIC string = "/memory/classes/heap/objects:bytes"
+
+// Keeps an eye on the heap in the background, recording the
+// biggest it's seen.
+type heapWatcher struct {
+	stop chan struct{}
+	done chan struct{}
+	peak uint64
+}
+
+func watchHeap() *heapWatcher {
+	w := &heapWatcher{stop: make(chan struct{}), done: make(chan struct{})}
+	w.sample()
+	go func() {
+		defer close(w.done)
+		ticker := time.NewTicker(HEAP_SAMPLE_INTERVAL)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-w.stop:
+				return
+			case <-ticker.C:
+				w.sample()
+			}
+		}
+	}()
+	return w
+}
+
+func (w *heapWatcher) sample() {
+	// Unlike runtime.ReadMemStats, this doesn't stop the world, so
+	// it's cheap enough to do frequently.
+	sample := []metrics.Sample{{Name: HEAP_METRIC}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() == metrics.KindUint64 {
+		w.peak = max(w.peak, sample[0].Value.Uint64())
+	}
+}
+
+// Stop watching, and return the peak heap size.
+func (w *heapWatcher) finish() uint64 {
+	close(w.stop)
+	<-w.done
+	w.sample()
+	return w.peak
+}
+
+// The files a part's profiles go in, e.g. day06-part1.cpu.pprof.
+func profilePaths(dir string, dayNumber int, part int) (cpuPath string, heapPath string) {
+	base := filepath.Join(dir, fmt.Sprintf("day%02d-part%d", dayNumber, part))
+	return base + ".cpu.pprof", base + ".heap.pprof"
+}
+
+// Run a part with profiling. As with measurePart, allocations are
+// counted and the time is kept separate from the cost of reading
+// memory stats - though the overhead of profiling itself will
+// inflate the time, so don't compare it against normal runs.
+//
+// Failing to write a profile isn't the part's fault, so it's logged
+// rather than stopping anything.
+func profilePart(settings runSettings, dayNumber int, partNumber int, part func() string) partRun {
+	logger := settings.logger.With(slog.Int("day", dayNumber), slog.Int("part", partNumber))
+	cpuPath, heapPath := profilePaths(settings.profileDir, dayNumber, partNumber)
+
+	// Start from a clean heap, so that whatever the last part left
+	// lying around doesn't count towards this one's peak.
+	runtime.GC()
+
+	cpuFile, err := os.Create(cpuPath)
+	if err != nil {
+		logger.Error("Couldn't create CPU profile", slog.Any("error", err))
+	} else {
+		defer cpuFile.Close()
+		if err := pprof.StartCPUProfile(cpuFile); err != nil {
+			logger.Error("Couldn't start CPU profile", slog.Any("error", err))
+			cpuFile = nil
+		}
+	}
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	watcher := watchHeap()
+	start := time.Now()
+	run := partRun{result: part()}
+	run.duration = time.Since(start)
+	run.peakHeap = watcher.finish()
+	runtime.ReadMemStats(&after)
+	run.allocs = after.Mallocs - before.Mallocs
+	run.bytesAllocated = after.TotalAlloc - before.TotalAlloc
+	run.allocsCounted = true
+	run.profiled = true
+
+	if cpuFile != nil {
+		pprof.StopCPUProfile()
+	}
+
+	// The heap profile is only brought up to date by a GC. What's
+	// still in use afterwards is mostly whatever part 1 is passing
+	// on to part 2.
+	runtime.GC()
+	heapFile, err := os.Create(heapPath)
+	if err != nil {
+		logger.Error("Couldn't create heap profile", slog.Any("error", err))
+		return run
+	}
+	defer heapFile.Close()
+	if err := pprof.Lookup("heap").WriteTo(heapFile, 0); err != nil {
+		logger.Error("Couldn't write heap profile", slog.Any("error", err))
+	}
+	return run
+}
+
+// Print a day's allocation figures after its results.
+func printProfile(run *dayRun, dir string) {
+	fmt.Println("--Profile--")
+	for ix, part := range run.real {
+		cpuPath, heapPath := profilePaths(dir, run.day.DayNumber, ix+1)
+		fmt.Printf("Part %d: %d allocs/op, %s/op, peak heap %s\n", ix+1, part.allocs, formatBytes(part.bytesAllocated), formatBytes(part.peakHeap))
+		fmt.Printf("        profiles in %s and %s\n", cpuPath, heapPath)
+	}
+	fmt.Printf("Peak heap for the day: %s\n", formatBytes(max(run.real[0].peakHeap, run.real[1].peakHeap)))
+}
+
+func formatBytes(bytes uint64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%dB", bytes)
+	}
+	value := float64(bytes)
+	suffix := 0
+	for value >= unit && suffix < 3 {
+		value /= unit
+		suffix++
+	}
+	return fmt.Sprintf("%.1f%s", value, []string{"B", "KiB", "MiB", "GiB"}[suffix])
+}
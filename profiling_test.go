@@ -0,0 +1,43 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestProfilePart(t *testing.T) {
+	settings := runSettings{logger: quietLogger(), profileDir: t.TempDir()}
+	var kept []byte
+	run := profilePart(settings, 6, 1, func() string {
+		kept = make([]byte, 1<<20)
+		return "41"
+	})
+	if run.result != "41" || !run.profiled {
+		t.Errorf("got %+v", run)
+	}
+	if run.allocs == 0 || run.bytesAllocated < 1<<20 || run.peakHeap < 1<<20 {
+		t.Errorf("didn't see the 1MiB allocation: %+v", run)
+	}
+	cpuPath, heapPath := profilePaths(settings.profileDir, 6, 1)
+	for _, path := range []string{cpuPath, heapPath} {
+		if info, err := os.Stat(path); err != nil || info.Size() == 0 {
+			t.Errorf("%s: missing or empty (%v)", path, err)
+		}
+	}
+	_ = kept
+}
+
+func TestFormatBytes(t *testing.T) {
+	for bytes, want := range map[uint64]string{
+		0:         "0B",
+		1023:      "1023B",
+		1536:      "1.5KiB",
+		400 << 20: "400.0MiB",
+		3 << 30:   "3.0GiB",
+		5 << 40:   "5120.0GiB",
+	} {
+		if got := formatBytes(bytes); got != want {
+			t.Errorf("formatBytes(%d): got %s, want %s", bytes, got, want)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// As well as printing results for people to read, we can write them
+// out for programs to read - one record per day and part - so that
+// they can go into dashboards, or two builds' results can be
+// compared automatically.
+
+const (
+	FORMAT_TEXT string = "text"
+	FORMAT_JSON string = "json"
+	FORMAT_CSV  string = "csv"
+)
+
+// How one part of one day went. Anything we don't know - because we
+// didn't run the real input or the example, or because days were
+// running in parallel so allocations couldn't be counted - is null.
+type resultRecord struct {
+	Day           int     `json:"day"`
+	Part          int     `json:"part"`
+	Answer        *string `json:"answer"`
+	WallTimeNs    *int64  `json:"wallTimeNs"`
+	Allocations   *uint64 `json:"allocations"`
+	ExamplePassed *bool   `json:"examplePassed"`
+	InputError    string  `json:"inputError,omitempty"`
+}
+
+func resultRecords(runs []dayRun) []resultRecord {
+	records := make([]resultRecord, 0, 2*len(runs))
+	for _, run := range runs {
+		for ix := range 2 {
+			record := resultRecord{Day: run.day.DayNumber, Part: ix + 1}
+			if run.ranReal {
+				part := run.real[ix]
+				wallTime := part.duration.Nanoseconds()
+				record.Answer = &part.result
+				record.WallTimeNs = &wallTime
+				if part.allocsCounted {
+					record.Allocations = &part.allocs
+				}
+			}
+			if run.ranExample {
+				record.ExamplePassed = &run.exampleCorrect[ix]
+			}
+			if run.inputErr != nil {
+				record.InputError = run.inputErr.Error()
+			}
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+// Write the results of runDays in the given format, which mustn't
+// be FORMAT_TEXT - that's printRuns' job.
+func writeRecords(w io.Writer, format string, runs []dayRun) error {
+	records := resultRecords(runs)
+	switch format {
+	case FORMAT_JSON:
+		data, err := json.MarshalIndent(records, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", data)
+		return err
+	case FORMAT_CSV:
+		out := csv.NewWriter(w)
+		out.Write([]string{"day", "part", "answer", "wallTimeNs", "allocations", "examplePassed", "inputError"})
+		for _, record := range records {
+			out.Write([]string{
+				strconv.Itoa(record.Day),
+				strconv.Itoa(record.Part),
+				csvField(record.Answer, func(s string) string { return s }),
+				csvField(record.WallTimeNs, func(n int64) string { return strconv.FormatInt(n, 10) }),
+				csvField(record.Allocations, func(n uint64) string { return strconv.FormatUint(n, 10) }),
+				csvField(record.ExamplePassed, strconv.FormatBool),
+				record.InputError,
+			})
+		}
+		out.Flush()
+		return out.Error()
+	default:
+		return fmt.Errorf("unknown output format %q", format)
+	}
+}
+
+// Nulls in CSV are just empty fields.
+func csvField[T any](value *T, format func(T) string) string {
+	if value == nil {
+		return ""
+	}
+	return format(*value)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteRecords(t *testing.T) {
+	runs := []dayRun{
+		{
+			day:            Day1,
+			ranExample:     true,
+			exampleCorrect: [2]bool{true, false},
+			ranReal:        true,
+			real: [2]partRun{
+				{result: "11", duration: time.Microsecond, allocs: 3, allocsCounted: true},
+				{result: "31,2", duration: 2 * time.Microsecond},
+			},
+		},
+		{day: Day2, inputErr: errors.New("no input")},
+	}
+
+	var out strings.Builder
+	if err := writeRecords(&out, FORMAT_CSV, runs); err != nil {
+		t.Fatal(err)
+	}
+	want := `day,part,answer,wallTimeNs,allocations,examplePassed,inputError
+1,1,11,1000,3,true,
+1,2,"31,2",2000,,false,
+2,1,,,,,no input
+2,2,,,,,no input
+`
+	if out.String() != want {
+		t.Errorf("CSV: got\n%s\nwant\n%s", out.String(), want)
+	}
+
+	out.Reset()
+	if err := writeRecords(&out, FORMAT_JSON, runs); err != nil {
+		t.Fatal(err)
+	}
+	var records []map[string]any
+	if err := json.Unmarshal([]byte(out.String()), &records); err != nil {
+		t.Fatalf("JSON output doesn't parse: %v\n%s", err, out.String())
+	}
+	if len(records) != 4 {
+		t.Fatalf("JSON: got %d records, want 4", len(records))
+	}
+	if records[0]["answer"] != "11" || records[0]["allocations"] != 3.0 || records[0]["examplePassed"] != true {
+		t.Errorf("JSON: wrong first record %v", records[0])
+	}
+	if records[1]["allocations"] != nil || records[2]["answer"] != nil {
+		t.Errorf("JSON: unknown values should be null, got %v and %v", records[1], records[2])
+	}
+}
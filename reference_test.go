@@ -0,0 +1,599 @@
+//go:build reference
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Differential tests for the days whose fast solutions lean on
+// shortcuts - Day 2's Problem Dampener, Day 11's stone counting, Day
+// 14's variance and remainder trick, Day 17's three bits at a time
+// and Day 24's assumptions about what an adder looks like. Each has
+// a reference implementation here that's slow but obviously right,
+// and we throw thousands of generated inputs at both and check they
+// agree.
+//
+// They take a while, so they're behind a build tag:
+//   go test -tags reference -run TestReference
+// Add -referenceSeed=N to repeat a run that found a problem.
+
+var referenceSeed = flag.Uint64("referenceSeed", 0, "seed for generating reference test inputs (0 for a random one)")
+
+// How long the fast solutions get per input. They should need
+// nowhere near this.
+const REFERENCE_TIME_LIMIT time.Duration = 10 * time.Second
+
+// Stop reporting a day's mismatches after this many - there's
+// rarely anything to learn from the rest.
+const REFERENCE_MAX_FAILURES int = 5
+
+type referenceTest struct {
+	day   puzzle
+	cases int
+	// Generate an input, along with any parameters it needs.
+	generate func(rng *rand.Rand) (string, map[*dayParameter]int)
+	// Work out the answers the slow way. If ok is false, the
+	// reference can't say what the answer is - there might not be
+	// one - so the fast answer isn't checked.
+	part1 func(input string, params map[*dayParameter]int) (answer string, ok bool)
+	part2 func(input string, params map[*dayParameter]int) (answer string, ok bool)
+}
+
+var referenceTests = []referenceTest{
+	{
+		day:   Day2,
+		cases: 5000,
+		generate: func(rng *rand.Rand) (string, map[*dayParameter]int) {
+			input := generateDay2(rng, 1+rng.IntN(20))
+			if rng.IntN(2) == 0 {
+				return input, nil
+			}
+			// The rules can be changed too, so check some others.
+			minStep := rng.IntN(3)
+			return input, map[*dayParameter]int{
+				d2MinStep:     minStep,
+				d2MaxStep:     minStep + rng.IntN(4),
+				d2Monotonic:   rng.IntN(2),
+				d2MaxRemovals: rng.IntN(4),
+			}
+		},
+		part1: referenceDay2(false),
+		part2: referenceDay2(true),
+	},
+	{
+		day:   Day11,
+		cases: 200,
+		generate: func(rng *rand.Rand) (string, map[*dayParameter]int) {
+			// Not 75 blinks, or we'd be here all week.
+			return generateDay11(rng, 1+rng.IntN(3)), map[*dayParameter]int{d11Part1Blinks: 15, d11Part2Blinks: 25 + rng.IntN(6)}
+		},
+		part1: referenceDay11(d11Part1Blinks),
+		part2: referenceDay11(d11Part2Blinks),
+	},
+	{
+		day:   Day14,
+		cases: 1000,
+		generate: func(rng *rand.Rand) (string, map[*dayParameter]int) {
+			return generateDay14(rng, 50+rng.IntN(100)), nil
+		},
+		part1: referenceDay14Part1,
+		part2: referenceDay14Part2,
+	},
+	{
+		day:   Day17,
+		cases: 5000,
+		generate: func(rng *rand.Rand) (string, map[*dayParameter]int) {
+			return generateDay17(rng, rng.IntN(6)), nil
+		},
+		part1: referenceDay17Part1,
+		part2: referenceDay17Part2,
+	},
+	{
+		day:   Day24,
+		cases: 1000,
+		generate: func(rng *rand.Rand) (string, map[*dayParameter]int) {
+			// Small enough for the reference to try every swap and
+			// every input.
+			pairs := 1 + rng.IntN(2)
+			return generateDay24(rng, 3+rng.IntN(2), pairs), map[*dayParameter]int{d24SwappedPairs: pairs}
+		},
+		part1: referenceDay24Part1,
+		part2: referenceDay24Part2,
+	},
+}
+
+func TestReference(t *testing.T) {
+	seed := *referenceSeed
+	if seed == 0 {
+		seed = uint64(time.Now().UnixNano())
+	}
+	t.Logf("seed %d", seed)
+
+	for _, test := range referenceTests {
+		t.Run(fmt.Sprintf("Day%02d", test.day.DayNumber), func(t *testing.T) {
+			t.Parallel()
+			rng := rand.New(rand.NewPCG(seed, uint64(test.day.DayNumber)))
+			failures, checked := 0, [2]int{}
+			for range test.cases {
+				input, params := test.generate(rng)
+				if !checkAgainstReference(t, test, input, params, &checked) {
+					failures++
+					if failures == REFERENCE_MAX_FAILURES {
+						t.Fatalf("giving up after %d mismatches", failures)
+					}
+				}
+			}
+			t.Logf("checked %d part 1 and %d part 2 answers", checked[0], checked[1])
+		})
+	}
+}
+
+// Run one input through both the fast solution and the reference,
+// reporting any difference.
+func checkAgainstReference(t *testing.T, test referenceTest, input string, params map[*dayParameter]int, checked *[2]int) bool {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(withParameterOverrides(context.Background(), params), REFERENCE_TIME_LIMIT)
+	defer cancel()
+	logger := quietLogger()
+
+	got1, part1Context, err := test.day.runPart1(ctx, logger, input)
+	if err != nil {
+		t.Errorf("part 1 failed: %v\ninput:\n%s", err, input)
+		return false
+	}
+	if want, ok := test.part1(input, params); ok {
+		checked[0]++
+		if got1 != want {
+			t.Errorf("part 1: got %q, reference says %q\ninput:\n%s", got1, want, input)
+			return false
+		}
+	}
+
+	// Ask the reference first, as if it says there's no answer the
+	// fast solution might just keep looking.
+	want, ok := test.part2(input, params)
+	if !ok {
+		return true
+	}
+	checked[1]++
+	got2, err := test.day.runPart2(ctx, logger, input, part1Context)
+	if err != nil {
+		t.Errorf("part 2 failed: %v\ninput:\n%s", err, input)
+		return false
+	}
+	if got2 != want {
+		t.Errorf("part 2: got %q, reference says %q\ninput:\n%s", got2, want, input)
+		return false
+	}
+	return true
+}
+
+func parseReferenceInts(line string, separator string) []int {
+	var nums []int
+	for _, field := range strings.Split(line, separator) {
+		num, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			panic(fmt.Sprintf("reference can't parse %q: %v", line, err))
+		}
+		nums = append(nums, num)
+	}
+	return nums
+}
+
+// Day 2: a report is safe if it only goes one way (unless the rules
+// say otherwise), a step of 1 to 3 (or whatever) at a time. With the
+// dampener, try taking out every level in turn, and then every other
+// level in turn, and so on.
+func referenceDay2(dampener bool) func(string, map[*dayParameter]int) (string, bool) {
+	return func(input string, params map[*dayParameter]int) (string, bool) {
+		param := func(p *dayParameter) int {
+			if value, found := params[p]; found {
+				return value
+			}
+			return p.Default
+		}
+		minStep, maxStep, monotonic := param(d2MinStep), param(d2MaxStep), param(d2Monotonic) != 0
+		inRange := func(step int) bool {
+			return step >= minStep && step <= maxStep
+		}
+		safe := func(report []int) bool {
+			allUp, allDown, allEither := true, true, true
+			for ix := 1; ix < len(report); ix++ {
+				step := report[ix] - report[ix-1]
+				allUp = allUp && inRange(step)
+				allDown = allDown && inRange(-step)
+				allEither = allEither && (inRange(step) || inRange(-step))
+			}
+			return allUp || allDown || (!monotonic && allEither)
+		}
+		var safeWithout func(report []int, removals int) bool
+		safeWithout = func(report []int, removals int) bool {
+			if safe(report) {
+				return true
+			}
+			for ix := 0; removals > 0 && ix < len(report); ix++ {
+				if safeWithout(slices.Delete(slices.Clone(report), ix, ix+1), removals-1) {
+					return true
+				}
+			}
+			return false
+		}
+
+		removals := 0
+		if dampener {
+			removals = param(d2MaxRemovals)
+		}
+		count := 0
+		for _, line := range strings.Split(input, "\n") {
+			if safeWithout(parseReferenceInts(line, " "), removals) {
+				count++
+			}
+		}
+		return strconv.Itoa(count), true
+	}
+}
+
+// Day 11: keep every stone in a list, in order, and blink at each
+// one.
+func referenceDay11(blinks *dayParameter) func(string, map[*dayParameter]int) (string, bool) {
+	return func(input string, params map[*dayParameter]int) (string, bool) {
+		stones := parseReferenceInts(input, " ")
+		for range params[blinks] {
+			var next []int
+			for _, stone := range stones {
+				digits := strconv.Itoa(stone)
+				switch {
+				case stone == 0:
+					next = append(next, 1)
+				case len(digits)%2 == 0:
+					left, _ := strconv.Atoi(digits[:len(digits)/2])
+					right, _ := strconv.Atoi(digits[len(digits)/2:])
+					next = append(next, left, right)
+				default:
+					next = append(next, stone*2024)
+				}
+			}
+			stones = next
+		}
+		return strconv.Itoa(len(stones)), true
+	}
+}
+
+func parseReferenceRobots(input string) (positions []gridPos, vectors []gridPos) {
+	for _, line := range strings.Split(input, "\n") {
+		var pos, vector gridPos
+		if _, err := fmt.Sscanf(line, "p=%d,%d v=%d,%d", &pos.col, &pos.row, &vector.col, &vector.row); err != nil {
+			panic(fmt.Sprintf("reference can't parse %q: %v", line, err))
+		}
+		positions = append(positions, pos)
+		vectors = append(vectors, vector)
+	}
+	return positions, vectors
+}
+
+func moveReferenceRobots(positions []gridPos, vectors []gridPos, width int, height int) {
+	for ix := range positions {
+		positions[ix].row = ((positions[ix].row+vectors[ix].row)%height + height) % height
+		positions[ix].col = ((positions[ix].col+vectors[ix].col)%width + width) % width
+	}
+}
+
+// Day 14 part 1: move the robots one second at a time, then count
+// them up.
+func referenceDay14Part1(input string, _ map[*dayParameter]int) (string, bool) {
+	width, height := d14Width.Default, d14Height.Default
+	positions, vectors := parseReferenceRobots(input)
+	for range d14Seconds.Default {
+		moveReferenceRobots(positions, vectors, width, height)
+	}
+	var quadrants [2][2]int
+	for _, pos := range positions {
+		if pos.row != height/2 && pos.col != width/2 {
+			quadrants[pos.row/(height/2+1)][pos.col/(width/2+1)]++
+		}
+	}
+	return strconv.Itoa(quadrants[0][0] * quadrants[0][1] * quadrants[1][0] * quadrants[1][1]), true
+}
+
+// Day 14 part 2: try every second until the robots are back where
+// they started, and pick the one where they're most bunched up.
+func referenceDay14Part2(input string, _ map[*dayParameter]int) (string, bool) {
+	width, height := d14Width.Default, d14Height.Default
+	positions, vectors := parseReferenceRobots(input)
+	best, bestSpread := 0, math.MaxFloat64
+	for second := 1; second <= width*height; second++ {
+		moveReferenceRobots(positions, vectors, width, height)
+		var rowSum, colSum float64
+		for _, pos := range positions {
+			rowSum += float64(pos.row)
+			colSum += float64(pos.col)
+		}
+		meanRow, meanCol := rowSum/float64(len(positions)), colSum/float64(len(positions))
+		var spread float64
+		for _, pos := range positions {
+			spread += (float64(pos.row)-meanRow)*(float64(pos.row)-meanRow) + (float64(pos.col)-meanCol)*(float64(pos.col)-meanCol)
+		}
+		if spread < bestSpread {
+			best, bestSpread = second, spread
+		}
+	}
+	return strconv.Itoa(best), true
+}
+
+type referenceDay17Program struct {
+	a, b, c int
+	program []int
+}
+
+func parseReferenceDay17(input string) referenceDay17Program {
+	var prog referenceDay17Program
+	var program string
+	if _, err := fmt.Sscanf(input, "Register A: %d\nRegister B: %d\nRegister C: %d\n\nProgram: %s", &prog.a, &prog.b, &prog.c, &program); err != nil {
+		panic(fmt.Sprintf("reference can't parse %q: %v", input, err))
+	}
+	prog.program = parseReferenceInts(program, ",")
+	return prog
+}
+
+// Run the program, straight from the puzzle's description.
+func (prog referenceDay17Program) run(a int) []int {
+	divide := func(numerator int, power int) int {
+		if power >= 63 {
+			return 0
+		}
+		return numerator / (1 << power)
+	}
+	b, c := prog.b, prog.c
+	var output []int
+	for ip := 0; ip+1 < len(prog.program); ip += 2 {
+		operand := prog.program[ip+1]
+		combo := []int{0, 1, 2, 3, a, b, c, -1}[operand]
+		switch prog.program[ip] {
+		case 0:
+			a = divide(a, combo)
+		case 1:
+			b = b ^ operand
+		case 2:
+			b = combo % 8
+		case 3:
+			if a != 0 {
+				ip = operand - 2
+			}
+		case 4:
+			b = b ^ c
+		case 5:
+			output = append(output, combo%8)
+		case 6:
+			b = divide(a, combo)
+		case 7:
+			c = divide(a, combo)
+		}
+	}
+	return output
+}
+
+func referenceDay17Part1(input string, _ map[*dayParameter]int) (string, bool) {
+	prog := parseReferenceDay17(input)
+	return joinInts(prog.run(prog.a), ","), true
+}
+
+// Day 17 part 2. Trying every A in turn is hopeless beyond a handful
+// of instructions, so this does lean on one thing about the programs:
+// each time round the loop works only from what's in A, then shifts
+// it right by three bits, so the output for A is the output for A/8
+// with one more number on the front. (The generated programs are
+// built that way, as the real ones are.) That means every answer is
+// some A/8 that outputs all but the first number of the program, with
+// three more bits on the end - so we find every such A, all the way
+// down, and take the smallest. Unlike the fast solution, that's
+// every possibility rather than the first one that looks promising.
+func referenceDay17Part2(input string, _ map[*dayParameter]int) (string, bool) {
+	prog := parseReferenceDay17(input)
+	candidates := []int{0}
+	for pos := len(prog.program) - 1; pos >= 0; pos-- {
+		var next []int
+		for _, candidate := range candidates {
+			for bits := range 8 {
+				a := candidate<<3 | bits
+				if a != 0 && slices.Equal(prog.run(a), prog.program[pos:]) {
+					next = append(next, a)
+				}
+			}
+		}
+		candidates = next
+	}
+	if len(candidates) == 0 {
+		// This program can't output itself.
+		return "", false
+	}
+	return strconv.Itoa(slices.Min(candidates)), true
+}
+
+type referenceDay24Gate struct {
+	in1, op, in2, out string
+}
+
+func parseReferenceDay24(input string) (initial map[string]bool, gates []referenceDay24Gate, bits int) {
+	initial = make(map[string]bool)
+	values, gateLines, _ := strings.Cut(input, "\n\n")
+	for _, line := range strings.Split(values, "\n") {
+		name, value, _ := strings.Cut(line, ": ")
+		initial[name] = value == "1"
+		if name[0] == 'x' {
+			bits++
+		}
+	}
+	for _, line := range strings.Split(gateLines, "\n") {
+		var gate referenceDay24Gate
+		if _, err := fmt.Sscanf(line, "%s %s %s -> %s", &gate.in1, &gate.op, &gate.in2, &gate.out); err != nil {
+			panic(fmt.Sprintf("reference can't parse %q: %v", line, err))
+		}
+		gates = append(gates, gate)
+	}
+	return initial, gates, bits
+}
+
+// Work out every wire's value by going over the gates again and
+// again until nothing changes. Values are bitmasks, so that many
+// different inputs can be tried at once. ok is false if some wire
+// never gets a value, which means there's a loop.
+func evaluateReferenceDay24(initial map[string][4]uint64, gates []referenceDay24Gate) (map[string][4]uint64, bool) {
+	values := make(map[string][4]uint64, len(initial)+len(gates))
+	for name, value := range initial {
+		values[name] = value
+	}
+	for remaining := len(gates); remaining > 0; {
+		progress := false
+		for _, gate := range gates {
+			if _, done := values[gate.out]; done {
+				continue
+			}
+			in1, found1 := values[gate.in1]
+			in2, found2 := values[gate.in2]
+			if !found1 || !found2 {
+				continue
+			}
+			var out [4]uint64
+			for ix := range out {
+				switch gate.op {
+				case "AND":
+					out[ix] = in1[ix] & in2[ix]
+				case "OR":
+					out[ix] = in1[ix] | in2[ix]
+				case "XOR":
+					out[ix] = in1[ix] ^ in2[ix]
+				}
+			}
+			values[gate.out] = out
+			remaining--
+			progress = true
+		}
+		if !progress {
+			return nil, false
+		}
+	}
+	return values, true
+}
+
+func referenceDay24Part1(input string, _ map[*dayParameter]int) (string, bool) {
+	initial, gates, _ := parseReferenceDay24(input)
+	masks := make(map[string][4]uint64)
+	for name, value := range initial {
+		if value {
+			masks[name] = [4]uint64{1}
+		} else {
+			masks[name] = [4]uint64{}
+		}
+	}
+	values, ok := evaluateReferenceDay24(masks, gates)
+	if !ok {
+		return "", false
+	}
+	var z []string
+	for name := range values {
+		if name[0] == 'z' {
+			z = append(z, name)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(z)))
+	result := 0
+	for _, name := range z {
+		result = result<<1 | int(values[name][0]&1)
+	}
+	return strconv.Itoa(result), true
+}
+
+// Day 24 part 2: try every way of choosing the swapped pairs, and
+// keep the ones that make the circuit add up for every possible
+// input. That's only practical for tiny adders - up to four bits,
+// which makes 256 inputs, all evaluated at once as bitmasks.
+func referenceDay24Part2(input string, params map[*dayParameter]int) (string, bool) {
+	_, gates, bits := parseReferenceDay24(input)
+	if bits > 4 {
+		return "", false
+	}
+
+	// Input number n is bit n of the masks: its X is the bottom
+	// bits of n and its Y the top.
+	initial := make(map[string][4]uint64)
+	want := make(map[string][4]uint64)
+	for n := range 1 << (2 * bits) {
+		x, y := n&(1<<bits-1), n>>bits
+		for bit := range bits {
+			set := func(values map[string][4]uint64, name string, value int) {
+				mask := values[name]
+				mask[n/64] |= uint64(value&1) << (n % 64)
+				values[name] = mask
+			}
+			set(initial, fmt.Sprintf("x%02d", bit), x>>bit)
+			set(initial, fmt.Sprintf("y%02d", bit), y>>bit)
+		}
+		for bit := range bits + 1 {
+			mask := want[fmt.Sprintf("z%02d", bit)]
+			mask[n/64] |= uint64((x+y)>>bit&1) << (n % 64)
+			want[fmt.Sprintf("z%02d", bit)] = mask
+		}
+	}
+	adds := func(gates []referenceDay24Gate) bool {
+		values, ok := evaluateReferenceDay24(initial, gates)
+		if !ok {
+			return false
+		}
+		for name, mask := range want {
+			if values[name] != mask {
+				return false
+			}
+		}
+		return true
+	}
+
+	// Pairs are chosen in increasing order of their first gate, so
+	// that each set of swaps is only tried once.
+	var answers []string
+	var try func(first int, pairsLeft int, swapped []int)
+	try = func(first int, pairsLeft int, swapped []int) {
+		if pairsLeft == 0 {
+			if adds(gates) {
+				names := make([]string, len(swapped))
+				for ix, gateIx := range swapped {
+					names[ix] = gates[gateIx].out
+				}
+				slices.Sort(names)
+				answers = append(answers, strings.Join(names, ","))
+			}
+			return
+		}
+		for one := first; one < len(gates); one++ {
+			if slices.Contains(swapped, one) {
+				continue
+			}
+			for other := one + 1; other < len(gates); other++ {
+				if slices.Contains(swapped, other) {
+					continue
+				}
+				gates[one].out, gates[other].out = gates[other].out, gates[one].out
+				try(one+1, pairsLeft-1, append(swapped, one, other))
+				gates[one].out, gates[other].out = gates[other].out, gates[one].out
+			}
+		}
+	}
+	try(0, params[d24SwappedPairs], nil)
+
+	if len(answers) != 1 {
+		// Either no way to fix it, or more than one, in which case
+		// the puzzle doesn't say which is right.
+		return "", false
+	}
+	return answers[0], true
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Day 2 only wants to know how many reports are safe, but if you're
+// auditing a reactor you'll want to know why the others aren't:
+// which step breaks the rules and how, whether the Problem Dampener
+// can rescue the report and by removing what, and what else it
+// could have tried and why that wouldn't have done. That's what
+// this is, built on the same rules as the puzzle.
+
+// The first step in a report that breaks the rules.
+type d2Problem struct {
+	// The index of the level the step goes to, and the levels
+	// either side of the step.
+	Index int    `json:"index"`
+	From  int    `json:"from"`
+	To    int    `json:"to"`
+	Kind  string `json:"kind"`
+	// Which way the report was going, if it has to go one way.
+	Going string `json:"going,omitempty"`
+}
+
+const (
+	D2_PROBLEM_DIRECTION = "direction"
+	D2_PROBLEM_TOO_SMALL = "tooSmall"
+	D2_PROBLEM_TOO_BIG   = "tooBig"
+)
+
+// Everything about why one report is or isn't safe.
+type d2Explanation struct {
+	Line    int        `json:"line"`
+	Levels  []int      `json:"levels"`
+	Safe    bool       `json:"safe"`
+	Problem *d2Problem `json:"problem,omitempty"`
+
+	// Whether the Problem Dampener can make an unsafe report safe,
+	// and the indices of the levels it removes to do it.
+	Rescued bool  `json:"rescued"`
+	Removed []int `json:"removed,omitempty"`
+
+	// What happens if each level is removed on its own instead.
+	// There are far too many ways of removing more than one level
+	// to list, so we don't.
+	Alternatives []d2Alternative `json:"alternatives,omitempty"`
+}
+
+type d2Alternative struct {
+	Removed int `json:"removed"`
+	// What's still wrong without that level, or nil if removing it
+	// would have worked as well.
+	Problem *d2Problem `json:"problem,omitempty"`
+}
+
+// Work out what's wrong with the step to report[ix], which
+// checkReport has said breaks the rules.
+func (rules d2Rules) problemAt(report []int, ix int) *d2Problem {
+	problem := &d2Problem{Index: ix, From: report[ix-1], To: report[ix]}
+	direction := d2Direction(report)
+	if rules.monotonic {
+		problem.Going = d2DirectionName(direction)
+	}
+	step := report[ix] - report[ix-1]
+	switch {
+	case rules.monotonic && step*direction < 0:
+		problem.Kind = D2_PROBLEM_DIRECTION
+	case max(step, -step) < rules.minStep:
+		problem.Kind = D2_PROBLEM_TOO_SMALL
+	default:
+		problem.Kind = D2_PROBLEM_TOO_BIG
+	}
+	return problem
+}
+
+func d2DirectionName(direction int) string {
+	if direction < 0 {
+		return "down"
+	}
+	return "up"
+}
+
+func (rules d2Rules) describe(problem *d2Problem) string {
+	step := problem.To - problem.From
+	goes := d2DirectionName(step)
+	switch {
+	case problem.Kind == D2_PROBLEM_DIRECTION:
+		return fmt.Sprintf("index %d goes %s from %d to %d, but the report's going %s", problem.Index, goes, problem.From, problem.To, problem.Going)
+	case step == 0:
+		return fmt.Sprintf("index %d stays at %d, rather than changing by at least %d", problem.Index, problem.From, rules.minStep)
+	case problem.Kind == D2_PROBLEM_TOO_SMALL:
+		return fmt.Sprintf("index %d only goes %s by %d, from %d to %d, rather than at least %d", problem.Index, goes, max(step, -step), problem.From, problem.To, rules.minStep)
+	default:
+		return fmt.Sprintf("index %d goes %s by %d, from %d to %d, rather than at most %d", problem.Index, goes, max(step, -step), problem.From, problem.To, rules.maxStep)
+	}
+}
+
+// Explain one report, found on the given line of the input.
+func (rules d2Rules) explain(line int, report []int, maxRemovals int) d2Explanation {
+	explanation := d2Explanation{Line: line, Levels: report}
+	safe, problemIx := rules.checkReport(report)
+	if safe {
+		explanation.Safe = true
+		return explanation
+	}
+	explanation.Problem = rules.problemAt(report, problemIx)
+	if maxRemovals == 0 {
+		return explanation
+	}
+
+	removed, rescued := rules.dampen(report, maxRemovals)
+	if rescued {
+		explanation.Rescued, explanation.Removed = true, removed
+	}
+	for ix := range report {
+		if rescued && len(removed) == 1 && removed[0] == ix {
+			continue
+		}
+		alternative := d2Alternative{Removed: ix}
+		shorter := slices.Delete(slices.Clone(report), ix, ix+1)
+		if safe, problemIx := rules.checkReport(shorter); !safe {
+			alternative.Problem = rules.problemAt(shorter, problemIx)
+			// Say where the problem is in the whole report, not
+			// the shorter one.
+			if alternative.Problem.Index >= ix {
+				alternative.Problem.Index++
+			}
+		}
+		explanation.Alternatives = append(explanation.Alternatives, alternative)
+	}
+	return explanation
+}
+
+// The reports command: explain why each of day 2's reports is or
+// isn't safe.
+func runReports(args []string) error {
+	var inputPath string
+	var example, asJSON, all bool
+	overrides := make(map[*dayParameter]int)
+	flags := flag.NewFlagSet(os.Args[0]+" reports", flag.ContinueOnError)
+	flags.StringVar(&inputPath, "i", "", "")
+	flags.StringVar(&inputPath, "input", "", "")
+	flags.BoolVar(&example, "example", false, "")
+	flags.BoolVar(&asJSON, "json", false, "")
+	flags.BoolVar(&all, "all", false, "")
+	flags.Func("param", "", func(arg string) error {
+		return parseParameterOverride(overrides, arg)
+	})
+	flags.Usage = printReportsUsage
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("unexpected %q", flags.Arg(0))
+	}
+
+	input, err := chooseInput(Day2, inputPath, example)
+	if err != nil {
+		return err
+	}
+	input = normaliseInput(input)
+	reports, err := parseD2Reports(input)
+	if err != nil {
+		return locateInputError(err)
+	}
+	ctx := Day2.withParameters(withParameterOverrides(context.Background(), overrides), input)
+	rules, maxRemovals := d2RulesFrom(ctx), d2MaxRemovals.get(ctx)
+
+	explanations := make([]d2Explanation, 0, len(reports))
+	for ix, report := range reports {
+		explanations = append(explanations, rules.explain(ix+1, report, maxRemovals))
+	}
+	if asJSON {
+		return writeD2Explanations(os.Stdout, explanations, all)
+	}
+	printD2Explanations(os.Stdout, rules, maxRemovals, explanations, all)
+	return nil
+}
+
+// Write the explanations as a JSON array, leaving out the safe
+// reports unless all is set.
+func writeD2Explanations(w io.Writer, explanations []d2Explanation, all bool) error {
+	if !all {
+		explanations = slices.DeleteFunc(slices.Clone(explanations), func(explanation d2Explanation) bool {
+			return explanation.Safe
+		})
+	}
+	data, err := json.MarshalIndent(explanations, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
+
+func printD2Explanations(w io.Writer, rules d2Rules, maxRemovals int, explanations []d2Explanation, all bool) {
+	way := "going up and down as they like"
+	if rules.monotonic {
+		way = "all going the same way"
+	}
+	fmt.Fprintf(w, "Rules: steps of %d to %d, %s, with up to %s removed by the Problem Dampener\n", rules.minStep, rules.maxStep, way, countD2Levels(maxRemovals))
+
+	safe, rescued := 0, 0
+	for _, explanation := range explanations {
+		switch {
+		case explanation.Safe:
+			safe++
+		case explanation.Rescued:
+			rescued++
+		}
+		if explanation.Safe && !all {
+			continue
+		}
+
+		fmt.Fprintf(w, "\nLine %d: %s\n", explanation.Line, joinInts(explanation.Levels, " "))
+		if explanation.Safe {
+			fmt.Fprintln(w, "  Safe")
+			continue
+		}
+		fmt.Fprintf(w, "  Unsafe: %s\n", rules.describe(explanation.Problem))
+		if explanation.Rescued {
+			fmt.Fprintf(w, "  Rescued by removing %s\n", describeD2Removals(explanation.Levels, explanation.Removed))
+		} else if maxRemovals > 0 {
+			fmt.Fprintf(w, "  Can't be rescued by removing up to %s\n", countD2Levels(maxRemovals))
+		}
+		for _, alternative := range explanation.Alternatives {
+			removing := describeD2Removals(explanation.Levels, []int{alternative.Removed})
+			if alternative.Problem == nil {
+				fmt.Fprintf(w, "  Removing %s instead would also work\n", removing)
+			} else {
+				fmt.Fprintf(w, "  Removing %s instead: %s\n", removing, rules.describe(alternative.Problem))
+			}
+		}
+	}
+	fmt.Fprintf(w, "\n%d reports: %d safe, %d more rescued by the Problem Dampener, %d unsafe\n", len(explanations), safe, rescued, len(explanations)-safe-rescued)
+}
+
+func countD2Levels(count int) string {
+	if count == 1 {
+		return "1 level"
+	}
+	return fmt.Sprintf("%d levels", count)
+}
+
+// e.g. "index 1 (3) and index 4 (7)"
+func describeD2Removals(report []int, removed []int) string {
+	descriptions := make([]string, len(removed))
+	for ix, levelIx := range removed {
+		descriptions[ix] = fmt.Sprintf("index %d (%d)", levelIx, report[levelIx])
+	}
+	if len(descriptions) == 1 {
+		return descriptions[0]
+	}
+	return strings.Join(descriptions[:len(descriptions)-1], ", ") + " and " + descriptions[len(descriptions)-1]
+}
+
+const REPORTS_USAGE_TEXT = `Usage: %s reports [options]
+
+Explain which of day 2's reports are unsafe and why: the first step
+that breaks the rules, whether the Problem Dampener can rescue the
+report and how, and why removing each other level wouldn't.
+
+Options:
+  -i, --input    Use the reports in this file (or stdin, if "-"), rather than day 2's
+                 input in %s
+      --example  Use day 2's example input
+      --all      Include the safe reports too
+      --json     Print the explanations as JSON, one object per report
+      --param    Change the rules, as when running days, e.g. --param 2.maxRemovals=2
+`
+
+func printReportsUsage() {
+	fmt.Printf(REPORTS_USAGE_TEXT, os.Args[0], INPUT_DIRNAME)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestExplainD2Reports(t *testing.T) {
+	rules := d2Rules{minStep: 1, maxStep: 3, monotonic: true}
+	for _, test := range []struct {
+		report []int
+		want   d2Explanation
+	}{
+		{[]int{7, 6, 4, 2, 1}, d2Explanation{Levels: []int{7, 6, 4, 2, 1}, Safe: true}},
+		{[]int{1, 2, 7, 8, 9}, d2Explanation{
+			Levels:  []int{1, 2, 7, 8, 9},
+			Problem: &d2Problem{2, 2, 7, D2_PROBLEM_TOO_BIG, "up"},
+			Alternatives: []d2Alternative{
+				{0, &d2Problem{2, 2, 7, D2_PROBLEM_TOO_BIG, "up"}},
+				{1, &d2Problem{2, 1, 7, D2_PROBLEM_TOO_BIG, "up"}},
+				{2, &d2Problem{3, 2, 8, D2_PROBLEM_TOO_BIG, "up"}},
+				{3, &d2Problem{2, 2, 7, D2_PROBLEM_TOO_BIG, "up"}},
+				{4, &d2Problem{2, 2, 7, D2_PROBLEM_TOO_BIG, "up"}},
+			},
+		}},
+		{[]int{1, 3, 2, 4, 5}, d2Explanation{
+			Levels:  []int{1, 3, 2, 4, 5},
+			Problem: &d2Problem{2, 3, 2, D2_PROBLEM_DIRECTION, "up"},
+			Rescued: true,
+			Removed: []int{1},
+			Alternatives: []d2Alternative{
+				{0, &d2Problem{3, 2, 4, D2_PROBLEM_DIRECTION, "down"}},
+				{2, nil},
+				{3, &d2Problem{2, 3, 2, D2_PROBLEM_DIRECTION, "up"}},
+				{4, &d2Problem{2, 3, 2, D2_PROBLEM_DIRECTION, "up"}},
+			},
+		}},
+		{[]int{8, 6, 4, 4, 1}, d2Explanation{
+			Levels:  []int{8, 6, 4, 4, 1},
+			Problem: &d2Problem{3, 4, 4, D2_PROBLEM_TOO_SMALL, "down"},
+			Rescued: true,
+			Removed: []int{2},
+			Alternatives: []d2Alternative{
+				{0, &d2Problem{3, 4, 4, D2_PROBLEM_TOO_SMALL, "down"}},
+				{1, &d2Problem{2, 8, 4, D2_PROBLEM_TOO_BIG, "down"}},
+				{3, nil},
+				{4, &d2Problem{3, 4, 4, D2_PROBLEM_TOO_SMALL, "down"}},
+			},
+		}},
+	} {
+		if got := rules.explain(0, test.report, 1); !reflect.DeepEqual(got, test.want) {
+			gotJSON, _ := json.Marshal(got)
+			wantJSON, _ := json.Marshal(test.want)
+			t.Errorf("%v: got %s\nwant %s", test.report, gotJSON, wantJSON)
+		}
+	}
+}
+
+// The JSON should only have the unsafe reports in it, unless asked
+// for all of them, and should read back in as what was written.
+func TestWriteD2Explanations(t *testing.T) {
+	reports, err := parseD2Reports(Day2.ExampleInput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := d2Rules{minStep: 1, maxStep: 3, monotonic: true}
+	var explanations []d2Explanation
+	for ix, report := range reports {
+		explanations = append(explanations, rules.explain(ix+1, report, 1))
+	}
+
+	for _, all := range []bool{false, true} {
+		var out bytes.Buffer
+		if err := writeD2Explanations(&out, explanations, all); err != nil {
+			t.Fatal(err)
+		}
+		var got []d2Explanation
+		if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		want := explanations
+		if !all {
+			want = []d2Explanation{explanations[1], explanations[2], explanations[3], explanations[4]}
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("with all %v, read back %+v, want %+v", all, got, want)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Who needs regular expressions when you can simply compile your
+// own state machine? Day 3's corrupted memory is full of
+// instructions like mul(X,Y) hidden among junk, and this finds them
+// - any set of them, each described by its name, how many operands
+// it takes and how many digits those can have - far faster than
+// regexp manages, by virtue of being specialised.
+//
+// Finding an instruction happens in two halves. First, its name and
+// opening bracket: the names are compiled into a table saying which
+// state to go to from each state on each byte, in the manner of
+// Aho-Corasick, so that however the names overlap with each other
+// and the junk around them, it's one lookup per byte. Then, once
+// we've seen e.g. "mul(", the operands, which are simple enough to
+// check as we go without any more table.
+
+// What an instruction looks like, e.g. mul(X,Y) is
+// {"mul", 2, 1, 3}: two operands of one to three digits each.
+type instructionSignature struct {
+	name                 string
+	arity                int
+	minDigits, maxDigits int
+}
+
+type instructionScanner struct {
+	instructions []instructionSignature
+	maxArity     int
+
+	// The state to go to while looking for a name, from each state
+	// on each byte: next[state<<8|byte]. State 0 is having nothing
+	// that might be the start of a name.
+	next []uint16
+
+	// For each state, which instruction's name and opening bracket
+	// we've just found, plus 1 - or 0 if we haven't.
+	found []int
+}
+
+// Operands longer than this might not fit in an int.
+const SCANNER_MAX_DIGITS int = 18
+
+func compileInstructionScanner(instructions []instructionSignature) (*instructionScanner, error) {
+	s := &instructionScanner{instructions: instructions}
+
+	// Build a trie of everything we're looking for before the
+	// operands, i.e. "mul(" for mul.
+	trie := [][256]uint16{{}}
+	s.found = []int{0}
+	for ix, instruction := range instructions {
+		switch {
+		case instruction.name == "":
+			return nil, fmt.Errorf("instruction %d has no name", ix)
+		case strings.ContainsAny(instruction.name, "(),"):
+			return nil, fmt.Errorf("%q can't have brackets or commas in its name", instruction.name)
+		case instruction.arity < 0:
+			return nil, fmt.Errorf("%s can't have %d operands", instruction.name, instruction.arity)
+		case instruction.arity > 0 && (instruction.minDigits < 1 || instruction.maxDigits < instruction.minDigits || instruction.maxDigits > SCANNER_MAX_DIGITS):
+			return nil, fmt.Errorf("%s's operands can't have %d to %d digits", instruction.name, instruction.minDigits, instruction.maxDigits)
+		}
+		s.maxArity = max(s.maxArity, instruction.arity)
+
+		state := 0
+		for _, char := range []byte(instruction.name + "(") {
+			if trie[state][char] == 0 {
+				trie = append(trie, [256]uint16{})
+				s.found = append(s.found, 0)
+				trie[state][char] = uint16(len(trie) - 1)
+			}
+			state = int(trie[state][char])
+		}
+		if s.found[state] != 0 {
+			return nil, fmt.Errorf("there's more than one %s instruction", instruction.name)
+		}
+		s.found[state] = ix + 1
+	}
+	if len(trie) > 1<<16 {
+		return nil, errors.New("too many instructions, or their names are too long")
+	}
+
+	// Now fill in where to go when the next byte isn't in the trie.
+	// That's wherever we'd be if we'd started from the longest
+	// suffix of what we've seen that is in the trie, which is
+	// where its parent would go on the same byte - so we work
+	// through the trie shortest first, and the parent's always
+	// been done already. The root just stays where it is.
+	s.next = make([]uint16, len(trie)<<8)
+	fallback := make([]uint16, len(trie))
+	queue := []int{0}
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+		for char := range 256 {
+			child := int(trie[state][char])
+			switch {
+			case child != 0:
+				if state != 0 {
+					fallback[child] = s.next[int(fallback[state])<<8|char]
+				}
+				s.next[state<<8|char] = uint16(child)
+				queue = append(queue, child)
+			case state != 0:
+				s.next[state<<8|char] = s.next[int(fallback[state])<<8|char]
+			}
+		}
+		// If one name ends in another, e.g. undo and do, finding
+		// the longer one finds the shorter one too - but we'd
+		// rather the longer one, if we're looking for both.
+		if s.found[state] == 0 {
+			s.found[state] = s.found[fallback[state]]
+		}
+	}
+	return s, nil
+}
+
+func mustCompileInstructionScanner(instructions []instructionSignature) *instructionScanner {
+	s, err := compileInstructionScanner(instructions)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Find every instruction in the input, in order, calling execute
+// with its index in the list the scanner was compiled from and its
+// operands. The operands slice is reused, so execute mustn't keep
+// hold of it.
+func (s *instructionScanner) scan(input string, execute func(instruction int, operands []int)) {
+	scanChunk(s, s.newScanState(), input, execute)
+}
+
+// Where a scan's got to - which is only ever a few numbers, however
+// much input it's been through - so that it can carry on with the
+// next chunk of input as if there'd been no break.
+type scanState struct {
+	state, instruction, operandIx, digits int
+	operands                              []int
+}
+
+func (s *instructionScanner) newScanState() *scanState {
+	return &scanState{instruction: -1, operands: make([]int, s.maxArity)}
+}
+
+// Carry on a scan with the next chunk of input. An instruction can
+// be split across chunks anywhere.
+func scanChunk[T string | []byte](s *instructionScanner, scan *scanState, input T, execute func(instruction int, operands []int)) {
+	// Working on local copies of the state is quite a bit quicker.
+	state, instruction, operandIx, digits, operands := scan.state, scan.instruction, scan.operandIx, scan.digits, scan.operands
+	for ix := 0; ix < len(input); ix++ {
+		char := input[ix]
+		if instruction >= 0 {
+			sig := &s.instructions[instruction]
+			switch {
+			case char >= '0' && char <= '9' && operandIx < sig.arity && digits < sig.maxDigits:
+				operands[operandIx] = operands[operandIx]*10 + int(char-'0')
+				digits++
+				continue
+			case char == ',' && operandIx < sig.arity-1 && digits >= sig.minDigits:
+				operandIx++
+				digits = 0
+				continue
+			case char == ')' && (sig.arity == 0 || (operandIx == sig.arity-1 && digits >= sig.minDigits)):
+				execute(instruction, operands[:sig.arity])
+				instruction, state = -1, 0
+				continue
+			}
+			// That's not what we were expecting, so this wasn't an
+			// instruction after all - but this byte might be the
+			// start of the next one, so it still needs looking at.
+			instruction, state = -1, 0
+		}
+
+		state = int(s.next[state<<8|int(char)])
+		if found := s.found[state]; found != 0 {
+			instruction, operandIx, digits = found-1, 0, 0
+			clear(operands)
+		}
+	}
+	scan.state, scan.instruction, scan.operandIx, scan.digits = state, instruction, operandIx, digits
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"reflect"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+// New instructions shouldn't need anything more than a signature.
+func TestInstructionScanner(t *testing.T) {
+	s, err := compileInstructionScanner([]instructionSignature{
+		{"mul", 2, 1, 3},
+		{"add", 2, 1, 3},
+		{"xor", 3, 2, 4},
+		{"do", 0, 0, 0},
+		{"undo", 0, 0, 0},
+		{"aab", 1, 1, 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	s.scan("mmul(1,2)add(3,4]add(5,6)xor(1,22,333)xor(11,22,333)xor(11,22,33333)mul(1234,5)"+
+		"mul(mul(7,8)do()undo()do(1)aaab(9)aab(10)add(,1)add(1,)",
+		func(instruction int, operands []int) {
+			got = append(got, fmt.Sprint(instruction, operands))
+		})
+	want := []string{"0 [1 2]", "1 [5 6]", "2 [11 22 333]", "0 [7 8]", "3 []", "4 []", "5 [9]"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestCompileInstructionScannerErrors(t *testing.T) {
+	for _, instructions := range [][]instructionSignature{
+		{{"", 1, 1, 3}},
+		{{"mul(", 2, 1, 3}},
+		{{"mul", -1, 1, 3}},
+		{{"mul", 2, 0, 3}},
+		{{"mul", 2, 3, 2}},
+		{{"mul", 2, 1, SCANNER_MAX_DIGITS + 1}},
+		{{"mul", 2, 1, 3}, {"mul", 1, 1, 3}},
+	} {
+		if _, err := compileInstructionScanner(instructions); err == nil {
+			t.Errorf("%v compiled, want an error", instructions)
+		}
+	}
+}
+
+// The obvious way of doing day 3, which the scanner should agree
+// with and beat.
+var d3Regexp = regexp.MustCompile(`mul\((\d{1,3}),(\d{1,3})\)|do\(\)|don't\(\)`)
+
+func d3WithRegexp(input string, conditionals bool) int {
+	sum := 0
+	enabled := true
+	for _, match := range d3Regexp.FindAllStringSubmatch(input, -1) {
+		switch match[0] {
+		case "do()":
+			enabled = true
+		case "don't()":
+			enabled = !conditionals
+		default:
+			if enabled {
+				x, _ := strconv.Atoi(match[1])
+				y, _ := strconv.Atoi(match[2])
+				sum += x * y
+			}
+		}
+	}
+	return sum
+}
+
+func TestD3ScannerMatchesRegexp(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 3))
+	for range 100 {
+		input := generateDay3(rng, 1+rng.IntN(5000))
+		memory := newD3Stream()
+		memory.WriteString(input)
+		got := [2]int{memory.total, memory.enabledTotal}
+		if want := [2]int{d3WithRegexp(input, false), d3WithRegexp(input, true)}; got != want {
+			t.Fatalf("got %v, want %v for %q", got, want, input)
+		}
+	}
+}
+
+// However the memory's split up, the totals should be the same as
+// scanning it all in one go - and so should where the scan's got to,
+// in case there's more to come.
+func TestD3StreamChunks(t *testing.T) {
+	rng := rand.New(rand.NewPCG(2, 3))
+	for range 100 {
+		// Stopping anywhere, so that there's often an instruction
+		// part-way through at the end.
+		input := []byte(generateDay3(rng, 1+rng.IntN(5000)))
+		input = input[:1+rng.IntN(len(input))]
+		whole := newD3Stream()
+		whole.Write(input)
+
+		chunked := newD3Stream()
+		for rest := input; len(rest) > 0; {
+			size := min(1+rng.IntN(10), len(rest))
+			chunked.Write(rest[:size])
+			rest = rest[size:]
+		}
+		if !reflect.DeepEqual(chunked, whole) {
+			t.Fatalf("got %+v (scan %+v) in chunks, want %+v (scan %+v)", *chunked, *chunked.scan, *whole, *whole.scan)
+		}
+	}
+}
+
+func BenchmarkD3Scanner(b *testing.B) {
+	input := generateDay3(rand.New(rand.NewPCG(1, 3)), 18000)
+	b.Run("Scanner", func(b *testing.B) {
+		b.SetBytes(int64(len(input)))
+		for range b.N {
+			newD3Stream().WriteString(input)
+		}
+	})
+	b.Run("Regexp", func(b *testing.B) {
+		b.SetBytes(int64(len(input)))
+		for range b.N {
+			d3WithRegexp(input, true)
+		}
+	})
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// The days that simulate things moving around a grid can show the
+// simulation as it happens, frame by frame, in the terminal. They
+// find a visualiser in their context and hand it each frame as they
+// go; when there isn't one, which is all the time except under the
+// visualise command, they don't draw anything at all, so the
+// solutions run exactly as fast as ever.
+//
+// There's no reading keys as they're pressed without either a
+// library or a lot of per-platform terminal fiddling, so commands
+// are typed a line at a time - just pressing Enter pauses and
+// resumes.
+type visualiser struct {
+	out      io.Writer
+	commands <-chan string
+
+	// Frames per second. Beyond VISUALISER_MAX_FRAME_RATE, frames
+	// get skipped rather than drawn any faster.
+	speed    int
+	paused   bool
+	stepping bool
+	stopped  bool
+
+	frames int
+	latest func() (string, string)
+	sleep  func(time.Duration)
+}
+
+const (
+	VISUALISER_DEFAULT_SPEED  = 20
+	VISUALISER_MAX_SPEED      = 1 << 20
+	VISUALISER_MAX_FRAME_RATE = 50
+)
+
+var visualisedDays = []int{6, 14, 15, 16, 18, 20}
+
+type visualiserKey struct{}
+
+func newVisualiser(out io.Writer, commands <-chan string, speed int) *visualiser {
+	return &visualiser{out: out, commands: commands, speed: speed, sleep: time.Sleep}
+}
+
+func withVisualiser(ctx context.Context, vis *visualiser) context.Context {
+	return context.WithValue(ctx, visualiserKey{}, vis)
+}
+
+// The visualiser to show frames to, or nil if nobody's watching.
+// Days should check for nil before building a frame, so that
+// they don't pay for it when there's nobody to show it to.
+func visualiserFrom(ctx context.Context) *visualiser {
+	vis, _ := ctx.Value(visualiserKey{}).(*visualiser)
+	return vis
+}
+
+// Show the next frame. draw returns a caption and a picture, and
+// only gets called if the frame's actually going to be drawn - at
+// high speeds, most aren't. Anything it refers to needs to stay as
+// it was until the next frame, as the last frame gets drawn again
+// at the end.
+func (vis *visualiser) show(draw func() (caption string, picture string)) {
+	vis.latest = draw
+	if vis.stopped {
+		return
+	}
+	vis.frames++
+
+	// Deal with anything that's been typed since the last frame.
+	for waiting := true; waiting && vis.commands != nil; {
+		select {
+		case command, ok := <-vis.commands:
+			vis.obey(command, ok)
+		default:
+			waiting = false
+		}
+	}
+
+	if vis.paused {
+		// Every frame gets drawn while we're paused, and then we
+		// wait to be told to step on to the next one or carry on.
+		vis.draw()
+		for vis.paused && !vis.stepping {
+			command, ok := <-vis.commands
+			vis.obey(command, ok)
+			if vis.paused && !vis.stepping {
+				// Still here, e.g. after a change of speed, so
+				// show the new status.
+				vis.draw()
+			}
+		}
+		vis.stepping = false
+		return
+	}
+
+	skip := max(vis.speed/VISUALISER_MAX_FRAME_RATE, 1)
+	if vis.frames%skip != 0 {
+		return
+	}
+	vis.draw()
+	vis.sleep(time.Second * time.Duration(skip) / time.Duration(vis.speed))
+}
+
+// Draw the last frame we were shown, whether or not it was drawn at
+// the time, so that the end of the simulation stays on screen.
+func (vis *visualiser) finish() {
+	if vis.latest != nil {
+		vis.draw()
+	}
+}
+
+func (vis *visualiser) obey(command string, ok bool) {
+	if !ok {
+		// There'll be no more commands, so there's no point
+		// waiting for any.
+		vis.commands = nil
+		vis.paused = false
+		return
+	}
+	switch {
+	case command == "":
+		vis.paused = !vis.paused
+	case command == "s":
+		vis.stepping = vis.paused
+		vis.paused = true
+	case command == "q":
+		vis.stopped = true
+		vis.paused = false
+	case strings.Trim(command, "+") == "":
+		vis.speed = min(vis.speed<<min(len(command), 20), VISUALISER_MAX_SPEED)
+	case strings.Trim(command, "-") == "":
+		vis.speed = max(vis.speed>>min(len(command), 20), 1)
+	}
+}
+
+func (vis *visualiser) draw() {
+	caption, picture := vis.latest()
+	status := fmt.Sprintf("Running at %d frames/s | Enter: pause/resume, s: step, +/-: faster/slower, q: skip to the end", vis.speed)
+	switch {
+	case vis.stopped:
+		status = "Finished"
+	case vis.paused:
+		status = "Paused | Enter: resume, s: step, q: skip to the end"
+	}
+	// Clear the screen and start again from the top left.
+	fmt.Fprintf(vis.out, "\x1b[H\x1b[2J%s\n%s\n%s\n", picture, caption, status)
+}
+
+// Turn a grid into a picture, one character per square.
+func renderPicture(numRows int, numCols int, square func(pos gridPos) rune) string {
+	var sb strings.Builder
+	sb.Grow(numRows * (numCols + 1))
+	for row := range numRows {
+		if row > 0 {
+			sb.WriteByte('\n')
+		}
+		for col := range numCols {
+			sb.WriteRune(square(gridPos{row, col}))
+		}
+	}
+	return sb.String()
+}
+
+// Read commands a line at a time.
+func readCommands(r io.Reader) <-chan string {
+	commands := make(chan string)
+	go func() {
+		defer close(commands)
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			commands <- strings.TrimSpace(scanner.Text())
+		}
+	}()
+	return commands
+}
+
+// The visualise command: run one day with a visualiser watching.
+func runVisualise(args []string) error {
+	var dayNumber, speed int
+	var inputPath string
+	var example bool
+	overrides := make(map[*dayParameter]int)
+	flags := flag.NewFlagSet(os.Args[0]+" visualise", flag.ContinueOnError)
+	flags.IntVar(&dayNumber, "d", 0, "")
+	flags.IntVar(&dayNumber, "day", 0, "")
+	flags.StringVar(&inputPath, "i", "", "")
+	flags.StringVar(&inputPath, "input", "", "")
+	flags.BoolVar(&example, "example", false, "")
+	flags.IntVar(&speed, "speed", VISUALISER_DEFAULT_SPEED, "")
+	flags.Func("param", "", func(arg string) error {
+		return parseParameterOverride(overrides, arg)
+	})
+	flags.Usage = printVisualiseUsage
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	switch {
+	case flags.NArg() > 0:
+		return fmt.Errorf("unexpected %q", flags.Arg(0))
+	case !slices.Contains(visualisedDays, dayNumber):
+		return fmt.Errorf("there's no visualisation for day %d", dayNumber)
+	case inputPath == "-":
+		return errors.New("the input can't come from stdin, as that's where the commands come from")
+	case speed < 1 || speed > VISUALISER_MAX_SPEED:
+		return fmt.Errorf("--speed must be between 1 and %d", VISUALISER_MAX_SPEED)
+	}
+
+	day := allDays[dayNumber-1]
+	input, err := chooseInput(day, inputPath, example)
+	if err != nil {
+		return err
+	}
+
+	// Anything logged would scribble over the pictures.
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	vis := newVisualiser(os.Stdout, readCommands(os.Stdin), speed)
+	ctx := withVisualiser(withParameterOverrides(context.Background(), overrides), vis)
+	part1Result, part1Context, err := day.runPart1(ctx, logger, input)
+	if err != nil {
+		return err
+	}
+	part2Result, err := day.runPart2(ctx, logger, input, part1Context)
+	if err != nil {
+		return err
+	}
+	vis.stopped = true
+	vis.finish()
+	fmt.Printf("Part 1: %s\nPart 2: %s\n", part1Result, part2Result)
+	return nil
+}
+
+const VISUALISE_USAGE_TEXT = `Usage: %s visualise -d <day> [options]
+
+Show a day's simulation in the terminal as it runs. Days %s can be
+visualised. While it's running, type a command and press Enter:
+  (nothing)  Pause or resume
+  s          Step on one frame, pausing first if need be
+  +, -       Double or halve the speed - repeat for more, e.g. +++
+  q          Stop showing frames and skip to the end
+
+Options:
+  -d, --day      Which day to visualise
+  -i, --input    Use the input in this file, rather than the one in %s
+      --example  Use the day's example input
+      --speed    Frames per second to start at (default %d). Beyond %d, frames are
+                 skipped rather than drawn faster
+      --param    Set one of the day's parameters, as when running days
+`
+
+func printVisualiseUsage() {
+	days := make([]string, len(visualisedDays))
+	for ix, day := range visualisedDays {
+		days[ix] = fmt.Sprint(day)
+	}
+	fmt.Printf(VISUALISE_USAGE_TEXT, os.Args[0], strings.Join(days, ", "), INPUT_DIRNAME, VISUALISER_DEFAULT_SPEED, VISUALISER_MAX_FRAME_RATE)
+}
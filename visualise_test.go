@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Watching a day shouldn't change its answers.
+func TestVisualisedDays(t *testing.T) {
+	for _, dayNumber := range visualisedDays {
+		t.Run(fmt.Sprintf("Day%02d", dayNumber), func(t *testing.T) {
+			day := allDays[dayNumber-1]
+			input := day.ExampleInput
+			if input == "" {
+				input = inputGenerators[dayNumber].generate(rand.New(rand.NewPCG(1, uint64(dayNumber))), 50)
+			}
+			want := solveBothParts(t, context.Background(), day, input)
+
+			var out bytes.Buffer
+			vis := newVisualiser(&out, nil, VISUALISER_MAX_FRAME_RATE)
+			vis.sleep = func(time.Duration) {}
+			got := solveBothParts(t, withVisualiser(context.Background(), vis), day, input)
+			if got != want {
+				t.Errorf("got %v with the visualiser, want %v", got, want)
+			}
+			if vis.frames == 0 || out.Len() == 0 {
+				t.Errorf("showed %d frames and drew %d bytes, want some of each", vis.frames, out.Len())
+			}
+		})
+	}
+}
+
+func solveBothParts(t *testing.T, ctx context.Context, day puzzle, input string) [2]string {
+	part1Result, part1Context, err := day.runPart1(ctx, quietLogger(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part2Result, err := day.runPart2(ctx, quietLogger(), input, part1Context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return [2]string{part1Result, part2Result}
+}
+
+func TestVisualiserCommands(t *testing.T) {
+	vis := newVisualiser(nil, make(chan string), 20)
+	for _, step := range []struct {
+		command          string
+		paused, stepping bool
+		speed            int
+	}{
+		{"", true, false, 20},
+		{"+++", true, false, 160},
+		{"s", true, true, 160},
+		{"", false, true, 160},
+		{"s", true, false, 160},
+		{"-", true, false, 80},
+		{"-----------------------------", true, false, 1},
+		{"wibble", true, false, 1},
+	} {
+		vis.obey(step.command, true)
+		if vis.paused != step.paused || vis.stepping != step.stepping || vis.speed != step.speed {
+			t.Errorf("after %q: paused %v, stepping %v, speed %d; want %v, %v, %d", step.command, vis.paused, vis.stepping, vis.speed, step.paused, step.stepping, step.speed)
+		}
+	}
+	vis.obey("", false)
+	if vis.paused || vis.commands != nil {
+		t.Errorf("still waiting for commands after they ran out")
+	}
+}
+
+func TestVisualiserSkipsFrames(t *testing.T) {
+	var out bytes.Buffer
+	vis := newVisualiser(&out, nil, 10*VISUALISER_MAX_FRAME_RATE)
+	var slept []time.Duration
+	vis.sleep = func(d time.Duration) { slept = append(slept, d) }
+	show := func(frame int) {
+		vis.show(func() (string, string) { return fmt.Sprintf("frame %d", frame), "" })
+	}
+
+	// Too fast to draw every frame, so only every tenth gets drawn,
+	// and shown for as long as ten frames would take.
+	for frame := 1; frame <= 25; frame++ {
+		show(frame)
+	}
+	if got := strings.Count(out.String(), "\nframe "); got != 2 {
+		t.Errorf("drew %d frames, want 2", got)
+	}
+	if want := time.Second / VISUALISER_MAX_FRAME_RATE; len(slept) != 2 || slept[0] != want {
+		t.Errorf("slept for %v, want %v twice", slept, want)
+	}
+
+	// Once stopped, only the last frame gets drawn, at the end.
+	vis.obey("q", true)
+	out.Reset()
+	for frame := 26; frame <= 50; frame++ {
+		show(frame)
+	}
+	vis.finish()
+	if got := out.String(); strings.Count(got, "\nframe ") != 1 || !strings.Contains(got, "frame 50") {
+		t.Errorf("drew %q after stopping, want just frame 50", got)
+	}
+}